@@ -0,0 +1,68 @@
+// Command backtest replays a labeled transactions CSV through the active fraud detector configuration and reports
+// precision, recall, false positive rate, alert volume and score distributions per reason code. The detector is
+// selected with the same FRAUD_* environment variables as the fraud Lambda. No AWS services are called.
+//
+//	FRAUD_RULES_PATH=rules.yaml backtest -file labeled_transactions.csv -label IsFraud
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+func main() {
+	csvFilePath := flag.String("file", "bank_transactions_data.csv", "labeled transactions CSV in the bank transactions export format")
+	labelColumn := flag.String("label", "IsFraud", "column holding the fraud label, such as 1/0 or true/false")
+	listsPath := flag.String("lists", "", "optional JSON array of allowlist and denylist entries to apply")
+	format := flag.String("format", "text", "report format, text or json")
+	flag.Parse()
+
+	config.InitializeLocalConfig()
+	ctx := context.Background()
+
+	var lists []models.ListEntry
+	if *listsPath != "" {
+		data, err := os.ReadFile(*listsPath)
+		if err != nil {
+			log.Fatalf("Unable to read list entries: %v", err)
+		}
+		if err := json.Unmarshal(data, &lists); err != nil {
+			log.Fatalf("Unable to parse list entries: %v", err)
+		}
+	}
+
+	runner, err := backtest.NewRunner(ctx, lists...)
+	if err != nil {
+		log.Fatalf("Failed to configure fraud detectors: %v", err)
+	}
+
+	file, err := os.Open(*csvFilePath)
+	if err != nil {
+		log.Fatalf("Unable to open CSV file: %v", err)
+	}
+	defer file.Close()
+
+	report, err := runner.Replay(ctx, file, *labelColumn)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	default:
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("Unable to write report: %v", err)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -93,10 +92,7 @@ func main() {
 	}
 
 	// Find column indices
-	colMap := make(map[string]int)
-	for i, col := range header {
-		colMap[col] = i
-	}
+	colMap := models.CSVColumns(header)
 
 	// Skip to last processed index
 	currentIndex := 0
@@ -132,7 +128,7 @@ func main() {
 		currentIndex++
 
 		// Parse CSV record into transaction
-		transaction, err := parseTransaction(record, colMap)
+		transaction, err := models.ParseCSVTransaction(record, colMap)
 		if err != nil {
 			log.Printf("Error parsing transaction at index %d: %v", currentIndex, err)
 			continue
@@ -226,46 +222,6 @@ func saveState(state *ProcessingState, statePath string) error {
 	return nil
 }
 
-// parseTransaction converts a CSV record to a Transaction using column map
-func parseTransaction(record []string, colMap map[string]int) (models.Transaction, error) {
-	// Parse numeric fields
-	customerAge, _ := strconv.Atoi(record[colMap["CustomerAge"]])
-	transactionDuration, _ := strconv.Atoi(record[colMap["TransactionDuration"]])
-	loginAttempts, _ := strconv.Atoi(record[colMap["LoginAttempts"]])
-	accountBalance, _ := strconv.ParseFloat(record[colMap["AccountBalance"]], 64)
-	amount, _ := strconv.ParseFloat(record[colMap["TransactionAmount"]], 64)
-
-	// Format phone number - add "+" prefix if not present
-	phoneNumber := record[colMap["PhoneNumber"]]
-	if phoneNumber != "" && !strings.HasPrefix(phoneNumber, "+") {
-		phoneNumber = "+" + phoneNumber
-	}
-
-	transaction := models.Transaction{
-		TransactionID:           record[colMap["TransactionID"]],
-		AccountID:               record[colMap["AccountID"]],
-		TransactionAmount:       amount,
-		TransactionDate:         record[colMap["TransactionDate"]],
-		TransactionType:         record[colMap["TransactionType"]],
-		Location:                record[colMap["Location"]],
-		DeviceID:                record[colMap["DeviceID"]],
-		IPAddress:               record[colMap["IPAddress"]],
-		MerchantID:              record[colMap["MerchantID"]],
-		Channel:                 record[colMap["Channel"]],
-		CustomerAge:             customerAge,
-		CustomerOccupation:      record[colMap["CustomerOccupation"]],
-		TransactionDuration:     transactionDuration,
-		LoginAttempts:           loginAttempts,
-		AccountBalance:          accountBalance,
-		PreviousTransactionDate: record[colMap["PreviousTransactionDate"]],
-		PhoneNumber:             phoneNumber,
-		Email:                   record[colMap["Email"]],
-		TransactionStatus:       record[colMap["TransactionStatus"]],
-	}
-
-	return transaction, nil
-}
-
 // processBatch sends a batch of transactions to SQS
 func processBatch(ctx context.Context, wg *sync.WaitGroup, sqsHandler *messaging.SQSHandler,
 	batch []models.Transaction, startIndex int) {
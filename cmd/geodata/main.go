@@ -0,0 +1,190 @@
+// Command geodata converts the DB-IP Lite city database into the cidr rows of the bundled locations dataset.
+//
+//	geodata -in dbip-city-lite.csv -out internal/geo/data/networks.csv
+//
+// Coordinates are rounded to -precision decimal places and adjacent ranges that round to the same place are
+// merged, which keeps the bundled file small without moving any address further than the travel rules can see.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type addressRange struct {
+	start     netip.Addr
+	end       netip.Addr
+	latitude  string
+	longitude string
+}
+
+func main() {
+	in := flag.String("in", "", "DB-IP Lite city CSV, optionally gzipped")
+	out := flag.String("out", "internal/geo/data/networks.csv", "locations CSV to write")
+	precision := flag.Int("precision", 1, "decimal places to keep in coordinates")
+	flag.Parse()
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "usage: geodata -in dbip-city-lite.csv [-out networks.csv] [-precision 1]")
+		os.Exit(2)
+	}
+
+	input, err := open(*in)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %s\n", *in, err)
+	}
+	defer input.Close()
+
+	output, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %s\n", *out, err)
+	}
+	defer output.Close()
+
+	rows, err := convert(input, output, *precision)
+	if err != nil {
+		log.Fatalf("Failed to convert %s: %s\n", *in, err)
+	}
+	log.Printf("Wrote %d networks to %s", rows, *out)
+}
+
+func open(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// convert reads rows of ip_start,ip_end,continent,country,stateprov,city,latitude,longitude and writes each range
+// as the fewest CIDR networks that cover it
+func convert(input io.Reader, output io.Writer, precision int) (int, error) {
+	csvReader := csv.NewReader(bufio.NewReader(input))
+	csvReader.FieldsPerRecord = 8
+	csvReader.ReuseRecord = true
+
+	buffered := bufio.NewWriter(output)
+	writer := csv.NewWriter(buffered)
+	if err := writer.Write([]string{"type", "key", "latitude", "longitude", "timezone"}); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	var pending *addressRange
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		for _, prefix := range rangePrefixes(pending.start, pending.end) {
+			if err := writer.Write([]string{"cidr", prefix.String(), pending.latitude, pending.longitude, ""}); err != nil {
+				return err
+			}
+			rows++
+		}
+		pending = nil
+		return nil
+	}
+
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		start, startErr := netip.ParseAddr(record[0])
+		end, endErr := netip.ParseAddr(record[1])
+		latitude, latErr := roundCoordinate(record[6], precision)
+		longitude, lonErr := roundCoordinate(record[7], precision)
+		if startErr != nil || endErr != nil || latErr != nil || lonErr != nil || start.Is4() != end.Is4() || end.Less(start) {
+			return rows, fmt.Errorf("line %d: invalid range", line)
+		}
+
+		// Ranges are sorted, so a range that starts right after the pending one and rounds to the same place extends it
+		if pending != nil && pending.end.Next() == start && pending.latitude == latitude && pending.longitude == longitude {
+			pending.end = end
+			continue
+		}
+		if err := flush(); err != nil {
+			return rows, err
+		}
+		pending = &addressRange{start: start, end: end, latitude: latitude, longitude: longitude}
+	}
+	if err := flush(); err != nil {
+		return rows, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return rows, err
+	}
+	return rows, buffered.Flush()
+}
+
+func roundCoordinate(value string, precision int) (string, error) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", err
+	}
+	scale := math.Pow(10, float64(precision))
+	return strconv.FormatFloat(math.Round(parsed*scale)/scale, 'f', precision, 64), nil
+}
+
+// rangePrefixes splits the inclusive range start-end into the largest aligned networks it contains
+func rangePrefixes(start netip.Addr, end netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for {
+		bits := start.BitLen()
+		// Widen the network while it stays aligned on start and ends within the range
+		for bits > 0 {
+			wider := netip.PrefixFrom(start, bits-1)
+			if wider.Masked().Addr() != start || end.Less(lastAddr(wider)) {
+				break
+			}
+			bits--
+		}
+		prefix := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, prefix)
+
+		last := lastAddr(prefix)
+		if last == end || !last.Next().IsValid() {
+			return prefixes
+		}
+		start = last.Next()
+	}
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().As16()
+	offset := 0
+	if prefix.Addr().Is4() {
+		offset = 96
+	}
+	for bit := prefix.Bits() + offset; bit < 128; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	last := netip.AddrFrom16(bytes)
+	if prefix.Addr().Is4() {
+		return last.Unmap()
+	}
+	return last
+}
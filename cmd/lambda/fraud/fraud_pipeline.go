@@ -6,13 +6,11 @@ import (
 
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/ml"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-lambda-go/otellambda"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-lambda-go/otellambda/xrayconfig"
 	"go.opentelemetry.io/contrib/propagators/aws/xray"
@@ -31,15 +29,17 @@ func main() {
 	tableName := config.DBConfig.TableName
 	dbClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), tableName)
 	repository := db.NewTransactionRepository(dbClient)
-	snsClient := sns.NewFromConfig(awsConfig.Config)
+	profileDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ProfileDBConfig.TableName)
+	profileRepository := db.NewAccountProfileRepository(profileDBClient)
+	listDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ListDBConfig.TableName)
+	listRepository := db.NewListRepository(listDBClient)
+	conversationDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ConversationDBConfig.TableName)
+	conversationRepository := db.NewConversationRepository(conversationDBClient)
+	alertLimitDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.AlertLimitDBConfig.TableName)
+	alertLimitRepository := db.NewAlertLimitRepository(alertLimitDBClient)
+	outboxDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.OutboxDBConfig.TableName)
+	outboxRepository := db.NewOutboxRepository(outboxDBClient)
 
-	topicName := config.SNSMessengerConfig.TopicName
-	twilioUsername := config.SNSMessengerConfig.TwilioUsername
-	twiilioPassword := config.SNSMessengerConfig.TwilioPassword
-	topicArn, err := messaging.CreateTopic(snsClient, topicName)
-	if err != nil {
-		log.Fatalf("Failed to create SNS topic: %s\n", err)
-	}
 	// Initialize OpenTelemetry
 	tp, err := xrayconfig.NewTracerProvider(context)
 	if err != nil {
@@ -56,9 +56,12 @@ func main() {
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(xray.Propagator{})
 
-	snsMessenger := messaging.NewGfSNSMessenger(snsClient, topicName, topicArn, twilioUsername, twiilioPassword)
-	eventDispatcher := events.NewGfEventDispatcher(snsMessenger)
-	fraudService := services.NewFraudService(eventDispatcher, repository)
+	fraudService := services.NewFraudService(repository, profileRepository, listRepository, conversationRepository, alertLimitRepository, outboxRepository)
+	configRepository := db.NewConfigRepository(db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ConfigDBConfig.TableName))
+	err = fraudService.ConfigureDetectors(context, ml.NewS3ObjectGetter(awsConfig.Config, config.ModelConfig.S3Endpoint), configRepository)
+	if err != nil {
+		log.Fatalf("Failed to configure fraud detectors: %s\n", err)
+	}
 	fraudHandler := handlers.NewFraudHandler(fraudService)
 
 	lambda.Start(otellambda.InstrumentHandler(fraudHandler.ProcessFraudEvent, xrayconfig.WithRecommendedOptions(tp)...))
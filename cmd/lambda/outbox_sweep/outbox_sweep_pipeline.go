@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func main() {
+	ctx := context.Background()
+	config.InitializeConfig()
+
+	awsConf, err := config.LoadAWSConfig(ctx)
+	if err != nil {
+		fmt.Printf("Error loading AWS config in lambda initialization\n%s", err)
+	}
+
+	tableName := config.DBConfig.TableName
+	dbClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), tableName)
+	repository := db.NewTransactionRepository(dbClient)
+	profileDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ProfileDBConfig.TableName)
+	profileRepository := db.NewAccountProfileRepository(profileDBClient)
+	optOutDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.OptOutDBConfig.TableName)
+	optOutRepository := db.NewOptOutRepository(optOutDBClient)
+	alertLimitDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.AlertLimitDBConfig.TableName)
+	alertLimitRepository := db.NewAlertLimitRepository(alertLimitDBClient)
+	outboxDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.OutboxDBConfig.TableName)
+	outboxRepository := db.NewOutboxRepository(outboxDBClient)
+	snsClient := sns.NewFromConfig(awsConf.Config)
+
+	topicName := config.SNSMessengerConfig.TopicName
+	topicArn, err := messaging.CreateTopic(snsClient, topicName)
+	if err != nil {
+		log.Fatalf("Failed to create SNS topic: %s\n", err)
+	}
+
+	channels, err := messaging.ConfiguredChannels(messaging.ChannelEnvironment{SNSClient: snsClient, TopicArn: topicArn, OptOuts: optOutRepository})
+	if err != nil {
+		log.Fatalf("Failed to configure notification channels: %s\n", err)
+	}
+	deferredQueue := events.ConfiguredDeferredQueue(sqs.NewFromConfig(awsConf.Config))
+	dispatcher := events.NewGfEventDispatcher(channels, templates.ConfiguredCatalog(), profileRepository, alertLimitRepository, deferredQueue)
+	relayService := services.NewRelayService(dispatcher, repository, outboxRepository)
+	relayHandler := handlers.NewRelayHandler(relayService)
+
+	lambda.Start(relayHandler.ProcessSweepEvent)
+}
@@ -11,9 +11,12 @@ import (
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/twilio/twilio-go/client"
 )
 
 func main() {
@@ -28,20 +31,38 @@ func main() {
 	tableName := config.DBConfig.TableName
 	dbClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), tableName)
 	repository := db.NewTransactionRepository(dbClient)
+	profileDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ProfileDBConfig.TableName)
+	profileRepository := db.NewAccountProfileRepository(profileDBClient)
+	conversationDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ConversationDBConfig.TableName)
+	conversationRepository := db.NewConversationRepository(conversationDBClient)
+	optOutDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.OptOutDBConfig.TableName)
+	optOutRepository := db.NewOptOutRepository(optOutDBClient)
+	securityEventDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.SecurityEventDBConfig.TableName)
+	securityEventRepository := db.NewSecurityEventRepository(securityEventDBClient)
+	processedReplyDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ProcessedReplyDBConfig.TableName)
+	processedReplyRepository := db.NewProcessedReplyRepository(processedReplyDBClient)
 	snsClient := sns.NewFromConfig(awsConf.Config)
 
 	topicName := config.SNSMessengerConfig.TopicName
-	twilioUsername := config.SNSMessengerConfig.TwilioUsername
 	twiilioPassword := config.SNSMessengerConfig.TwilioPassword
+	if twiilioPassword == "" {
+		log.Fatalf("Twilio auth token is required to verify inbound messages\n")
+	}
 	topicArn, err := messaging.CreateTopic(snsClient, topicName)
 	if err != nil {
 		log.Fatalf("Failed to create SNS topic: %s\n", err)
 	}
 
-	snsMessenger := messaging.NewGfSNSMessenger(snsClient, topicName, topicArn, twilioUsername, twiilioPassword)
-	dispathcer := events.NewGfEventDispatcher(snsMessenger)
-	responseService := services.NewGfResponseService(dispathcer, repository)
-	responseHandler := handlers.NewResponseHandler(responseService)
+	channels, err := messaging.ConfiguredChannels(messaging.ChannelEnvironment{SNSClient: snsClient, TopicArn: topicArn, OptOuts: optOutRepository})
+	if err != nil {
+		log.Fatalf("Failed to configure notification channels: %s\n", err)
+	}
+	deferredQueue := events.ConfiguredDeferredQueue(sqs.NewFromConfig(awsConf.Config))
+	// Replies are not deduplicated, the same answer is rightly sent to each message that asks for it
+	dispathcer := events.NewGfEventDispatcher(channels, templates.ConfiguredCatalog(), profileRepository, nil, deferredQueue)
+	responseService := services.NewGfResponseService(dispathcer, repository, profileRepository, conversationRepository, optOutRepository, processedReplyRepository)
+	signatureValidator := client.NewRequestValidator(twiilioPassword)
+	responseHandler := handlers.NewResponseHandler(responseService, &signatureValidator, securityEventRepository)
 
 	lambda.Start(responseHandler.ProcessResponseEvent)
 
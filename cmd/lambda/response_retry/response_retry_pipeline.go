@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/twilio/twilio-go/client"
+)
+
+func main() {
+	ctx := context.Background()
+	config.InitializeConfig()
+
+	awsConf, err := config.LoadAWSConfig(ctx)
+	if err != nil {
+		fmt.Printf("Error loading AWS config in lambda initialization\n%s", err)
+	}
+
+	tableName := config.DBConfig.TableName
+	dbClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), tableName)
+	repository := db.NewTransactionRepository(dbClient)
+	profileDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ProfileDBConfig.TableName)
+	profileRepository := db.NewAccountProfileRepository(profileDBClient)
+	conversationDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ConversationDBConfig.TableName)
+	conversationRepository := db.NewConversationRepository(conversationDBClient)
+	optOutDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.OptOutDBConfig.TableName)
+	optOutRepository := db.NewOptOutRepository(optOutDBClient)
+	securityEventDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.SecurityEventDBConfig.TableName)
+	securityEventRepository := db.NewSecurityEventRepository(securityEventDBClient)
+	processedReplyDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConf.Config), config.ProcessedReplyDBConfig.TableName)
+	processedReplyRepository := db.NewProcessedReplyRepository(processedReplyDBClient)
+	snsClient := sns.NewFromConfig(awsConf.Config)
+
+	topicName := config.SNSMessengerConfig.TopicName
+	twiilioPassword := config.SNSMessengerConfig.TwilioPassword
+	if twiilioPassword == "" {
+		log.Fatalf("Twilio auth token is required to verify inbound messages\n")
+	}
+	topicArn, err := messaging.CreateTopic(snsClient, topicName)
+	if err != nil {
+		log.Fatalf("Failed to create SNS topic: %s\n", err)
+	}
+
+	channels, err := messaging.ConfiguredChannels(messaging.ChannelEnvironment{SNSClient: snsClient, TopicArn: topicArn, OptOuts: optOutRepository})
+	if err != nil {
+		log.Fatalf("Failed to configure notification channels: %s\n", err)
+	}
+	deferredQueue := events.ConfiguredDeferredQueue(sqs.NewFromConfig(awsConf.Config))
+	// Replies are not deduplicated, the same answer is rightly sent to each message that asks for it
+	dispathcer := events.NewGfEventDispatcher(channels, templates.ConfiguredCatalog(), profileRepository, nil, deferredQueue)
+	responseService := services.NewGfResponseService(dispathcer, repository, profileRepository, conversationRepository, optOutRepository, processedReplyRepository)
+	signatureValidator := client.NewRequestValidator(twiilioPassword)
+	responseRetryHandler := handlers.NewResponseRetryHandler(responseService, &signatureValidator, securityEventRepository)
+
+	lambda.Start(responseRetryHandler.ProcessDLQResponseEvent)
+
+}
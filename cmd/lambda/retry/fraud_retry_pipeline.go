@@ -6,13 +6,11 @@ import (
 
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/ml"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
 func main() {
@@ -25,23 +23,26 @@ func main() {
 		log.Fatalf("Failed to load AWS configuration: %s\n", err)
 	}
 
-	snsClient := sns.NewFromConfig(awsConfig.Config)
-
 	tableName := config.DBConfig.TableName
 	dbClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), tableName)
 	repository := db.NewTransactionRepository(dbClient)
-
-	topicName := config.SNSMessengerConfig.TopicName
-	topicArn, err := messaging.CreateTopic(snsClient, topicName)
+	profileDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ProfileDBConfig.TableName)
+	profileRepository := db.NewAccountProfileRepository(profileDBClient)
+	listDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ListDBConfig.TableName)
+	listRepository := db.NewListRepository(listDBClient)
+	conversationDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ConversationDBConfig.TableName)
+	conversationRepository := db.NewConversationRepository(conversationDBClient)
+	alertLimitDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.AlertLimitDBConfig.TableName)
+	alertLimitRepository := db.NewAlertLimitRepository(alertLimitDBClient)
+	outboxDBClient := db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.OutboxDBConfig.TableName)
+	outboxRepository := db.NewOutboxRepository(outboxDBClient)
+
+	fraudService := services.NewFraudService(repository, profileRepository, listRepository, conversationRepository, alertLimitRepository, outboxRepository)
+	configRepository := db.NewConfigRepository(db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ConfigDBConfig.TableName))
+	err = fraudService.ConfigureDetectors(context, ml.NewS3ObjectGetter(awsConfig.Config, config.ModelConfig.S3Endpoint), configRepository)
 	if err != nil {
-		log.Fatalf("Failed to create SNS topic: %s\n", err)
+		log.Fatalf("Failed to configure fraud detectors: %s\n", err)
 	}
-
-	twilioUsername := config.SNSMessengerConfig.TwilioUsername
-	twiilioPassword := config.SNSMessengerConfig.TwilioPassword
-	snsMessenger := messaging.NewGfSNSMessenger(snsClient, topicName, topicArn, twilioUsername, twiilioPassword)
-	eventDispatcher := events.NewGfEventDispatcher(snsMessenger)
-	fraudService := services.NewFraudService(eventDispatcher, repository)
 	fraudRetryHandler := handlers.NewFraudRetryHandler(fraudService)
 
 	lambda.Start(fraudRetryHandler.ProcessDLQFraudEvent)
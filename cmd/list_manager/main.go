@@ -0,0 +1,81 @@
+// Command list_manager adds, removes and audits allowlist and denylist entries.
+//
+//	list_manager add -list deny -kind ip -value 203.0.113.7 -ttl 72h -reason "card testing" -actor jdoe
+//	list_manager remove -kind ip -value 203.0.113.7 -reason "false positive" -actor jdoe
+//	list_manager audit -kind ip -value 203.0.113.7
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	list := command.String("list", "", "allow or deny")
+	kind := command.String("kind", "", "merchant, device, ip or account")
+	value := command.String("value", "", "MerchantID, DeviceID, IPAddress or AccountID to match")
+	ttl := command.Duration("ttl", 0, "how long the entry applies, 0 for no expiry")
+	reason := command.String("reason", "", "why the entry is being changed")
+	actor := command.String("actor", os.Getenv("USER"), "who is making the change")
+	if err := command.Parse(os.Args[2:]); err != nil {
+		log.Fatalf("Failed to parse flags: %s\n", err)
+	}
+
+	config.InitializeConfig()
+	ctx := context.Background()
+
+	awsConfig, err := config.LoadAWSConfig(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load AWS configuration: %s\n", err)
+	}
+	listRepository := db.NewListRepository(db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ListDBConfig.TableName))
+
+	switch os.Args[1] {
+	case "add":
+		entry, err := models.NewListEntry(*list, *kind, *value, *reason, *actor, *ttl, time.Now())
+		if err != nil {
+			log.Fatalf("Invalid list entry: %s\n", err)
+		}
+		if err := listRepository.PutListEntry(ctx, *entry); err != nil {
+			log.Fatalf("Failed to add list entry: %s\n", err)
+		}
+		log.Printf("Added %s to the %s list", entry.ListKey, entry.List)
+	case "remove":
+		if err := listRepository.RemoveListEntry(ctx, *kind, *value, *actor, *reason); err != nil {
+			log.Fatalf("Failed to remove list entry: %s\n", err)
+		}
+		log.Printf("Removed %s", models.ListKey(*kind, *value))
+	case "audit":
+		records, err := listRepository.GetListAudit(ctx, *kind, *value)
+		if err != nil {
+			log.Fatalf("Failed to get list audit: %s\n", err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(records); err != nil {
+			log.Fatalf("Failed to print list audit: %s\n", err)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: list_manager add|remove|audit [flags]")
+	os.Exit(2)
+}
@@ -0,0 +1,91 @@
+// Command preferences shows and sets how a customer wants to be contacted.
+//
+//	preferences get -account 12345678
+//	preferences set -account 12345678 -language es
+//	preferences set -account 12345678 -channels email,sms
+//	preferences set -account 12345678 -timezone America/Chicago
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	account := command.String("account", "", "AccountID of the customer")
+	language := command.String("language", "", "locale to send messages in, such as en or es, empty for the default")
+	channels := command.String("channels", "", "comma separated channels to try alerts on in order, such as sms,email,webhook, empty for the default")
+	timeZone := command.String("timezone", "", "IANA time zone for quiet hours, such as America/Chicago, empty to infer it from card use")
+	if err := command.Parse(os.Args[2:]); err != nil {
+		log.Fatalf("Failed to parse flags: %s\n", err)
+	}
+	if *account == "" {
+		log.Fatalf("-account is required\n")
+	}
+
+	config.InitializeConfig()
+	ctx := context.Background()
+
+	awsConfig, err := config.LoadAWSConfig(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load AWS configuration: %s\n", err)
+	}
+	profileRepository := db.NewAccountProfileRepository(db.NewDynamoDBClient(dynamodb.NewFromConfig(awsConfig.Config), config.ProfileDBConfig.TableName))
+
+	preferences, err := profileRepository.GetPreferences(ctx, *account)
+	if err != nil {
+		log.Fatalf("Failed to get preferences: %s\n", err)
+	}
+
+	switch os.Args[1] {
+	case "get":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(preferences); err != nil {
+			log.Fatalf("Failed to print preferences: %s\n", err)
+		}
+	case "set":
+		if *language != "" && !templates.ConfiguredCatalog().HasLocale(*language) {
+			log.Fatalf("No templates for language %q\n", *language)
+		}
+		if _, err := time.LoadLocation(*timeZone); *timeZone != "" && err != nil {
+			log.Fatalf("Unknown time zone %q: %s\n", *timeZone, err)
+		}
+		preferences.Language = *language
+		preferences.TimeZone = *timeZone
+		preferences.Channels = nil
+		for _, channel := range strings.Split(*channels, ",") {
+			if channel = strings.TrimSpace(channel); channel != "" {
+				preferences.Channels = append(preferences.Channels, channel)
+			}
+		}
+		preferences.UpdatedAt = time.Now().Unix()
+		if err := profileRepository.SavePreferences(ctx, preferences); err != nil {
+			log.Fatalf("Failed to save preferences: %s\n", err)
+		}
+		log.Printf("Set language of account %s to %q, channels to %v and time zone to %q", *account, *language, preferences.Channels, *timeZone)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: preferences get|set -account ID [-language LOCALE] [-channels CHANNELS] [-timezone ZONE]")
+	os.Exit(2)
+}
@@ -0,0 +1,620 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// MemoryTransactionRepository implements db.TransactionRepository in memory, so velocity and travel
+// features are computed from the transactions already replayed.
+type MemoryTransactionRepository struct {
+	mu           sync.RWMutex
+	transactions map[string]*models.Transaction
+	byAccount    map[string][]string
+	byDevice     map[string][]string
+}
+
+func NewMemoryTransactionRepository() *MemoryTransactionRepository {
+	return &MemoryTransactionRepository{
+		transactions: make(map[string]*models.Transaction),
+		byAccount:    make(map[string][]string),
+		byDevice:     make(map[string][]string),
+	}
+}
+
+// SaveTransaction stores a copy of the transaction. Unlike the DynamoDB repository it does not stamp transactions
+// without a usable TransactionDate with the current time, since every replayed row would then look simultaneous.
+func (r *MemoryTransactionRepository) SaveTransaction(ctx context.Context, t *models.Transaction) (*dynamodb.PutItemOutput, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := transactionKey(t.AccountID, t.TransactionID)
+	if _, exists := r.transactions[key]; exists {
+		return nil, "", fmt.Errorf("transaction already exists")
+	}
+
+	stored := *t
+	if stored.TransactionTimestamp == 0 {
+		if transactionTime := stored.GetTransactionTime(); !transactionTime.IsZero() {
+			stored.TransactionTimestamp = transactionTime.Unix()
+		}
+	}
+	r.transactions[key] = &stored
+	r.byAccount[stored.AccountID] = append(r.byAccount[stored.AccountID], key)
+	if stored.DeviceID != "" {
+		r.byDevice[stored.DeviceID] = append(r.byDevice[stored.DeviceID], key)
+	}
+	return &dynamodb.PutItemOutput{}, "", nil
+}
+
+func (r *MemoryTransactionRepository) GetTransaction(ctx context.Context, accountID, transactionID string) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.transactions[transactionKey(accountID, transactionID)]
+	if !ok {
+		return nil, fmt.Errorf("item not found")
+	}
+	txn := *stored
+	return &txn, nil
+}
+
+func (r *MemoryTransactionRepository) GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	return r.inRange(r.byAccount, accountID, start, end), nil
+}
+
+func (r *MemoryTransactionRepository) GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	return r.inRange(r.byDevice, deviceID, start, end), nil
+}
+
+func (r *MemoryTransactionRepository) inRange(index map[string][]string, value string, start time.Time, end time.Time) []models.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.Transaction
+	for _, key := range index[value] {
+		txn := r.transactions[key]
+		if txn.TransactionTimestamp == 0 {
+			continue
+		}
+		if txnTime := txn.GetTransactionTime(); !txnTime.Before(start) && !txnTime.After(end) {
+			result = append(result, *txn)
+		}
+	}
+	return result
+}
+
+// UpdateTransaction replaces the stored transaction, keeping its timestamp
+func (r *MemoryTransactionRepository) UpdateTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) (*dynamodb.UpdateItemOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := transactionKey(accountID, transactionID)
+	stored, ok := r.transactions[key]
+	if !ok {
+		return nil, fmt.Errorf("item not found")
+	}
+	updated := *values
+	updated.TransactionTimestamp = stored.TransactionTimestamp
+	r.transactions[key] = &updated
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// UpdatePendingTransaction replaces the stored transaction while it is still POTENTIAL_FRAUD
+func (r *MemoryTransactionRepository) UpdatePendingTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := transactionKey(accountID, transactionID)
+	stored, ok := r.transactions[key]
+	if !ok || stored.TransactionStatus != "POTENTIAL_FRAUD" {
+		return db.ErrTransactionResolved
+	}
+	updated := *values
+	updated.TransactionTimestamp = stored.TransactionTimestamp
+	r.transactions[key] = &updated
+	return nil
+}
+
+// UpdateAlertChannel sets the stored transaction's AlertChannel and AlertedAt
+func (r *MemoryTransactionRepository) UpdateAlertChannel(ctx context.Context, accountID, transactionID string, channel string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.transactions[transactionKey(accountID, transactionID)]
+	if !ok {
+		return fmt.Errorf("item not found")
+	}
+	stored.AlertChannel = channel
+	stored.AlertedAt = time.Now().Unix()
+	return nil
+}
+
+// UpdateReplyCode sets the stored transaction's ReplyCode while it is still POTENTIAL_FRAUD
+func (r *MemoryTransactionRepository) UpdateReplyCode(ctx context.Context, accountID, transactionID string, replyCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.transactions[transactionKey(accountID, transactionID)]
+	if !ok || stored.TransactionStatus != "POTENTIAL_FRAUD" {
+		return db.ErrTransactionResolved
+	}
+	stored.ReplyCode = replyCode
+	return nil
+}
+
+func (r *MemoryTransactionRepository) DeleteTransaction(ctx context.Context, accountID, transactionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.transactions, transactionKey(accountID, transactionID))
+	return nil
+}
+
+func transactionKey(accountID string, transactionID string) string {
+	return accountID + "#" + transactionID
+}
+
+// MemoryProfileRepository implements db.AccountProfileRepository in memory, learning devices and spending
+// from approved transactions the same way the DynamoDB repository does.
+type MemoryProfileRepository struct {
+	mu          sync.RWMutex
+	devices     map[string]*models.DeviceProfile
+	baselines   map[string]map[string]*models.SpendingBaseline
+	preferences map[string]models.AccountPreferences
+}
+
+func NewMemoryProfileRepository() *MemoryProfileRepository {
+	return &MemoryProfileRepository{
+		devices:     make(map[string]*models.DeviceProfile),
+		baselines:   make(map[string]map[string]*models.SpendingBaseline),
+		preferences: make(map[string]models.AccountPreferences),
+	}
+}
+
+func (r *MemoryProfileRepository) GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profile := models.NewDeviceProfile(accountID)
+	if stored, ok := r.devices[accountID]; ok {
+		for value, sighting := range stored.Devices {
+			profile.Devices[value] = sighting
+		}
+		for value, sighting := range stored.IPAddresses {
+			profile.IPAddresses[value] = sighting
+		}
+	}
+	return profile, nil
+}
+
+func (r *MemoryProfileRepository) RecordDeviceSighting(ctx context.Context, transaction models.Transaction, seenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.devices[transaction.AccountID]
+	if !ok {
+		profile = models.NewDeviceProfile(transaction.AccountID)
+		r.devices[transaction.AccountID] = profile
+	}
+	if transaction.DeviceID != "" {
+		profile.Devices[transaction.DeviceID] = recordSighting(profile.Devices[transaction.DeviceID], transaction.AccountID, models.SightingDevice, transaction.DeviceID, seenAt)
+	}
+	if transaction.IPAddress != "" {
+		profile.IPAddresses[transaction.IPAddress] = recordSighting(profile.IPAddresses[transaction.IPAddress], transaction.AccountID, models.SightingIP, transaction.IPAddress, seenAt)
+	}
+	return nil
+}
+
+func recordSighting(sighting models.Sighting, accountID string, kind string, value string, seenAt time.Time) models.Sighting {
+	if sighting.SeenCount == 0 {
+		sighting = models.Sighting{
+			AccountID:  accountID,
+			ProfileKey: models.ProfileKey(kind, value),
+			Kind:       kind,
+			Value:      value,
+			FirstSeen:  seenAt.Unix(),
+		}
+	}
+	sighting.LastSeen = seenAt.Unix()
+	sighting.SeenCount++
+	return sighting
+}
+
+func (r *MemoryProfileRepository) GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	baselines := make(map[string]*models.SpendingBaseline)
+	for key, baseline := range r.baselines[accountID] {
+		copied := *baseline
+		copied.Histogram = append([]int64(nil), baseline.Histogram...)
+		baselines[key] = &copied
+	}
+	return baselines, nil
+}
+
+func (r *MemoryProfileRepository) RecordSpending(ctx context.Context, transaction models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.baselines[transaction.AccountID] == nil {
+		r.baselines[transaction.AccountID] = make(map[string]*models.SpendingBaseline)
+	}
+	for _, baseline := range []*models.SpendingBaseline{
+		models.NewSpendingBaseline(transaction.AccountID, transaction.TransactionType, transaction.Channel),
+		models.NewSpendingBaseline(transaction.AccountID, models.AllSegments, models.AllSegments),
+	} {
+		stored, ok := r.baselines[transaction.AccountID][baseline.ProfileKey]
+		if !ok {
+			stored = baseline
+			r.baselines[transaction.AccountID][baseline.ProfileKey] = stored
+		}
+		stored.Add(transaction.TransactionAmount)
+	}
+	return nil
+}
+
+func (r *MemoryProfileRepository) GetPreferences(ctx context.Context, accountID string) (*models.AccountPreferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferences, ok := r.preferences[accountID]
+	if !ok {
+		return models.NewAccountPreferences(accountID), nil
+	}
+	return &preferences, nil
+}
+
+func (r *MemoryProfileRepository) SavePreferences(ctx context.Context, preferences *models.AccountPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.preferences[preferences.AccountID] = *preferences
+	return nil
+}
+
+// MemoryListRepository implements db.ListRepository in memory, for replaying with a fixed set of list entries.
+type MemoryListRepository struct {
+	mu      sync.RWMutex
+	entries map[string]models.ListEntry
+	audit   map[string][]models.ListAuditRecord
+}
+
+func NewMemoryListRepository(entries ...models.ListEntry) *MemoryListRepository {
+	repository := &MemoryListRepository{
+		entries: make(map[string]models.ListEntry),
+		audit:   make(map[string][]models.ListAuditRecord),
+	}
+	for _, entry := range entries {
+		repository.entries[entry.ListKey] = entry
+	}
+	return repository
+}
+
+func (r *MemoryListRepository) GetListEntries(ctx context.Context, listKeys []string) ([]models.ListEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []models.ListEntry
+	for _, listKey := range listKeys {
+		if entry, ok := r.entries[listKey]; ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *MemoryListRepository) PutListEntry(ctx context.Context, entry models.ListEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[entry.ListKey] = entry
+	r.audit[entry.ListKey] = append(r.audit[entry.ListKey], entry.AuditRecord(models.ListActionAdd, entry.CreatedBy, entry.Reason, time.Now()))
+	return nil
+}
+
+func (r *MemoryListRepository) RemoveListEntry(ctx context.Context, kind string, value string, actor string, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listKey := models.ListKey(kind, value)
+	entry, ok := r.entries[listKey]
+	if !ok {
+		return fmt.Errorf("no list entry for %s", listKey)
+	}
+	delete(r.entries, listKey)
+	r.audit[listKey] = append(r.audit[listKey], entry.AuditRecord(models.ListActionRemove, actor, reason, time.Now()))
+	return nil
+}
+
+func (r *MemoryListRepository) GetListAudit(ctx context.Context, kind string, value string) ([]models.ListAuditRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.ListAuditRecord(nil), r.audit[models.ListKey(kind, value)]...), nil
+}
+
+// MemoryConversationRepository implements db.ConversationRepository in memory. Backtests never receive replies,
+// so it only keeps the conversations alerts would have opened.
+type MemoryConversationRepository struct {
+	mu            sync.RWMutex
+	conversations map[string]models.Conversation
+}
+
+func NewMemoryConversationRepository() *MemoryConversationRepository {
+	return &MemoryConversationRepository{conversations: make(map[string]models.Conversation)}
+}
+
+func (r *MemoryConversationRepository) StartConversation(ctx context.Context, conversation *models.Conversation) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := conversation.PhoneNumber + "#" + conversation.AlertID
+	if _, exists := r.conversations[key]; exists {
+		return false, nil
+	}
+	r.conversations[key] = *conversation
+	return true, nil
+}
+
+func (r *MemoryConversationRepository) GetOpenConversations(ctx context.Context, phoneNumber string) ([]models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var open []models.Conversation
+	for _, conversation := range r.conversations {
+		if conversation.PhoneNumber == phoneNumber && conversation.IsOpen() {
+			open = append(open, conversation)
+		}
+	}
+	return open, nil
+}
+
+func (r *MemoryConversationRepository) UpdateConversation(ctx context.Context, conversation *models.Conversation, expectedState string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := conversation.PhoneNumber + "#" + conversation.AlertID
+	if stored, exists := r.conversations[key]; !exists || stored.State != expectedState {
+		return db.ErrConversationChanged
+	}
+	r.conversations[key] = *conversation
+	return nil
+}
+
+func (r *MemoryConversationRepository) GetAwaitingConversations(ctx context.Context, createdBefore time.Time) ([]models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var awaiting []models.Conversation
+	for _, conversation := range r.conversations {
+		if conversation.IsOpen() && conversation.CreatedAt < createdBefore.Unix() {
+			awaiting = append(awaiting, conversation)
+		}
+	}
+	return awaiting, nil
+}
+
+// MemoryAlertLimitRepository implements db.AlertLimitRepository in memory, with the same windows and sent message
+// records as the DynamoDB repository.
+type MemoryAlertLimitRepository struct {
+	mu      sync.Mutex
+	windows map[string]models.AlertWindow
+	sent    map[string]models.SentMessage
+}
+
+func NewMemoryAlertLimitRepository() *MemoryAlertLimitRepository {
+	return &MemoryAlertLimitRepository{
+		windows: make(map[string]models.AlertWindow),
+		sent:    make(map[string]models.SentMessage),
+	}
+}
+
+func (r *MemoryAlertLimitRepository) AdmitAlert(ctx context.Context, limitKey string, alert models.HeldAlert, now time.Time, window time.Duration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.windows[limitKey]
+	if ok {
+		if _, held := existing.Held[alert.TransactionID]; held {
+			return models.AlertHeld, nil
+		}
+		if existing.FirstID == alert.TransactionID {
+			return models.AlertSendNow, nil
+		}
+		if existing.Accepts(now) {
+			existing.Held[alert.TransactionID] = alert
+			existing.DigestState = models.DigestPending
+			r.windows[limitKey] = existing
+			return models.AlertHeld, nil
+		}
+	}
+	r.windows[limitKey] = *models.NewAlertWindow(limitKey, alert.TransactionID, now, window, config.AlertLimitConfig.DedupTTL)
+	return models.AlertSendNow, nil
+}
+
+func (r *MemoryAlertLimitRepository) GetDueDigests(ctx context.Context, now time.Time) ([]models.AlertWindow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.AlertWindow
+	for _, window := range r.windows {
+		if window.DigestState == models.DigestPending && window.WindowEnd <= now.Unix() {
+			copied := window
+			copied.Held = make(map[string]models.HeldAlert, len(window.Held))
+			for id, alert := range window.Held {
+				copied.Held[id] = alert
+			}
+			due = append(due, copied)
+		}
+	}
+	return due, nil
+}
+
+func (r *MemoryAlertLimitRepository) CompleteDigest(ctx context.Context, limitKey string, sent []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	window, ok := r.windows[limitKey]
+	if !ok {
+		return fmt.Errorf("no alert window %s", limitKey)
+	}
+	for _, transactionID := range sent {
+		delete(window.Held, transactionID)
+	}
+	if len(window.Held) == 0 {
+		window.DigestState = ""
+	}
+	r.windows[limitKey] = window
+	return nil
+}
+
+func (r *MemoryAlertLimitRepository) ClaimMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.sent[key]; ok && existing.ExpiresAt > now.Unix() {
+		return false, nil
+	}
+	r.sent[key] = models.SentMessage{LimitKey: key, SentAt: now.Unix(), ExpiresAt: now.Add(ttl).Unix()}
+	return true, nil
+}
+
+func (r *MemoryAlertLimitRepository) ConfirmMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent[key] = models.SentMessage{LimitKey: key, SentAt: now.Unix(), ExpiresAt: now.Add(ttl).Unix()}
+	return nil
+}
+
+func (r *MemoryAlertLimitRepository) ReleaseMessage(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sent, key)
+	return nil
+}
+
+// MemoryOutboxRepository implements db.OutboxRepository in memory. Flagging a transaction updates it in the
+// MemoryTransactionRepository and records its notification under one lock.
+type MemoryOutboxRepository struct {
+	mu            sync.Mutex
+	transactions  *MemoryTransactionRepository
+	notifications map[string]models.OutboxNotification
+}
+
+func NewMemoryOutboxRepository(transactions *MemoryTransactionRepository) *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{
+		transactions:  transactions,
+		notifications: make(map[string]models.OutboxNotification),
+	}
+}
+
+func (r *MemoryOutboxRepository) FlagTransaction(ctx context.Context, txn *models.Transaction, notification *models.OutboxNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.notifications[notification.OutboxID]; ok {
+		return db.ErrNotificationRecorded
+	}
+	if _, err := r.transactions.UpdateTransaction(ctx, txn.AccountID, txn.TransactionID, txn); err != nil {
+		return err
+	}
+	r.notifications[notification.OutboxID] = *notification
+	return nil
+}
+
+func (r *MemoryOutboxRepository) GetNotification(ctx context.Context, outboxID string) (*models.OutboxNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[outboxID]
+	if !ok {
+		return nil, fmt.Errorf("item not found")
+	}
+	return &notification, nil
+}
+
+func (r *MemoryOutboxRepository) GetPendingNotifications(ctx context.Context, createdBefore time.Time) ([]models.OutboxNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []models.OutboxNotification
+	for _, notification := range r.notifications {
+		if notification.IsPending() && notification.CreatedAt < createdBefore.Unix() {
+			pending = append(pending, notification)
+		}
+	}
+	return pending, nil
+}
+
+func (r *MemoryOutboxRepository) MarkSent(ctx context.Context, outboxID string, channel string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[outboxID]
+	if !ok || !notification.IsPending() {
+		return nil
+	}
+	notification.PendingState = ""
+	notification.Channel = channel
+	notification.SentAt = now.Unix()
+	notification.ExpiresAt = now.Add(config.OutboxConfig.SentTTL).Unix()
+	r.notifications[outboxID] = notification
+	return nil
+}
+
+func (r *MemoryOutboxRepository) MarkUndeliverable(ctx context.Context, outboxID string, reason string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[outboxID]
+	if !ok || !notification.IsPending() {
+		return nil
+	}
+	notification.PendingState = ""
+	notification.FailedAt = now.Unix()
+	notification.FailureReason = reason
+	notification.ExpiresAt = now.Add(config.OutboxConfig.SentTTL).Unix()
+	r.notifications[outboxID] = notification
+	return nil
+}
+
+// AlertRecorder implements events.EventDispatcher by counting alerts instead of sending them.
+type AlertRecorder struct {
+	mu     sync.Mutex
+	Alerts int
+}
+
+func (a *AlertRecorder) DispatchFraudAlertEvent(transaction models.Transaction) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Alerts++
+	return "", nil
+}
+
+func (a *AlertRecorder) DispatchFraudUpdateEvent(number string, notification models.Notification) error {
+	return nil
+}
+
+func (a *AlertRecorder) DispatchFraudEscalationEvent(transaction models.Transaction) (string, error) {
+	return "", nil
+}
+
+func (a *AlertRecorder) DispatchFraudDigestEvent(transactions []models.Transaction) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Alerts++
+	return "", nil
+}
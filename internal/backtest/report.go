@@ -0,0 +1,167 @@
+package backtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// ScoreBucketWidth is the width of each risk score histogram bucket
+const ScoreBucketWidth = 100
+
+// ScoreHistogram counts risk scores in buckets of ScoreBucketWidth, with MaxRiskScore in the last bucket
+type ScoreHistogram [fraud.MaxRiskScore / ScoreBucketWidth]int
+
+func (h *ScoreHistogram) Add(score int) {
+	bucket := min(fraud.ClampRiskScore(score)/ScoreBucketWidth, len(h)-1)
+	h[bucket]++
+}
+
+// Confusion counts detector decisions against labels
+type Confusion struct {
+	TruePositives  int `json:"truePositives"`
+	FalsePositives int `json:"falsePositives"`
+	TrueNegatives  int `json:"trueNegatives"`
+	FalseNegatives int `json:"falseNegatives"`
+}
+
+func (c *Confusion) Add(flagged bool, isFraud bool) {
+	switch {
+	case flagged && isFraud:
+		c.TruePositives++
+	case flagged:
+		c.FalsePositives++
+	case isFraud:
+		c.FalseNegatives++
+	default:
+		c.TrueNegatives++
+	}
+}
+
+// Precision is the share of alerts that were fraud
+func (c Confusion) Precision() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalsePositives)
+}
+
+// Recall is the share of fraud that was alerted on
+func (c Confusion) Recall() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalseNegatives)
+}
+
+// FalsePositiveRate is the share of legitimate transactions that were alerted on
+func (c Confusion) FalsePositiveRate() float64 {
+	return ratio(c.FalsePositives, c.FalsePositives+c.TrueNegatives)
+}
+
+// RuleStats describes one reason code. Triggering counts whenever the rule fired, even if the total score
+// stayed under the threshold, while Confusion only counts the transactions that were also alerted on.
+type RuleStats struct {
+	Triggered      int            `json:"triggered"`
+	TriggeredFraud int            `json:"triggeredFraud"`
+	Confusion      Confusion      `json:"confusion"`
+	ScoreHistogram ScoreHistogram `json:"scoreHistogram"`
+}
+
+// Report summarizes a backtest run
+type Report struct {
+	Transactions   int                   `json:"transactions"`
+	Failed         int                   `json:"failed"`
+	Alerts         int                   `json:"alerts"`
+	Confusion      Confusion             `json:"confusion"`
+	ScoreHistogram ScoreHistogram        `json:"scoreHistogram"`
+	FraudScores    ScoreHistogram        `json:"fraudScoreHistogram"`
+	Rules          map[string]*RuleStats `json:"rules"`
+}
+
+func NewReport() *Report {
+	return &Report{Rules: make(map[string]*RuleStats)}
+}
+
+// Add records a scored transaction. flagged is whether the fraud service alerted on it and isFraud is its label.
+func (r *Report) Add(transaction models.Transaction, flagged bool, isFraud bool) {
+	r.Transactions++
+	if flagged {
+		r.Alerts++
+	}
+	r.Confusion.Add(flagged, isFraud)
+	r.ScoreHistogram.Add(transaction.RiskScore)
+	if isFraud {
+		r.FraudScores.Add(transaction.RiskScore)
+	}
+
+	for _, reasonCode := range transaction.ReasonCodes {
+		stats, ok := r.Rules[reasonCode]
+		if !ok {
+			stats = &RuleStats{}
+			r.Rules[reasonCode] = stats
+		}
+		stats.Triggered++
+		if isFraud {
+			stats.TriggeredFraud++
+		}
+		if flagged {
+			stats.Confusion.Add(flagged, isFraud)
+		}
+		stats.ScoreHistogram.Add(transaction.RiskScore)
+	}
+}
+
+// AddFailure records a transaction the fraud service failed to score
+func (r *Report) AddFailure() {
+	r.Failed++
+}
+
+// WriteText prints the report as aligned tables
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Transactions\t%d\n", r.Transactions)
+	fmt.Fprintf(tw, "Failed\t%d\n", r.Failed)
+	fmt.Fprintf(tw, "Alerts\t%d (%.2f%%)\n", r.Alerts, 100*ratio(r.Alerts, r.Transactions))
+	fmt.Fprintf(tw, "Precision\t%.4f\n", r.Confusion.Precision())
+	fmt.Fprintf(tw, "Recall\t%.4f\n", r.Confusion.Recall())
+	fmt.Fprintf(tw, "False positive rate\t%.4f\n", r.Confusion.FalsePositiveRate())
+	fmt.Fprintf(tw, "TP / FP / TN / FN\t%d / %d / %d / %d\n",
+		r.Confusion.TruePositives, r.Confusion.FalsePositives, r.Confusion.TrueNegatives, r.Confusion.FalseNegatives)
+
+	fmt.Fprintf(tw, "\nScore\tAll\tFraud\n")
+	for bucket := range r.ScoreHistogram {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", bucketLabel(bucket), r.ScoreHistogram[bucket], r.FraudScores[bucket])
+	}
+
+	reasonCodes := make([]string, 0, len(r.Rules))
+	for reasonCode := range r.Rules {
+		reasonCodes = append(reasonCodes, reasonCode)
+	}
+	sort.Strings(reasonCodes)
+
+	fmt.Fprintf(tw, "\nReason code\tTriggered\tOn fraud\tAlerts\tAlert precision\tScores by bucket\n")
+	for _, reasonCode := range reasonCodes {
+		stats := r.Rules[reasonCode]
+		alerts := stats.Confusion.TruePositives + stats.Confusion.FalsePositives
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.4f\t%v\n",
+			reasonCode, stats.Triggered, stats.TriggeredFraud, alerts, stats.Confusion.Precision(), stats.ScoreHistogram)
+	}
+
+	return tw.Flush()
+}
+
+func bucketLabel(bucket int) string {
+	low := bucket * ScoreBucketWidth
+	high := low + ScoreBucketWidth - 1
+	if bucket == len(ScoreHistogram{})-1 {
+		high = fraud.MaxRiskScore
+	}
+	return fmt.Sprintf("%d-%d", low, high)
+}
+
+func ratio(numerator int, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
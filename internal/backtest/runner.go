@@ -0,0 +1,144 @@
+// Package backtest replays labeled transactions through the fraud service with in-memory storage, so a
+// detector configuration can be measured before it is deployed. No AWS services are called.
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var errOfflineOnly = errors.New("backtests only read models and rule files from local paths")
+
+// Runner replays transactions through a GfFraudService backed by in-memory repositories. The alerts it records
+// in Outbox are relayed to Alerts right away, as the outbox relay does.
+type Runner struct {
+	FraudService  *services.GfFraudService
+	Relay         *services.GfRelayService
+	Transactions  *MemoryTransactionRepository
+	Profiles      *MemoryProfileRepository
+	Lists         *MemoryListRepository
+	Conversations *MemoryConversationRepository
+	Outbox        *MemoryOutboxRepository
+	Alerts        *AlertRecorder
+}
+
+// NewRunner builds a fraud service with the detectors selected by config.ModelConfig and config.RulesConfig,
+// the same way the fraud Lambda does. lists are consulted before scoring like the live allowlist and denylist.
+func NewRunner(ctx context.Context, lists ...models.ListEntry) (*Runner, error) {
+	runner := &Runner{
+		Transactions:  NewMemoryTransactionRepository(),
+		Profiles:      NewMemoryProfileRepository(),
+		Lists:         NewMemoryListRepository(lists...),
+		Conversations: NewMemoryConversationRepository(),
+		Alerts:        &AlertRecorder{},
+	}
+	runner.Outbox = NewMemoryOutboxRepository(runner.Transactions)
+	runner.FraudService = services.NewFraudService(runner.Transactions, runner.Profiles, runner.Lists, runner.Conversations, nil, runner.Outbox)
+	runner.Relay = services.NewRelayService(runner.Alerts, runner.Transactions, runner.Outbox)
+	// The report covers challengers, and metrics written to stdout would mix into it
+	runner.FraudService.MetricsOutput = io.Discard
+	if err := runner.FraudService.ConfigureDetectors(ctx, offlineSource{}, offlineSource{}); err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
+
+// Replay streams a CSV in the bank transactions export format through the fraud service in file order, one
+// transaction at a time so each sees the history of the ones before it. labelColumn marks known fraud.
+func (r *Runner) Replay(ctx context.Context, input io.Reader, labelColumn string) (*Report, error) {
+	reader := csv.NewReader(input)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := models.CSVColumns(header)
+	labelIndex, ok := columns[labelColumn]
+	if !ok {
+		return nil, fmt.Errorf("CSV has no %s label column", labelColumn)
+	}
+
+	report := NewReport()
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", row, err)
+		}
+
+		isFraud, err := ParseLabel(record[labelIndex])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		transaction, err := models.ParseCSVTransaction(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		scored, flagged, err := r.score(ctx, transaction)
+		if err != nil {
+			log.Printf("Row %d: %s", row, err)
+			report.AddFailure()
+			continue
+		}
+		report.Add(*scored, flagged, isFraud)
+	}
+
+	return report, nil
+}
+
+func (r *Runner) score(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
+	if _, _, err := r.Transactions.SaveTransaction(ctx, &transaction); err != nil {
+		return nil, false, fmt.Errorf("failed to save transaction %s: %w", transaction.TransactionID, err)
+	}
+
+	fraudulent, failed, err := r.FraudService.PredictFraud(ctx, []models.Transaction{transaction})
+	if len(failed) > 0 {
+		return nil, false, err
+	}
+	if len(fraudulent) > 0 {
+		outboxID := models.AlertOutboxID(transaction.AccountID, transaction.TransactionID)
+		if _, err := r.Relay.RelayNotifications(ctx, []string{outboxID}); err != nil {
+			return nil, false, err
+		}
+	}
+
+	scored, err := r.Transactions.GetTransaction(ctx, transaction.AccountID, transaction.TransactionID)
+	if err != nil {
+		return nil, false, err
+	}
+	return scored, len(fraudulent) > 0, nil
+}
+
+// ParseLabel reads a fraud label such as 1/0, true/false, yes/no or fraud/legit
+func ParseLabel(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "y", "fraud":
+		return true, nil
+	case "0", "false", "no", "n", "legit", "legitimate":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unrecognized fraud label %q", value)
+	}
+}
+
+// offlineSource rejects S3 and DynamoDB model and rule file paths
+type offlineSource struct{}
+
+func (offlineSource) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, errOfflineOnly
+}
+
+func (offlineSource) GetConfigItem(ctx context.Context, configID string) (*models.ConfigItem, error) {
+	return nil, errOfflineOnly
+}
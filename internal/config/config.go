@@ -7,6 +7,9 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -62,6 +65,147 @@ var DBConfig = &struct {
 	}
 }{}
 
+// ProfileDBConfig stores the account profile table settings
+var ProfileDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+		SortKey      string
+	}
+}{}
+
+// ConfigDBConfig stores the config table settings, which holds documents such as rule files
+var ConfigDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+	}
+}{}
+
+// ListDBConfig stores the allowlist and denylist table settings
+var ListDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+		SortKey      string
+	}
+}{}
+
+// ConversationDBConfig stores the SMS conversation table settings. StateIndex finds conversations in a state by
+// when they were created.
+var ConversationDBConfig = &struct {
+	TableName  string
+	StateIndex string
+	Keys       struct {
+		PartitionKey string
+		SortKey      string
+	}
+}{}
+
+// EscalationConfig is the policy for fraud alerts the customer has not answered. After ReminderAfter the alert is
+// texted again, after SecondaryChannelAfter it is emailed, and after FinalActionAfter, or once the conversation
+// expires, FinalAction (HOLD or DECLINE) is applied. A zero duration skips that step.
+var EscalationConfig = &struct {
+	ReminderAfter         time.Duration
+	SecondaryChannelAfter time.Duration
+	FinalActionAfter      time.Duration
+	FinalAction           string
+}{
+	ReminderAfter:         30 * time.Minute,
+	SecondaryChannelAfter: 2 * time.Hour,
+	FinalActionAfter:      24 * time.Hour,
+	FinalAction:           "HOLD",
+}
+
+// OptOutDBConfig stores the SMS opt-out registry table settings
+var OptOutDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+	}
+}{}
+
+// SecurityEventDBConfig stores the security event log table settings
+var SecurityEventDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+		SortKey      string
+	}
+}{}
+
+// ProcessedReplyDBConfig stores the inbound SMS idempotency table settings
+var ProcessedReplyDBConfig = &struct {
+	TableName string
+	Keys      struct {
+		PartitionKey string
+	}
+}{}
+
+// ProcessedReplyConfig controls how long a reply's MessageSid is remembered, and how long a claim on a reply
+// lasts before another delivery may take it over
+var ProcessedReplyConfig = &struct {
+	TTL   time.Duration
+	Lease time.Duration
+}{
+	TTL:   7 * 24 * time.Hour,
+	Lease: 5 * time.Minute,
+}
+
+// AlertLimitDBConfig stores the outbound alert limit table settings. It holds each recipient's digest window and
+// the messages recently sent. DigestIndex finds windows with alerts waiting to be sent by when they are due.
+var AlertLimitDBConfig = &struct {
+	TableName   string
+	DigestIndex string
+	Keys        struct {
+		PartitionKey string
+	}
+}{}
+
+// AlertLimitConfig limits fraud alerts per phone number, or email when there is none. The first alert is sent
+// right away, and alerts flagged in the Window after it are held and sent together as one digest when the window
+// ends. Identical messages to the same address are sent at most once per DedupTTL. A message being sent is claimed
+// for ClaimLease first, so one whose sender stopped before sending it can be sent again once the lease runs out.
+var AlertLimitConfig = &struct {
+	Window     time.Duration
+	DedupTTL   time.Duration
+	ClaimLease time.Duration
+}{
+	Window:     10 * time.Minute,
+	DedupTTL:   24 * time.Hour,
+	ClaimLease: 5 * time.Minute,
+}
+
+// OutboxDBConfig stores the notification outbox table settings. Fraud alerts are written to it in the same
+// transaction that flags their transaction, and PendingIndex finds the ones not sent yet by when they were written.
+var OutboxDBConfig = &struct {
+	TableName    string
+	PendingIndex string
+	Keys         struct {
+		PartitionKey string
+	}
+}{}
+
+// OutboxConfig controls the relay sending outbox notifications. Notifications still pending SweepAfter they were
+// written are swept up by the scheduled relay, which stops trying to deliver them GiveUpAfter they were written.
+// Sent and undeliverable notifications are kept for SentTTL.
+var OutboxConfig = &struct {
+	SweepAfter  time.Duration
+	GiveUpAfter time.Duration
+	SentTTL     time.Duration
+}{
+	SweepAfter:  2 * time.Minute,
+	GiveUpAfter: 24 * time.Hour,
+	SentTTL:     7 * 24 * time.Hour,
+}
+
+// ConversationConfig controls how long a fraud alert waits for the customer's reply
+var ConversationConfig = &struct {
+	TTL time.Duration
+}{
+	TTL: 24 * time.Hour,
+}
+
 var SNSMessengerConfig = &struct {
 	TopicName      string
 	TwilioUsername string
@@ -77,6 +221,132 @@ var HandlerConfig = &struct {
 	IsRetry bool
 }{}
 
+// FraudConfig stores fraud rule engine settings, scores are on a 0-1000 scale and defaults are used until InitializeConfig runs
+var FraudConfig = &struct {
+	ScoreThreshold         int
+	AmountThreshold        float64
+	MaxLoginAttempts       int
+	MinTransactionDuration int
+	MaxTransactionDuration int
+	HighRiskChannels       []string
+	HighRiskMerchants      []string
+	VelocityMaxCount5m     int
+	VelocityMaxCount1h     int
+	VelocityMaxCount24h    int
+	VelocityMaxAmount1h    float64
+	VelocityMaxAmount24h   float64
+	MaxTravelSpeedKmh      float64
+	MinTravelDistanceKm    float64
+	NewDeviceHighAmount    float64
+	BaselineMinSamples     int64
+	BaselineMaxZScore      float64
+	BaselineMaxPercentile  float64
+}{
+	ScoreThreshold:         600,
+	AmountThreshold:        1000,
+	MaxLoginAttempts:       3,
+	MinTransactionDuration: 5,
+	MaxTransactionDuration: 600,
+	VelocityMaxCount5m:     3,
+	VelocityMaxCount1h:     10,
+	VelocityMaxCount24h:    25,
+	VelocityMaxAmount1h:    3000,
+	VelocityMaxAmount24h:   10000,
+	MaxTravelSpeedKmh:      900,
+	MinTravelDistanceKm:    100,
+	NewDeviceHighAmount:    500,
+	BaselineMinSamples:     10,
+	BaselineMaxZScore:      3,
+	BaselineMaxPercentile:  0.99,
+}
+
+// GeoConfig stores the location dataset used for impossible travel detection
+var GeoConfig = &struct {
+	DatasetPath string
+}{}
+
+// ModelConfig selects a trained model as the fraud scoring backend. The rule engine is used when Path is empty.
+// A model at ChallengerPath is evaluated in shadow next to the live backend.
+var ModelConfig = &struct {
+	Path           string
+	ChallengerPath string
+	S3Endpoint     string
+	Threshold      int
+}{
+	Threshold: 500,
+}
+
+// RulesConfig selects a rule file as the fraud scoring backend instead of the built-in rules. Paths may be local
+// files, s3://bucket/key or dynamodb://ConfigID, and are checked for changes every ReloadInterval.
+var RulesConfig = &struct {
+	Path           string
+	ChallengerPath string
+	ReloadInterval time.Duration
+}{
+	ReloadInterval: time.Minute,
+}
+
+// MetricsConfig is the CloudWatch namespace metrics are emitted under, such as challenger disagreement
+var MetricsConfig = &struct {
+	Namespace string
+}{
+	Namespace: "GreenFlag",
+}
+
+// ReplyConfig selects the synonym table used to read customer replies. The bundled table is used when SynonymsPath
+// is empty. Confirmations and denials below MinConfidence are answered with a prompt instead of being acted on.
+var ReplyConfig = &struct {
+	SynonymsPath  string
+	MinConfidence string
+}{
+	MinConfidence: "medium",
+}
+
+// TemplateConfig selects the catalog of customer-facing message templates. The bundled catalog is used when
+// CatalogPath is empty.
+var TemplateConfig = &struct {
+	CatalogPath string
+}{}
+
+// ChannelConfig names the notification channel providers to use, such as "twilio" for SMS and "sns" for email.
+// Channels without a provider are not used. Email is on by default so customers who reply STOP to texts still get
+// alerts, and is only sent once their subscription to the alert topic is confirmed and filtered on their account.
+var ChannelConfig = &struct {
+	Providers      []string
+	TwilioFrom     string
+	WebhookURL     string
+	WebhookSecret  string
+	WebhookTimeout time.Duration
+}{
+	Providers:      []string{"twilio", "sns"},
+	TwilioFrom:     "+18333981458",
+	WebhookTimeout: 5 * time.Second,
+}
+
+// RoutingConfig is the order fraud alerts try channels in for customers who have not chosen their own
+var RoutingConfig = &struct {
+	Channels []string
+}{
+	Channels: []string{"sms", "email", "webhook"},
+}
+
+// QuietHoursConfig holds back messages that are not urgent, such as replies, reminders and lower-risk alerts, while
+// it is between Start and End ("HH:MM") in the customer's time zone. They are queued on QueueURL until the quiet
+// hours end. Customers whose time zone is not known are assumed to be in TimeZone. Alerts scoring UrgentScore or
+// more are always sent right away. Quiet hours are off when QueueURL is empty or Start equals End.
+var QuietHoursConfig = &struct {
+	Start       string
+	End         string
+	TimeZone    string
+	UrgentScore int
+	QueueURL    string
+}{
+	Start:       "21:00",
+	End:         "08:00",
+	TimeZone:    "America/New_York",
+	UrgentScore: 800,
+}
+
 // AWSConfig stores AWS-specific configurations
 type AWSConfig struct {
 	Region      string
@@ -92,6 +362,40 @@ func GetEnv(key, fallback string) string {
 	return fallback
 }
 
+// GetEnvInt retrieves an integer environment variable or returns a default value
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvFloat retrieves a float environment variable or returns a default value
+func GetEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(GetEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvList retrieves a comma separated environment variable or returns a default value
+func GetEnvList(key string, fallback []string) []string {
+	value := GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // LoadAWSConfig initializes and returns a new AWSConfig instance
 func LoadAWSConfig(ctx context.Context) (*AWSConfig, error) {
 	region := GetEnv("AWS_REGION", "us-east-1")
@@ -125,6 +429,20 @@ func LoadAWSConfig(ctx context.Context) (*AWSConfig, error) {
 
 // InitializeConfig initializes the configuration by loading environment variables
 func InitializeConfig() {
+	InitializeLocalConfig()
+
+	secrets, err := LoadTwilioSecrets("greenflags/twilio")
+	if err != nil {
+		log.Printf("error loading Twilio secrets: %s", err)
+	} else {
+		SNSMessengerConfig.TwilioUsername = secrets.Username
+		SNSMessengerConfig.TwilioPassword = secrets.Password
+	}
+}
+
+// InitializeLocalConfig reads configuration from the environment only, without loading secrets from AWS,
+// for offline tools such as the backtester.
+func InitializeLocalConfig() {
 	LoadEnv() // Load .env variables
 
 	DBConfig.TableName = GetEnv("DYNAMODB_TABLE_NAME", "TestTransactionsTable")
@@ -146,6 +464,14 @@ func InitializeConfig() {
 		"PreviousTransactionDate": true,
 		"PhoneNumber":             true,
 		"Email":                   true,
+		"RiskScore":               true,
+		"ReasonCodes":             true,
+		"ShadowDecisions":         true,
+		"ListMatch":               true,
+		"ReplyCode":               true,
+		"Escalations":             true,
+		"AlertChannel":            true,
+		"AlertedAt":               true,
 	}
 	DBConfig.UpdateCondition = "TransactionStatus = Pending"
 	DBConfig.Keys = struct {
@@ -156,22 +482,122 @@ func InitializeConfig() {
 		SortKey:      "TransactionID",
 	}
 
+	ProfileDBConfig.TableName = GetEnv("ACCOUNT_PROFILE_TABLE_NAME", "AccountProfiles")
+	ProfileDBConfig.Keys.PartitionKey = "AccountID"
+	ProfileDBConfig.Keys.SortKey = "ProfileKey"
+
+	ConfigDBConfig.TableName = GetEnv("CONFIG_TABLE_NAME", "FraudConfig")
+	ConfigDBConfig.Keys.PartitionKey = "ConfigID"
+
+	ListDBConfig.TableName = GetEnv("FRAUD_LIST_TABLE_NAME", "FraudLists")
+	ListDBConfig.Keys.PartitionKey = "ListKey"
+	ListDBConfig.Keys.SortKey = "RecordKey"
+
+	ConversationDBConfig.TableName = GetEnv("CONVERSATION_TABLE_NAME", "Conversations")
+	ConversationDBConfig.Keys.PartitionKey = "PhoneNumber"
+	ConversationDBConfig.Keys.SortKey = "AlertID"
+	ConversationDBConfig.StateIndex = "StateCreatedIndex"
+	OptOutDBConfig.TableName = GetEnv("OPT_OUT_TABLE_NAME", "SmsOptOuts")
+	OptOutDBConfig.Keys.PartitionKey = "PhoneNumber"
+	SecurityEventDBConfig.TableName = GetEnv("SECURITY_EVENT_TABLE_NAME", "SecurityEvents")
+	SecurityEventDBConfig.Keys.PartitionKey = "EventType"
+	SecurityEventDBConfig.Keys.SortKey = "EventID"
+	ProcessedReplyDBConfig.TableName = GetEnv("PROCESSED_REPLY_TABLE_NAME", "ProcessedReplies")
+	ProcessedReplyDBConfig.Keys.PartitionKey = "MessageSid"
+	AlertLimitDBConfig.TableName = GetEnv("ALERT_LIMIT_TABLE_NAME", "AlertLimits")
+	AlertLimitDBConfig.Keys.PartitionKey = "LimitKey"
+	AlertLimitDBConfig.DigestIndex = "DigestDueIndex"
+
+	OutboxDBConfig.TableName = GetEnv("OUTBOX_TABLE_NAME", "NotificationOutbox")
+	OutboxDBConfig.Keys.PartitionKey = "OutboxID"
+	OutboxDBConfig.PendingIndex = "PendingNotificationIndex"
+
+	ConversationConfig.TTL = time.Duration(GetEnvInt("CONVERSATION_TTL_MINUTES", int(ConversationConfig.TTL.Minutes()))) * time.Minute
+	ProcessedReplyConfig.TTL = time.Duration(GetEnvInt("PROCESSED_REPLY_TTL_HOURS", int(ProcessedReplyConfig.TTL.Hours()))) * time.Hour
+	ProcessedReplyConfig.Lease = time.Duration(GetEnvInt("PROCESSED_REPLY_LEASE_SECONDS", int(ProcessedReplyConfig.Lease.Seconds()))) * time.Second
+	AlertLimitConfig.Window = time.Duration(GetEnvInt("ALERT_DIGEST_WINDOW_SECONDS", int(AlertLimitConfig.Window.Seconds()))) * time.Second
+	AlertLimitConfig.DedupTTL = time.Duration(GetEnvInt("ALERT_DEDUP_HOURS", int(AlertLimitConfig.DedupTTL.Hours()))) * time.Hour
+	AlertLimitConfig.ClaimLease = time.Duration(GetEnvInt("ALERT_DEDUP_LEASE_SECONDS", int(AlertLimitConfig.ClaimLease.Seconds()))) * time.Second
+	OutboxConfig.SweepAfter = time.Duration(GetEnvInt("OUTBOX_SWEEP_AFTER_SECONDS", int(OutboxConfig.SweepAfter.Seconds()))) * time.Second
+	OutboxConfig.GiveUpAfter = time.Duration(GetEnvInt("OUTBOX_GIVE_UP_AFTER_HOURS", int(OutboxConfig.GiveUpAfter.Hours()))) * time.Hour
+	OutboxConfig.SentTTL = time.Duration(GetEnvInt("OUTBOX_SENT_TTL_HOURS", int(OutboxConfig.SentTTL.Hours()))) * time.Hour
+
 	// Initialize SQS config
 	SQSConfig.QueueURL = GetEnv("QUEUE_URL", "")
 
 	// Initialize SNS config
 	SNSMessengerConfig.TopicName = GetEnv("SNS_TOPIC", "FraudAlerts")
-	secrets, err := LoadTwilioSecrets("greenflags/twilio")
-	if err != nil {
-		log.Printf("error loading Twilio secrets: %s", err)
-	} else {
-		SNSMessengerConfig.TwilioUsername = secrets.Username
-		SNSMessengerConfig.TwilioPassword = secrets.Password
-	}
 
 	// Initialize handler config
 	HandlerConfig.IsRetry = GetEnv("IS_RETRY", "false") == "true"
 
+	// Initialize fraud rule engine config
+	FraudConfig.ScoreThreshold = GetEnvInt("FRAUD_SCORE_THRESHOLD", FraudConfig.ScoreThreshold)
+	FraudConfig.AmountThreshold = GetEnvFloat("FRAUD_AMOUNT_THRESHOLD", FraudConfig.AmountThreshold)
+	FraudConfig.MaxLoginAttempts = GetEnvInt("FRAUD_MAX_LOGIN_ATTEMPTS", FraudConfig.MaxLoginAttempts)
+	FraudConfig.MinTransactionDuration = GetEnvInt("FRAUD_MIN_TRANSACTION_DURATION", FraudConfig.MinTransactionDuration)
+	FraudConfig.MaxTransactionDuration = GetEnvInt("FRAUD_MAX_TRANSACTION_DURATION", FraudConfig.MaxTransactionDuration)
+	FraudConfig.HighRiskChannels = GetEnvList("FRAUD_HIGH_RISK_CHANNELS", FraudConfig.HighRiskChannels)
+	FraudConfig.HighRiskMerchants = GetEnvList("FRAUD_HIGH_RISK_MERCHANTS", FraudConfig.HighRiskMerchants)
+	FraudConfig.VelocityMaxCount5m = GetEnvInt("FRAUD_VELOCITY_MAX_COUNT_5M", FraudConfig.VelocityMaxCount5m)
+	FraudConfig.VelocityMaxCount1h = GetEnvInt("FRAUD_VELOCITY_MAX_COUNT_1H", FraudConfig.VelocityMaxCount1h)
+	FraudConfig.VelocityMaxCount24h = GetEnvInt("FRAUD_VELOCITY_MAX_COUNT_24H", FraudConfig.VelocityMaxCount24h)
+	FraudConfig.VelocityMaxAmount1h = GetEnvFloat("FRAUD_VELOCITY_MAX_AMOUNT_1H", FraudConfig.VelocityMaxAmount1h)
+	FraudConfig.VelocityMaxAmount24h = GetEnvFloat("FRAUD_VELOCITY_MAX_AMOUNT_24H", FraudConfig.VelocityMaxAmount24h)
+	FraudConfig.MaxTravelSpeedKmh = GetEnvFloat("FRAUD_MAX_TRAVEL_SPEED_KMH", FraudConfig.MaxTravelSpeedKmh)
+	FraudConfig.MinTravelDistanceKm = GetEnvFloat("FRAUD_MIN_TRAVEL_DISTANCE_KM", FraudConfig.MinTravelDistanceKm)
+	FraudConfig.NewDeviceHighAmount = GetEnvFloat("FRAUD_NEW_DEVICE_HIGH_AMOUNT", FraudConfig.NewDeviceHighAmount)
+	FraudConfig.BaselineMinSamples = int64(GetEnvInt("FRAUD_BASELINE_MIN_SAMPLES", int(FraudConfig.BaselineMinSamples)))
+	FraudConfig.BaselineMaxZScore = GetEnvFloat("FRAUD_BASELINE_MAX_ZSCORE", FraudConfig.BaselineMaxZScore)
+	FraudConfig.BaselineMaxPercentile = GetEnvFloat("FRAUD_BASELINE_MAX_PERCENTILE", FraudConfig.BaselineMaxPercentile)
+
+	// Initialize geo config
+	GeoConfig.DatasetPath = GetEnv("GEO_DATASET_PATH", "")
+
+	// Initialize model config
+	ModelConfig.Path = GetEnv("FRAUD_MODEL_PATH", "")
+	ModelConfig.ChallengerPath = GetEnv("FRAUD_CHALLENGER_MODEL_PATH", "")
+	ModelConfig.S3Endpoint = GetEnv("FRAUD_MODEL_S3_ENDPOINT", "")
+	ModelConfig.Threshold = GetEnvInt("FRAUD_MODEL_THRESHOLD", ModelConfig.Threshold)
+
+	// Initialize rules config
+	RulesConfig.Path = GetEnv("FRAUD_RULES_PATH", "")
+	RulesConfig.ChallengerPath = GetEnv("FRAUD_CHALLENGER_RULES_PATH", "")
+	RulesConfig.ReloadInterval = time.Duration(GetEnvInt("FRAUD_RULES_RELOAD_SECONDS", int(RulesConfig.ReloadInterval.Seconds()))) * time.Second
+
+	// Initialize metrics config
+	MetricsConfig.Namespace = GetEnv("METRICS_NAMESPACE", MetricsConfig.Namespace)
+
+	// Initialize escalation config
+	EscalationConfig.ReminderAfter = time.Duration(GetEnvInt("ESCALATION_REMINDER_MINUTES", int(EscalationConfig.ReminderAfter.Minutes()))) * time.Minute
+	EscalationConfig.SecondaryChannelAfter = time.Duration(GetEnvInt("ESCALATION_SECONDARY_CHANNEL_MINUTES", int(EscalationConfig.SecondaryChannelAfter.Minutes()))) * time.Minute
+	EscalationConfig.FinalActionAfter = time.Duration(GetEnvInt("ESCALATION_FINAL_ACTION_MINUTES", int(EscalationConfig.FinalActionAfter.Minutes()))) * time.Minute
+	EscalationConfig.FinalAction = strings.ToUpper(GetEnv("ESCALATION_FINAL_ACTION", EscalationConfig.FinalAction))
+
+	// Initialize reply config
+	ReplyConfig.SynonymsPath = GetEnv("REPLY_SYNONYMS_PATH", "")
+	ReplyConfig.MinConfidence = GetEnv("REPLY_MIN_CONFIDENCE", ReplyConfig.MinConfidence)
+
+	// Initialize template config
+	TemplateConfig.CatalogPath = GetEnv("TEMPLATE_CATALOG_PATH", "")
+
+	// Initialize notification channel config
+	ChannelConfig.Providers = GetEnvList("NOTIFICATION_CHANNELS", ChannelConfig.Providers)
+	ChannelConfig.TwilioFrom = GetEnv("TWILIO_FROM_NUMBER", ChannelConfig.TwilioFrom)
+	ChannelConfig.WebhookURL = GetEnv("NOTIFICATION_WEBHOOK_URL", "")
+	ChannelConfig.WebhookSecret = GetEnv("NOTIFICATION_WEBHOOK_SECRET", "")
+	ChannelConfig.WebhookTimeout = time.Duration(GetEnvInt("NOTIFICATION_WEBHOOK_TIMEOUT_SECONDS", int(ChannelConfig.WebhookTimeout.Seconds()))) * time.Second
+
+	// Initialize routing config
+	RoutingConfig.Channels = GetEnvList("ALERT_CHANNELS", RoutingConfig.Channels)
+
+	// Initialize quiet hours config
+	QuietHoursConfig.Start = GetEnv("QUIET_HOURS_START", QuietHoursConfig.Start)
+	QuietHoursConfig.End = GetEnv("QUIET_HOURS_END", QuietHoursConfig.End)
+	QuietHoursConfig.TimeZone = GetEnv("QUIET_HOURS_TIME_ZONE", QuietHoursConfig.TimeZone)
+	QuietHoursConfig.UrgentScore = GetEnvInt("QUIET_HOURS_URGENT_SCORE", QuietHoursConfig.UrgentScore)
+	QuietHoursConfig.QueueURL = GetEnv("DEFERRED_MESSAGE_QUEUE_URL", "")
+
 	log.Printf("DynamoDB Table: %s", DBConfig.TableName)
 	log.Printf("DynamoDB Endpoint: %s", DBConfig.DynamoDBEndpoint)
 	log.Printf("AWS Region: %s", GetEnv("AWS_REGION", "us-east-1"))
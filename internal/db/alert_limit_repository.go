@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// admitAttempts is how many times admitting an alert is tried while other invocations keep changing its window
+const admitAttempts = 3
+
+// AlertLimitRepository is the store shared by every invocation for limiting outbound alerts. It holds each
+// recipient's digest window and the messages recently sent.
+type AlertLimitRepository interface {
+	AdmitAlert(ctx context.Context, limitKey string, alert models.HeldAlert, now time.Time, window time.Duration) (string, error)
+	GetDueDigests(ctx context.Context, now time.Time) ([]models.AlertWindow, error)
+	CompleteDigest(ctx context.Context, limitKey string, sent []string) error
+	ClaimMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) (bool, error)
+	ConfirmMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) error
+	ReleaseMessage(ctx context.Context, key string) error
+}
+
+type DynamoAlertLimitRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewAlertLimitRepository(db *DynamoDBClient) AlertLimitRepository {
+	return &DynamoAlertLimitRepository{DB: db}
+}
+
+// AdmitAlert decides whether an alert is sent now or held for its window's digest. An alert opening a new window is
+// sent now, and one flagged while a window is open is held. Admitting the same alert again gives the same answer.
+func (r *DynamoAlertLimitRepository) AdmitAlert(ctx context.Context, limitKey string, alert models.HeldAlert, now time.Time, window time.Duration) (string, error) {
+	if limitKey == "" {
+		return "", fmt.Errorf("%s cannot be empty", config.AlertLimitDBConfig.Keys.PartitionKey)
+	}
+
+	for attempt := 0; attempt < admitAttempts; attempt++ {
+		held, existing, err := r.holdAlert(ctx, limitKey, alert, now)
+		if err != nil {
+			return "", err
+		}
+		if held {
+			return models.AlertHeld, nil
+		}
+
+		if existing != nil {
+			if _, ok := existing.Held[alert.TransactionID]; ok {
+				return models.AlertHeld, nil
+			}
+			if existing.FirstID == alert.TransactionID {
+				return models.AlertSendNow, nil
+			}
+			if existing.Accepts(now) {
+				// The window was replaced after the alert failed to join it
+				continue
+			}
+		}
+
+		opened, err := r.openWindow(ctx, models.NewAlertWindow(limitKey, alert.TransactionID, now, window, config.AlertLimitConfig.DedupTTL), now)
+		if err != nil {
+			return "", err
+		}
+		if opened {
+			return models.AlertSendNow, nil
+		}
+	}
+	return "", fmt.Errorf("failed to admit alert for transaction %s: window %s kept changing", alert.TransactionID, limitKey)
+}
+
+// holdAlert adds an alert to a window that still accepts alerts. When it does not, the window as it was is returned,
+// or nil if there is none.
+func (r *DynamoAlertLimitRepository) holdAlert(ctx context.Context, limitKey string, alert models.HeldAlert, now time.Time) (bool, *models.AlertWindow, error) {
+	heldAlert := expression.Name("Held." + alert.TransactionID)
+	update := expression.Set(heldAlert, expression.Value(alert)).
+		Set(expression.Name("DigestState"), expression.Value(models.DigestPending))
+	condition := expression.Name(config.AlertLimitDBConfig.Keys.PartitionKey).AttributeExists().
+		And(expression.Name("WindowEnd").GreaterThan(expression.Value(now.Unix())).
+			Or(expression.Name("DigestState").Equal(expression.Value(models.DigestPending)))).
+		And(expression.Name("FirstID").NotEqual(expression.Value(alert.TransactionID))).
+		And(expression.AttributeNotExists(heldAlert))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to build alert window update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                           aws.String(r.DB.TableName),
+		Key:                                 r.key(limitKey),
+		UpdateExpression:                    expr.Update(),
+		ConditionExpression:                 expr.Condition(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		if len(conditionCheckErr.Item) == 0 {
+			return false, nil, nil
+		}
+		var existing models.AlertWindow
+		if err := attributevalue.UnmarshalMap(conditionCheckErr.Item, &existing); err != nil {
+			return false, nil, fmt.Errorf("failed to unmarshal alert window: %w", err)
+		}
+		return false, &existing, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to hold alert for transaction %s: %w", alert.TransactionID, err)
+	}
+	return true, nil, nil
+}
+
+// openWindow replaces a window that has ended with nothing left to send, and reports false if another invocation
+// got there first.
+func (r *DynamoAlertLimitRepository) openWindow(ctx context.Context, window *models.AlertWindow, now time.Time) (bool, error) {
+	item, err := attributevalue.MarshalMap(window)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal alert window: %w", err)
+	}
+
+	condition := expression.AttributeNotExists(expression.Name(config.AlertLimitDBConfig.Keys.PartitionKey)).Or(
+		expression.Name("WindowEnd").LessThanEqual(expression.Value(now.Unix())).
+			And(expression.AttributeNotExists(expression.Name("DigestState"))),
+	)
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return false, fmt.Errorf("failed to build alert window condition: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open alert window %s: %w", window.LimitKey, err)
+	}
+	return true, nil
+}
+
+// GetDueDigests returns the windows that have ended with alerts still held
+func (r *DynamoAlertLimitRepository) GetDueDigests(ctx context.Context, now time.Time) ([]models.AlertWindow, error) {
+	keyEx := expression.Key("DigestState").Equal(expression.Value(models.DigestPending)).
+		And(expression.Key("WindowEnd").LessThanEqual(expression.Value(now.Unix())))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build digest query: %w", err)
+	}
+
+	var windows []models.AlertWindow
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		IndexName:                 aws.String(config.AlertLimitDBConfig.DigestIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query due digests: %w", err)
+		}
+
+		var page []models.AlertWindow
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal alert windows: %w", err)
+		}
+		windows = append(windows, page...)
+	}
+	return windows, nil
+}
+
+// CompleteDigest removes the alerts a digest was sent for from their window. The window leaves the digest index once
+// nothing is held, so alerts held while the digest was being sent go out with the next one.
+func (r *DynamoAlertLimitRepository) CompleteDigest(ctx context.Context, limitKey string, sent []string) error {
+	if len(sent) > 0 {
+		update := expression.Remove(expression.Name("Held." + sent[0]))
+		for _, transactionID := range sent[1:] {
+			update = update.Remove(expression.Name("Held." + transactionID))
+		}
+		if err := r.updateWindow(ctx, limitKey, update, expression.Name(config.AlertLimitDBConfig.Keys.PartitionKey).AttributeExists()); err != nil {
+			return fmt.Errorf("failed to remove sent alerts from window %s: %w", limitKey, err)
+		}
+	}
+
+	err := r.updateWindow(ctx, limitKey,
+		expression.Remove(expression.Name("DigestState")),
+		expression.Name("Held").Size().Equal(expression.Value(0)),
+	)
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionCheckErr) {
+		return fmt.Errorf("failed to complete digest of window %s: %w", limitKey, err)
+	}
+	return nil
+}
+
+func (r *DynamoAlertLimitRepository) updateWindow(ctx context.Context, limitKey string, update expression.UpdateBuilder, condition expression.ConditionBuilder) error {
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build alert window update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Key:                       r.key(limitKey),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return err
+}
+
+// ClaimMessage records that a message is being sent for ttl and reports false if the same message was already
+// claimed or sent and has not expired.
+func (r *DynamoAlertLimitRepository) ClaimMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) (bool, error) {
+	item, err := attributevalue.MarshalMap(models.SentMessage{
+		LimitKey:  key,
+		SentAt:    now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal sent message: %w", err)
+	}
+
+	// Expired items may not have been deleted by the TTL sweep yet
+	condition := expression.AttributeNotExists(expression.Name(config.AlertLimitDBConfig.Keys.PartitionKey)).Or(
+		expression.Name("ExpiresAt").LessThanEqual(expression.Value(now.Unix())),
+	)
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return false, fmt.Errorf("failed to build sent message condition: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim message %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// ConfirmMessage records that a claimed message was delivered, so the same message is not sent again for ttl
+func (r *DynamoAlertLimitRepository) ConfirmMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) error {
+	update := expression.Set(expression.Name("SentAt"), expression.Value(now.Unix())).
+		Set(expression.Name("ExpiresAt"), expression.Value(now.Add(ttl).Unix()))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build sent message update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Key:                       r.key(key),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to confirm message %s: %w", key, err)
+	}
+	return nil
+}
+
+// ReleaseMessage forgets a claimed message that could not be sent, so it may be sent again
+func (r *DynamoAlertLimitRepository) ReleaseMessage(ctx context.Context, key string) error {
+	_, err := r.DB.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key:       r.key(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release message %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *DynamoAlertLimitRepository) key(limitKey string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		config.AlertLimitDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: limitKey},
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ConfigRepository is the data access layer for configuration documents that can change without a deploy.
+type ConfigRepository interface {
+	GetConfigItem(ctx context.Context, configID string) (*models.ConfigItem, error)
+}
+
+type DynamoConfigRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewConfigRepository(db *DynamoDBClient) ConfigRepository {
+	return &DynamoConfigRepository{DB: db}
+}
+
+func (r *DynamoConfigRepository) GetConfigItem(ctx context.Context, configID string) (*models.ConfigItem, error) {
+	if configID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.ConfigDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := r.DB.GetItem(ctx, map[string]types.AttributeValue{
+		config.ConfigDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: configID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config item %s: %w", configID, err)
+	}
+
+	configItem := &models.ConfigItem{}
+	if err := attributevalue.UnmarshalMap(item, configItem); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config item %s: %w", configID, err)
+	}
+	return configItem, nil
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrConversationChanged is returned when a conversation was updated by someone else since it was read.
+var ErrConversationChanged = errors.New("conversation was changed concurrently")
+
+// ConversationRepository is the data access layer for SMS conversations about fraud alerts.
+type ConversationRepository interface {
+	StartConversation(ctx context.Context, conversation *models.Conversation) (bool, error)
+	GetOpenConversations(ctx context.Context, phoneNumber string) ([]models.Conversation, error)
+	UpdateConversation(ctx context.Context, conversation *models.Conversation, expectedState string) error
+	GetAwaitingConversations(ctx context.Context, createdBefore time.Time) ([]models.Conversation, error)
+}
+
+type DynamoConversationRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewConversationRepository(db *DynamoDBClient) ConversationRepository {
+	return &DynamoConversationRepository{DB: db}
+}
+
+// StartConversation saves a new conversation and reports false without changing anything if the alert already has one.
+func (r *DynamoConversationRepository) StartConversation(ctx context.Context, conversation *models.Conversation) (bool, error) {
+	if conversation.PhoneNumber == "" {
+		return false, fmt.Errorf("%s cannot be empty", config.ConversationDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := attributevalue.MarshalMap(conversation)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.DB.TableName),
+		Item:                item,
+		ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", config.ConversationDBConfig.Keys.SortKey)),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to start conversation for alert %s: %w", conversation.AlertID, err)
+	}
+	return true, nil
+}
+
+// GetOpenConversations returns every conversation with a phone number still awaiting a reply, including expired ones.
+func (r *DynamoConversationRepository) GetOpenConversations(ctx context.Context, phoneNumber string) ([]models.Conversation, error) {
+	if phoneNumber == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.ConversationDBConfig.Keys.PartitionKey)
+	}
+
+	keyEx := expression.Key(config.ConversationDBConfig.Keys.PartitionKey).Equal(expression.Value(phoneNumber))
+	filterEx := expression.Name("State").Equal(expression.Value(models.ConversationAwaitingReply))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithFilter(filterEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build conversation query: %w", err)
+	}
+
+	var conversations []models.Conversation
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query conversations: %w", err)
+		}
+
+		var page []models.Conversation
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal conversations: %w", err)
+		}
+		conversations = append(conversations, page...)
+	}
+
+	return conversations, nil
+}
+
+// UpdateConversation saves a conversation only if it is still in expectedState, so two replies processed
+// at once cannot both resolve the same alert.
+func (r *DynamoConversationRepository) UpdateConversation(ctx context.Context, conversation *models.Conversation, expectedState string) error {
+	item, err := attributevalue.MarshalMap(conversation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	condition := expression.Name("State").Equal(expression.Value(expectedState))
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build conversation condition: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return ErrConversationChanged
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update conversation for alert %s: %w", conversation.AlertID, err)
+	}
+	return nil
+}
+
+// GetAwaitingConversations returns every conversation across phone numbers still awaiting a reply that was started
+// before createdBefore, using the state index.
+func (r *DynamoConversationRepository) GetAwaitingConversations(ctx context.Context, createdBefore time.Time) ([]models.Conversation, error) {
+	keyEx := expression.Key("State").Equal(expression.Value(models.ConversationAwaitingReply)).
+		And(expression.Key("CreatedAt").LessThan(expression.Value(createdBefore.Unix())))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build conversation query: %w", err)
+	}
+
+	var conversations []models.Conversation
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		IndexName:                 aws.String(config.ConversationDBConfig.StateIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query awaiting conversations: %w", err)
+		}
+
+		var page []models.Conversation
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal conversations: %w", err)
+		}
+		conversations = append(conversations, page...)
+	}
+
+	return conversations, nil
+}
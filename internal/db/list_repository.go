@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxBatchGetAttempts = 3
+
+// ListRepository is the data access layer for allowlist and denylist entries and their audit trail.
+type ListRepository interface {
+	GetListEntries(ctx context.Context, listKeys []string) ([]models.ListEntry, error)
+	PutListEntry(ctx context.Context, entry models.ListEntry) error
+	RemoveListEntry(ctx context.Context, kind string, value string, actor string, reason string) error
+	GetListAudit(ctx context.Context, kind string, value string) ([]models.ListAuditRecord, error)
+}
+
+type DynamoListRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewListRepository(db *DynamoDBClient) ListRepository {
+	return &DynamoListRepository{DB: db}
+}
+
+// GetListEntries loads the active entry for each list key, including expired entries DynamoDB has not deleted yet.
+func (r *DynamoListRepository) GetListEntries(ctx context.Context, listKeys []string) ([]models.ListEntry, error) {
+	if len(listKeys) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(listKeys))
+	for _, listKey := range listKeys {
+		keys = append(keys, r.key(listKey, models.ListEntryRecord))
+	}
+
+	var entries []models.ListEntry
+	requests := map[string]types.KeysAndAttributes{r.DB.TableName: {Keys: keys}}
+	for attempt := 0; len(requests) > 0; attempt++ {
+		if attempt == maxBatchGetAttempts {
+			return nil, fmt.Errorf("failed to get list entries: keys still unprocessed after %d attempts", maxBatchGetAttempts)
+		}
+
+		response, err := r.DB.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: requests})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list entries: %w", err)
+		}
+
+		var page []models.ListEntry
+		if err := attributevalue.UnmarshalListOfMaps(response.Responses[r.DB.TableName], &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal list entries: %w", err)
+		}
+		entries = append(entries, page...)
+		requests = response.UnprocessedKeys
+	}
+
+	return entries, nil
+}
+
+// PutListEntry adds or replaces an entry and writes its audit record in the same transaction.
+func (r *DynamoListRepository) PutListEntry(ctx context.Context, entry models.ListEntry) error {
+	if entry.ListKey == "" {
+		return fmt.Errorf("%s cannot be empty", config.ListDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal list entry: %w", err)
+	}
+	audit, err := r.auditItem(entry.AuditRecord(models.ListActionAdd, entry.CreatedBy, entry.Reason, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{TableName: aws.String(r.DB.TableName), Item: item}},
+			audit,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put list entry %s: %w", entry.ListKey, err)
+	}
+	return nil
+}
+
+// RemoveListEntry deletes the active entry and records who removed it and why.
+func (r *DynamoListRepository) RemoveListEntry(ctx context.Context, kind string, value string, actor string, reason string) error {
+	if actor == "" {
+		return fmt.Errorf("removing a list entry needs the person removing it for the audit trail")
+	}
+
+	listKey := models.ListKey(kind, value)
+	item, err := r.DB.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key:       r.key(listKey, models.ListEntryRecord),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get list entry %s: %w", listKey, err)
+	}
+	if item.Item == nil {
+		return fmt.Errorf("no list entry for %s", listKey)
+	}
+
+	var entry models.ListEntry
+	if err := attributevalue.UnmarshalMap(item.Item, &entry); err != nil {
+		return fmt.Errorf("failed to unmarshal list entry %s: %w", listKey, err)
+	}
+	audit, err := r.auditItem(entry.AuditRecord(models.ListActionRemove, actor, reason, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{TableName: aws.String(r.DB.TableName), Key: r.key(listKey, models.ListEntryRecord)}},
+			audit,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove list entry %s: %w", listKey, err)
+	}
+	return nil
+}
+
+// GetListAudit returns every change to an entry, oldest first.
+func (r *DynamoListRepository) GetListAudit(ctx context.Context, kind string, value string) ([]models.ListAuditRecord, error) {
+	keyEx := expression.Key(config.ListDBConfig.Keys.PartitionKey).Equal(expression.Value(models.ListKey(kind, value))).
+		And(expression.Key(config.ListDBConfig.Keys.SortKey).BeginsWith(models.ListAuditPrefix))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list audit query: %w", err)
+	}
+
+	var records []models.ListAuditRecord
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query list audit: %w", err)
+		}
+
+		var page []models.ListAuditRecord
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal list audit: %w", err)
+		}
+		records = append(records, page...)
+	}
+
+	return records, nil
+}
+
+// auditItem writes an audit record only if none exists with the same key, so history is never overwritten
+func (r *DynamoListRepository) auditItem(record models.ListAuditRecord) (types.TransactWriteItem, error) {
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to marshal list audit record: %w", err)
+	}
+	if record.Actor == "" {
+		return types.TransactWriteItem{}, errors.New("list audit record needs an actor")
+	}
+
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(r.DB.TableName),
+		Item:                item,
+		ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", config.ListDBConfig.Keys.SortKey)),
+	}}, nil
+}
+
+func (r *DynamoListRepository) key(listKey string, recordKey string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		config.ListDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: listKey},
+		config.ListDBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: recordKey},
+	}
+}
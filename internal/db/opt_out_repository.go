@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// OptOutRepository is the data access layer for the SMS opt-out registry.
+type OptOutRepository interface {
+	IsOptedOut(ctx context.Context, phoneNumber string) (bool, error)
+	SetOptedOut(ctx context.Context, phoneNumber string, optedOut bool, keyword string, at time.Time) error
+}
+
+type DynamoOptOutRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewOptOutRepository(db *DynamoDBClient) OptOutRepository {
+	return &DynamoOptOutRepository{DB: db}
+}
+
+// IsOptedOut reports whether a phone number has opted out. Numbers that never texted a keyword are opted in.
+func (r *DynamoOptOutRepository) IsOptedOut(ctx context.Context, phoneNumber string) (bool, error) {
+	if phoneNumber == "" {
+		return false, fmt.Errorf("%s cannot be empty", config.OptOutDBConfig.Keys.PartitionKey)
+	}
+
+	response, err := r.DB.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.OptOutDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: phoneNumber},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get opt-out for %s: %w", phoneNumber, err)
+	}
+	if response.Item == nil {
+		return false, nil
+	}
+
+	var optOut models.SMSOptOut
+	if err := attributevalue.UnmarshalMap(response.Item, &optOut); err != nil {
+		return false, fmt.Errorf("failed to unmarshal opt-out: %w", err)
+	}
+	return optOut.OptedOut, nil
+}
+
+// SetOptedOut records the keyword a phone number sent and whether it is now opted out.
+func (r *DynamoOptOutRepository) SetOptedOut(ctx context.Context, phoneNumber string, optedOut bool, keyword string, at time.Time) error {
+	if phoneNumber == "" {
+		return fmt.Errorf("%s cannot be empty", config.OptOutDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := attributevalue.MarshalMap(models.SMSOptOut{
+		PhoneNumber: phoneNumber,
+		OptedOut:    optedOut,
+		Keyword:     keyword,
+		UpdatedAt:   at.Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal opt-out: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save opt-out for %s: %w", phoneNumber, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrNotificationRecorded is returned when flagging a transaction whose notification is already in the outbox. The
+// earlier write stored the transaction's change along with it, so there is nothing left to do.
+var ErrNotificationRecorded = errors.New("notification already recorded")
+
+// OutboxRepository stores notifications together with the transaction changes they are about, for a relay to send.
+type OutboxRepository interface {
+	FlagTransaction(ctx context.Context, txn *models.Transaction, notification *models.OutboxNotification) error
+	GetNotification(ctx context.Context, outboxID string) (*models.OutboxNotification, error)
+	GetPendingNotifications(ctx context.Context, createdBefore time.Time) ([]models.OutboxNotification, error)
+	MarkSent(ctx context.Context, outboxID string, channel string, now time.Time) error
+	MarkUndeliverable(ctx context.Context, outboxID string, reason string, now time.Time) error
+}
+
+type DynamoOutboxRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewOutboxRepository(db *DynamoDBClient) OutboxRepository {
+	return &DynamoOutboxRepository{DB: db}
+}
+
+// FlagTransaction updates a transaction and records its notification in one DynamoDB transaction, so either both
+// are stored or neither is.
+func (r *DynamoOutboxRepository) FlagTransaction(ctx context.Context, txn *models.Transaction, notification *models.OutboxNotification) error {
+	if txn.AccountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.PartitionKey)
+	}
+	if txn.TransactionID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.SortKey)
+	}
+
+	update, err := r.transactionUpdate(txn)
+	if err != nil {
+		return err
+	}
+	put, err := r.notificationPut(notification)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Update: update}, {Put: put}},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) && len(canceled.CancellationReasons) == 2 &&
+		aws.ToString(canceled.CancellationReasons[1].Code) == "ConditionalCheckFailed" {
+		return ErrNotificationRecorded
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flag transaction %s: %w", txn.TransactionID, err)
+	}
+
+	fmt.Printf("Transaction flagged: %s | Notification: %s\n", txn.TransactionID, notification.OutboxID)
+	return nil
+}
+
+// transactionUpdate sets the same fields UpdateTransaction does, on a transaction that must already exist
+func (r *DynamoOutboxRepository) transactionUpdate(txn *models.Transaction) (*types.Update, error) {
+	updates, err := txn.TransactionUpdatePayload()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert transaction to update map: %w", err)
+	}
+	if len(updates) == 0 {
+		return nil, errors.New("no fields provided for update")
+	}
+
+	var update expression.UpdateBuilder
+	for field, value := range updates {
+		update = update.Set(expression.Name(field), expression.Value(value))
+	}
+	condition := expression.AttributeExists(expression.Name(config.DBConfig.Keys.PartitionKey))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction update: %w", err)
+	}
+
+	return &types.Update{
+		TableName: aws.String(config.DBConfig.TableName),
+		Key: map[string]types.AttributeValue{
+			config.DBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: txn.AccountID},
+			config.DBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: txn.TransactionID},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, nil
+}
+
+// notificationPut records a notification unless it already is
+func (r *DynamoOutboxRepository) notificationPut(notification *models.OutboxNotification) (*types.Put, error) {
+	item, err := attributevalue.MarshalMap(notification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outbox notification: %w", err)
+	}
+
+	condition := expression.AttributeNotExists(expression.Name(config.OutboxDBConfig.Keys.PartitionKey))
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build outbox notification condition: %w", err)
+	}
+
+	return &types.Put{
+		TableName:                aws.String(r.DB.TableName),
+		Item:                     item,
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	}, nil
+}
+
+func (r *DynamoOutboxRepository) GetNotification(ctx context.Context, outboxID string) (*models.OutboxNotification, error) {
+	if outboxID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.OutboxDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := r.DB.GetItem(ctx, r.key(outboxID))
+	if err != nil {
+		return nil, err
+	}
+
+	var notification models.OutboxNotification
+	if err := attributevalue.UnmarshalMap(item, &notification); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox notification: %w", err)
+	}
+	return &notification, nil
+}
+
+// GetPendingNotifications returns the notifications written before createdBefore that have not been sent
+func (r *DynamoOutboxRepository) GetPendingNotifications(ctx context.Context, createdBefore time.Time) ([]models.OutboxNotification, error) {
+	keyEx := expression.Key("PendingState").Equal(expression.Value(models.OutboxPending)).
+		And(expression.Key("CreatedAt").LessThan(expression.Value(createdBefore.Unix())))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pending notification query: %w", err)
+	}
+
+	var notifications []models.OutboxNotification
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		IndexName:                 aws.String(config.OutboxDBConfig.PendingIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query pending notifications: %w", err)
+		}
+
+		var page []models.OutboxNotification
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal outbox notifications: %w", err)
+		}
+		notifications = append(notifications, page...)
+	}
+	return notifications, nil
+}
+
+// MarkSent takes a notification out of the pending index and lets it expire. Marking it again changes nothing.
+func (r *DynamoOutboxRepository) MarkSent(ctx context.Context, outboxID string, channel string, now time.Time) error {
+	update := expression.Remove(expression.Name("PendingState")).
+		Set(expression.Name("SentAt"), expression.Value(now.Unix())).
+		Set(expression.Name("ExpiresAt"), expression.Value(now.Add(config.OutboxConfig.SentTTL).Unix()))
+	if channel != "" {
+		update = update.Set(expression.Name("Channel"), expression.Value(channel))
+	}
+	if err := r.markDone(ctx, outboxID, update); err != nil {
+		return fmt.Errorf("failed to mark notification %s sent: %w", outboxID, err)
+	}
+	return nil
+}
+
+// MarkUndeliverable takes a notification that cannot be delivered out of the pending index with the reason, and lets
+// it expire like a sent one. Marking it again changes nothing.
+func (r *DynamoOutboxRepository) MarkUndeliverable(ctx context.Context, outboxID string, reason string, now time.Time) error {
+	update := expression.Remove(expression.Name("PendingState")).
+		Set(expression.Name("FailedAt"), expression.Value(now.Unix())).
+		Set(expression.Name("FailureReason"), expression.Value(reason)).
+		Set(expression.Name("ExpiresAt"), expression.Value(now.Add(config.OutboxConfig.SentTTL).Unix()))
+	if err := r.markDone(ctx, outboxID, update); err != nil {
+		return fmt.Errorf("failed to mark notification %s undeliverable: %w", outboxID, err)
+	}
+	return nil
+}
+
+// markDone applies update to a notification that is still pending, and does nothing once it is not
+func (r *DynamoOutboxRepository) markDone(ctx context.Context, outboxID string, update expression.UpdateBuilder) error {
+	condition := expression.Name("PendingState").Equal(expression.Value(models.OutboxPending))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build outbox notification update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.DB.TableName),
+		Key:                       r.key(outboxID),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return nil
+	}
+	return err
+}
+
+func (r *DynamoOutboxRepository) key(outboxID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		config.OutboxDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: outboxID},
+	}
+}
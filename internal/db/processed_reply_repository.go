@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ProcessedReplyRepository is the idempotency store for inbound SMS replies.
+type ProcessedReplyRepository interface {
+	ClaimReply(ctx context.Context, reply *models.ProcessedReply, staleBefore time.Time) (*models.ProcessedReply, bool, error)
+	CompleteReply(ctx context.Context, reply *models.ProcessedReply) error
+	ReleaseReply(ctx context.Context, messageSid string) error
+}
+
+type DynamoProcessedReplyRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewProcessedReplyRepository(db *DynamoDBClient) ProcessedReplyRepository {
+	return &DynamoProcessedReplyRepository{DB: db}
+}
+
+// ClaimReply saves a reply as being processed unless its MessageSid was seen before. A claim still processing since
+// before staleBefore was abandoned and is taken over. Otherwise the existing record is returned with false.
+func (r *DynamoProcessedReplyRepository) ClaimReply(ctx context.Context, reply *models.ProcessedReply, staleBefore time.Time) (*models.ProcessedReply, bool, error) {
+	if reply.MessageSid == "" {
+		return nil, false, fmt.Errorf("%s cannot be empty", config.ProcessedReplyDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := attributevalue.MarshalMap(reply)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal processed reply: %w", err)
+	}
+
+	condition := expression.AttributeNotExists(expression.Name(config.ProcessedReplyDBConfig.Keys.PartitionKey)).Or(
+		expression.Name("Status").Equal(expression.Value(models.ReplyProcessing)).
+			And(expression.Name("ClaimedAt").LessThan(expression.Value(staleBefore.Unix()))),
+	)
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build processed reply condition: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           aws.String(r.DB.TableName),
+		Item:                                item,
+		ConditionExpression:                 expr.Condition(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		var existing models.ProcessedReply
+		if err := attributevalue.UnmarshalMap(conditionCheckErr.Item, &existing); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal processed reply: %w", err)
+		}
+		return &existing, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim reply %s: %w", reply.MessageSid, err)
+	}
+	return reply, true, nil
+}
+
+// CompleteReply saves a reply's outcome
+func (r *DynamoProcessedReplyRepository) CompleteReply(ctx context.Context, reply *models.ProcessedReply) error {
+	item, err := attributevalue.MarshalMap(reply)
+	if err != nil {
+		return fmt.Errorf("failed to marshal processed reply: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete reply %s: %w", reply.MessageSid, err)
+	}
+	return nil
+}
+
+// ReleaseReply forgets a claim that failed before doing anything, so a redelivery processes the reply again.
+func (r *DynamoProcessedReplyRepository) ReleaseReply(ctx context.Context, messageSid string) error {
+	condition := expression.Name("Status").Equal(expression.Value(models.ReplyProcessing))
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build processed reply condition: %w", err)
+	}
+
+	_, err = r.DB.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.ProcessedReplyDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: messageSid},
+		},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionCheckErr) {
+		return fmt.Errorf("failed to release reply %s: %w", messageSid, err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// AccountProfileRepository is the data access layer for what we know about each account.
+type AccountProfileRepository interface {
+	GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error)
+	RecordDeviceSighting(ctx context.Context, transaction models.Transaction, seenAt time.Time) error
+	GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error)
+	RecordSpending(ctx context.Context, transaction models.Transaction) error
+	GetPreferences(ctx context.Context, accountID string) (*models.AccountPreferences, error)
+	SavePreferences(ctx context.Context, preferences *models.AccountPreferences) error
+}
+
+type DynamoAccountProfileRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewAccountProfileRepository(db *DynamoDBClient) AccountProfileRepository {
+	return &DynamoAccountProfileRepository{DB: db}
+}
+
+// GetDeviceProfile loads every known device and IP address for an account.
+func (r *DynamoAccountProfileRepository) GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	keyEx := expression.Key(config.ProfileDBConfig.Keys.PartitionKey).Equal(expression.Value(accountID))
+	filterEx := expression.Name("Kind").In(expression.Value(models.SightingDevice), expression.Value(models.SightingIP))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithFilter(filterEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build device profile query: %w", err)
+	}
+
+	profile := models.NewDeviceProfile(accountID)
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query device profile: %w", err)
+		}
+
+		var sightings []models.Sighting
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &sightings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal device profile: %w", err)
+		}
+		for _, sighting := range sightings {
+			if sighting.Kind == models.SightingDevice {
+				profile.Devices[sighting.Value] = sighting
+			} else {
+				profile.IPAddresses[sighting.Value] = sighting
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+// RecordDeviceSighting marks the transaction's device and IP address as known for its account.
+func (r *DynamoAccountProfileRepository) RecordDeviceSighting(ctx context.Context, transaction models.Transaction, seenAt time.Time) error {
+	var errs []error
+	if transaction.DeviceID != "" {
+		errs = append(errs, r.recordSighting(ctx, transaction.AccountID, models.SightingDevice, transaction.DeviceID, seenAt))
+	}
+	if transaction.IPAddress != "" {
+		errs = append(errs, r.recordSighting(ctx, transaction.AccountID, models.SightingIP, transaction.IPAddress, seenAt))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *DynamoAccountProfileRepository) recordSighting(ctx context.Context, accountID string, kind string, value string, seenAt time.Time) error {
+	if accountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	update := expression.Set(expression.Name("Kind"), expression.Value(kind)).
+		Set(expression.Name("Value"), expression.Value(value)).
+		Set(expression.Name("FirstSeen"), expression.IfNotExists(expression.Name("FirstSeen"), expression.Value(seenAt.Unix()))).
+		Set(expression.Name("LastSeen"), expression.Value(seenAt.Unix())).
+		Add(expression.Name("SeenCount"), expression.Value(1))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build sighting update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.ProfileDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: accountID},
+			config.ProfileDBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: models.ProfileKey(kind, value)},
+		},
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record %s sighting for account %s: %w", kind, accountID, err)
+	}
+
+	return nil
+}
+
+// GetSpendingBaselines loads every spending baseline for an account keyed by profile key.
+func (r *DynamoAccountProfileRepository) GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	keyEx := expression.Key(config.ProfileDBConfig.Keys.PartitionKey).Equal(expression.Value(accountID)).
+		And(expression.Key(config.ProfileDBConfig.Keys.SortKey).BeginsWith(models.ProfileBaseline + "#"))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build spending baseline query: %w", err)
+	}
+
+	baselines := make(map[string]*models.SpendingBaseline)
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query spending baselines: %w", err)
+		}
+
+		for _, item := range response.Items {
+			baseline := &models.SpendingBaseline{}
+			if err := attributevalue.UnmarshalMap(item, baseline); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal spending baseline: %w", err)
+			}
+			baseline.Histogram = make([]int64, len(models.BaselineBucketBounds)+1)
+			for bucket := range baseline.Histogram {
+				if count, ok := item[models.BaselineBucketAttribute(bucket)].(*types.AttributeValueMemberN); ok {
+					baseline.Histogram[bucket], _ = strconv.ParseInt(count.Value, 10, 64)
+				}
+			}
+			baselines[baseline.ProfileKey] = baseline
+		}
+	}
+
+	return baselines, nil
+}
+
+// RecordSpending folds the transaction amount into its TransactionType and Channel baseline and the account-wide baseline.
+// Counters are added atomically so concurrent transactions for the same account never overwrite each other.
+func (r *DynamoAccountProfileRepository) RecordSpending(ctx context.Context, transaction models.Transaction) error {
+	return errors.Join(
+		r.recordSpending(ctx, models.NewSpendingBaseline(transaction.AccountID, transaction.TransactionType, transaction.Channel), transaction.TransactionAmount),
+		r.recordSpending(ctx, models.NewSpendingBaseline(transaction.AccountID, models.AllSegments, models.AllSegments), transaction.TransactionAmount),
+	)
+}
+
+func (r *DynamoAccountProfileRepository) recordSpending(ctx context.Context, baseline *models.SpendingBaseline, amount float64) error {
+	if baseline.AccountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	update := expression.Set(expression.Name("Kind"), expression.Value(baseline.Kind)).
+		Set(expression.Name("TransactionType"), expression.Value(baseline.TransactionType)).
+		Set(expression.Name("Channel"), expression.Value(baseline.Channel)).
+		Add(expression.Name("Count"), expression.Value(1)).
+		Add(expression.Name("Sum"), expression.Value(amount)).
+		Add(expression.Name("SumSquares"), expression.Value(amount*amount)).
+		Add(expression.Name(models.BaselineBucketAttribute(models.BaselineBucket(amount))), expression.Value(1))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build spending baseline update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.ProfileDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: baseline.AccountID},
+			config.ProfileDBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: baseline.ProfileKey},
+		},
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record spending baseline %s for account %s: %w", baseline.ProfileKey, baseline.AccountID, err)
+	}
+
+	return nil
+}
+
+// GetPreferences loads an account's contact preferences. Accounts that never set any get empty preferences.
+func (r *DynamoAccountProfileRepository) GetPreferences(ctx context.Context, accountID string) (*models.AccountPreferences, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	response, err := r.DB.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.ProfileDBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: accountID},
+			config.ProfileDBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: models.ProfilePreferences},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences for account %s: %w", accountID, err)
+	}
+
+	preferences := models.NewAccountPreferences(accountID)
+	if response.Item == nil {
+		return preferences, nil
+	}
+	if err := attributevalue.UnmarshalMap(response.Item, preferences); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// SavePreferences replaces an account's contact preferences.
+func (r *DynamoAccountProfileRepository) SavePreferences(ctx context.Context, preferences *models.AccountPreferences) error {
+	if preferences.AccountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.ProfileDBConfig.Keys.PartitionKey)
+	}
+
+	item, err := attributevalue.MarshalMap(preferences)
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save preferences for account %s: %w", preferences.AccountID, err)
+	}
+	return nil
+}
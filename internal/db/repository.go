@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"time"
 
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -16,13 +15,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// ErrTransactionResolved is returned when a transaction is no longer awaiting the customer by the time it is updated
+var ErrTransactionResolved = errors.New("transaction was resolved concurrently")
+
 // TransactionRepository is the data access layer for transactions.
 type TransactionRepository interface {
 	SaveTransaction(ctx context.Context, t *models.Transaction) (*dynamodb.PutItemOutput, string, error)
 	GetTransaction(ctx context.Context, accountID, transactionID string) (*models.Transaction, error)
-	GetTransactionByNumberAndStatus(ctx context.Context, phoneNumber string, status string) ([]models.Transaction, error)
+	GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error)
+	GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error)
 	UpdateTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) (*dynamodb.UpdateItemOutput, error)
-	UpdateFraudTransaction(ctx context.Context, phoneNumber string, isFraud bool, status string) (int, error)
+	UpdatePendingTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) error
+	UpdateAlertChannel(ctx context.Context, accountID, transactionID string, channel string) error
+	UpdateReplyCode(ctx context.Context, accountID, transactionID string, replyCode string) error
 	DeleteTransaction(ctx context.Context, accountID, transactionID string) error
 }
 
@@ -42,6 +47,15 @@ func (r *DynamoTransactionRepository) SaveTransaction(ctx context.Context, t *mo
 		return nil, "", fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Stamp a sortable time so the transaction can be found by the time range indexes
+	if t.TransactionTimestamp == 0 {
+		transactionTime := t.GetTransactionTime()
+		if transactionTime.IsZero() {
+			transactionTime = time.Now()
+		}
+		t.TransactionTimestamp = transactionTime.Unix()
+	}
+
 	item, err := t.MarshalDynamoDB()
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to marshal transaction: %w", err)
@@ -56,45 +70,55 @@ func (r *DynamoTransactionRepository) SaveTransaction(ctx context.Context, t *mo
 	return output, metadata, nil
 }
 
-func (r *DynamoTransactionRepository) GetTransactionByNumberAndStatus(ctx context.Context, phoneNumber string, status string) ([]models.Transaction, error) {
+// GetTransactionsByAccountAndTimeRange returns an account's transactions with a TransactionTimestamp between start and end (inclusive)
+func (r *DynamoTransactionRepository) GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.PartitionKey)
+	}
+	return r.queryByTimeRange(ctx, "AccountTimeIndex", "AccountID", accountID, start, end)
+}
+
+// GetTransactionsByDeviceAndTimeRange returns a device's transactions across all accounts with a TransactionTimestamp between start and end (inclusive)
+func (r *DynamoTransactionRepository) GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	if deviceID == "" {
+		return nil, errors.New("DeviceID cannot be empty")
+	}
+	return r.queryByTimeRange(ctx, "DeviceTimeIndex", "DeviceID", deviceID, start, end)
+}
+
+func (r *DynamoTransactionRepository) queryByTimeRange(ctx context.Context, indexName string, keyName string, keyValue string, start time.Time, end time.Time) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	keyEx := expression.Key("PhoneNumber").Equal(expression.Value(phoneNumber))
-	filterEx := expression.Name("TransactionStatus").Equal((expression.Value(status)))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithFilter(filterEx).Build()
+	keyEx := expression.Key(keyName).Equal(expression.Value(keyValue)).
+		And(expression.Key("TransactionTimestamp").Between(expression.Value(start.Unix()), expression.Value(end.Unix())))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
 	if err != nil {
-		fmt.Printf("Couldn't build expression for query. Here's why: %v\n", err)
-		return nil, err
-	} else {
-		queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
-			TableName:                 aws.String(r.DB.TableName),
-			IndexName:                 aws.String("PhoneNumberIndex"),
-			KeyConditionExpression:    expr.KeyCondition(),
-			FilterExpression:          expr.Filter(),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-		})
-		for queryPaginator.HasMorePages() {
-			response, err := queryPaginator.NextPage(ctx)
-			if err != nil {
-				fmt.Printf("Error getting transactions: %s", err)
-				return nil, err
-
-			} else {
-				var transactionsPage []models.Transaction
-				err = attributevalue.UnmarshalListOfMaps(response.Items, &transactionsPage)
-				if err != nil {
-					fmt.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
-					return nil, err
-				} else {
-					transactions = append(transactions, transactionsPage...)
-				}
-			}
+		return nil, fmt.Errorf("failed to build %s query: %w", indexName, err)
+	}
+
+	queryPaginator := dynamodb.NewQueryPaginator(r.DB.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.DB.TableName),
+		IndexName:                 aws.String(indexName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for queryPaginator.HasMorePages() {
+		response, err := queryPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", indexName, err)
 		}
+
+		var transactionsPage []models.Transaction
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &transactionsPage); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s query response: %w", indexName, err)
+		}
+		transactions = append(transactions, transactionsPage...)
 	}
+
 	return transactions, nil
 }
 
-// GetTransaction retrieves a transaction by AccountID and TransactionID
+// GetTransaction retrieves a transaction by AccountID and TransactionID with a strongly consistent read
 func (r *DynamoTransactionRepository) GetTransaction(ctx context.Context, accountID, transactionID string) (*models.Transaction, error) {
 	// Validate input using config keys
 	if accountID == "" {
@@ -110,14 +134,21 @@ func (r *DynamoTransactionRepository) GetTransaction(ctx context.Context, accoun
 		config.DBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: transactionID},
 	}
 
-	// Fetch transaction from DynamoDB
-	item, err := r.DB.GetItem(ctx, key)
+	// Read strongly consistently, since callers decide what to do next from the transaction's status
+	response, err := r.DB.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.DB.TableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
 	if err != nil {
-		return nil, err // Handles "item not found" and other errors
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
+	}
+	if response.Item == nil {
+		return nil, fmt.Errorf("item not found")
 	}
 
 	// Unmarshal into a Transaction struct
-	transaction, err := models.UnmarshalDynamoDB(item)
+	transaction, err := models.UnmarshalDynamoDB(response.Item)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
 	}
@@ -164,6 +195,132 @@ func (r *DynamoTransactionRepository) UpdateTransaction(ctx context.Context, acc
 	return result, nil
 }
 
+// UpdatePendingTransaction updates a transaction like UpdateTransaction, but only while it is still POTENTIAL_FRAUD,
+// so a change based on an earlier read cannot overwrite the customer's answer.
+func (r *DynamoTransactionRepository) UpdatePendingTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) error {
+	if accountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.PartitionKey)
+	}
+	if transactionID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.SortKey)
+	}
+
+	updates, err := values.TransactionUpdatePayload()
+	if err != nil {
+		return fmt.Errorf("failed to convert transaction to update map: %w", err)
+	}
+	if len(updates) == 0 {
+		return errors.New("no fields provided for update")
+	}
+
+	var update expression.UpdateBuilder
+	for field, value := range updates {
+		update = update.Set(expression.Name(field), expression.Value(value))
+	}
+	condition := expression.Name("TransactionStatus").Equal(expression.Value("POTENTIAL_FRAUD"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build transaction update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.DBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: accountID},
+			config.DBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: transactionID},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return ErrTransactionResolved
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update transaction %s: %w", transactionID, err)
+	}
+
+	fmt.Printf("Pending transaction updated: %s\n", transactionID)
+	return nil
+}
+
+// UpdateAlertChannel records the channel a transaction's alert was delivered on and when, which escalation counts
+// from. Only AlertChannel and AlertedAt are written, so a status the customer set since the transaction was read is kept.
+func (r *DynamoTransactionRepository) UpdateAlertChannel(ctx context.Context, accountID, transactionID string, channel string) error {
+	if accountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.PartitionKey)
+	}
+	if transactionID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.SortKey)
+	}
+
+	update := expression.Set(expression.Name("AlertChannel"), expression.Value(channel)).
+		Set(expression.Name("AlertedAt"), expression.Value(time.Now().Unix()))
+	condition := expression.AttributeExists(expression.Name(config.DBConfig.Keys.PartitionKey))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build alert channel update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.DBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: accountID},
+			config.DBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: transactionID},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record alert channel of transaction %s: %w", transactionID, err)
+	}
+
+	fmt.Printf("Transaction %s alerted by %s\n", transactionID, channel)
+	return nil
+}
+
+// UpdateReplyCode records the reply code of a transaction's alert while it is still POTENTIAL_FRAUD, and returns
+// ErrTransactionResolved once the customer has answered. Only ReplyCode is written.
+func (r *DynamoTransactionRepository) UpdateReplyCode(ctx context.Context, accountID, transactionID string, replyCode string) error {
+	if accountID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.PartitionKey)
+	}
+	if transactionID == "" {
+		return fmt.Errorf("%s cannot be empty", config.DBConfig.Keys.SortKey)
+	}
+
+	update := expression.Set(expression.Name("ReplyCode"), expression.Value(replyCode))
+	condition := expression.Name("TransactionStatus").Equal(expression.Value("POTENTIAL_FRAUD"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("failed to build reply code update: %w", err)
+	}
+
+	_, err = r.DB.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Key: map[string]types.AttributeValue{
+			config.DBConfig.Keys.PartitionKey: &types.AttributeValueMemberS{Value: accountID},
+			config.DBConfig.Keys.SortKey:      &types.AttributeValueMemberS{Value: transactionID},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionCheckErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionCheckErr) {
+		return ErrTransactionResolved
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record reply code of transaction %s: %w", transactionID, err)
+	}
+	return nil
+}
+
 // DeleteTransaction removes a transaction using configured keys
 func (r *DynamoTransactionRepository) DeleteTransaction(ctx context.Context, accountID, transactionID string) error {
 	// Validate input using config keys
@@ -189,53 +346,3 @@ func (r *DynamoTransactionRepository) DeleteTransaction(ctx context.Context, acc
 	fmt.Printf("Transaction deleted: %s\n", transactionID)
 	return nil
 }
-
-func (r *DynamoTransactionRepository) UpdateFraudTransaction(ctx context.Context, phoneNumber string, isFraud bool, status string) (int, error) {
-	potentialFrauds, err := r.GetTransactionByNumberAndStatus(ctx, phoneNumber, status)
-	if err != nil {
-		return 0, err
-	}
-	if len(potentialFrauds) == 0 {
-		return 0, nil
-	}
-
-	var wg sync.WaitGroup
-	errorResults := make(chan error, len(potentialFrauds))
-	for _, msg := range potentialFrauds {
-		wg.Add(1)
-		go func(txn models.Transaction) {
-
-			defer wg.Done()
-
-			if isFraud {
-				txn.TransactionStatus = "FRAUD"
-				_, err := r.UpdateTransaction(
-					ctx,
-					txn.AccountID,
-					txn.TransactionID,
-					&txn,
-				)
-				if err != nil {
-					fmt.Printf("Error updating transaction with phone number: %s to fraud. Error: %s", phoneNumber, err)
-					errorResults <- err
-				}
-
-			} else {
-				txn.TransactionStatus = "APPROVED"
-				_, err := r.UpdateTransaction(
-					ctx,
-					txn.AccountID,
-					txn.TransactionID,
-					&txn,
-				)
-				if err != nil {
-					fmt.Printf("Error updating transaction with phone number: %s to Approved. Error: %s", phoneNumber, err)
-					errorResults <- err
-				}
-			}
-		}(msg)
-	}
-	wg.Wait()
-	close(errorResults)
-	return len(potentialFrauds), middleware.MergeErrors(errorResults)
-}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// SecurityEventRepository is the data access layer for the security event log.
+type SecurityEventRepository interface {
+	RecordSecurityEvent(ctx context.Context, event *models.SecurityEvent) error
+}
+
+type DynamoSecurityEventRepository struct {
+	DB *DynamoDBClient
+}
+
+func NewSecurityEventRepository(db *DynamoDBClient) SecurityEventRepository {
+	return &DynamoSecurityEventRepository{DB: db}
+}
+
+// RecordSecurityEvent appends an event to the log
+func (r *DynamoSecurityEventRepository) RecordSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	item, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal security event: %w", err)
+	}
+
+	_, err = r.DB.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.DB.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record security event %s: %w", event.EventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+// Package dsl implements the small expression language used to write fraud rules, for example
+// `amount > 3 * account.avg_amount && device.is_new`.
+//
+// Expressions support numbers, strings, booleans, the operators || && ! == != < <= > >= + - * /,
+// parentheses and `x in [a, b]` membership tests. Every identifier must be declared with a type when
+// compiling, so mistakes are caught when a rule file is loaded rather than when a transaction is scored.
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Type int
+
+const (
+	TypeNumber Type = iota + 1
+	TypeBool
+	TypeString
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeBool:
+		return "bool"
+	case TypeString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// Variables declares the type of every identifier an expression may reference.
+type Variables map[string]Type
+
+// Env holds variable values while evaluating: float64 for numbers, bool and string.
+// Variables missing from the Env evaluate to their type's zero value.
+type Env map[string]any
+
+// Expression is a compiled boolean expression.
+type Expression struct {
+	Source string
+	eval   func(env Env) any
+}
+
+// Compile parses and type checks a boolean expression against the declared variables.
+func Compile(source string, variables Variables) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, variables: variables}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, fmt.Errorf("column %d: unexpected %q", next.pos, next.text)
+	}
+	if root.typ != TypeBool {
+		return nil, fmt.Errorf("expression must be a bool, got %s", root.typ)
+	}
+
+	return &Expression{Source: source, eval: root.eval}, nil
+}
+
+func (e *Expression) Evaluate(env Env) bool {
+	return e.eval(env).(bool)
+}
+
+type node struct {
+	typ  Type
+	eval func(env Env) any
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables Variables
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(operator string) error {
+	if t := p.peek(); !p.accept(operator) {
+		return fmt.Errorf("column %d: expected %q, got %q", t.pos, operator, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeBool, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typ: TypeBool, eval: func(env Env) any { return l(env).(bool) || r(env).(bool) }}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.parseEquality()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeBool, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typ: TypeBool, eval: func(env Env) any { return l(env).(bool) && r(env).(bool) }}
+	}
+}
+
+func (p *parser) parseEquality() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("==") && !p.accept("!=") {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return node{}, err
+		}
+		if left.typ != right.typ {
+			return node{}, fmt.Errorf("column %d: cannot compare %s %s %s", t.pos, left.typ, t.text, right.typ)
+		}
+		l, r, negate := left.eval, right.eval, t.text == "!="
+		left = node{typ: TypeBool, eval: func(env Env) any { return equal(l(env), r(env)) != negate }}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseMembership()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || (t.text != "<" && t.text != "<=" && t.text != ">" && t.text != ">=") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMembership()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeNumber, left, right); err != nil {
+			return node{}, err
+		}
+		l, r, operator := left.eval, right.eval, t.text
+		left = node{typ: TypeBool, eval: func(env Env) any {
+			a, b := l(env).(float64), r(env).(float64)
+			switch operator {
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			default:
+				return a >= b
+			}
+		}}
+	}
+}
+
+func (p *parser) parseMembership() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return node{}, err
+	}
+	t := p.peek()
+	if t.kind != tokenIdent || t.text != "in" {
+		return left, nil
+	}
+	p.next()
+
+	if err := p.expect("["); err != nil {
+		return node{}, err
+	}
+	var items []func(env Env) any
+	for !p.accept("]") {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return node{}, err
+			}
+		}
+		item, err := p.parseAdditive()
+		if err != nil {
+			return node{}, err
+		}
+		if item.typ != left.typ {
+			return node{}, fmt.Errorf("column %d: list of %s contains a %s", t.pos, left.typ, item.typ)
+		}
+		items = append(items, item.eval)
+	}
+
+	l := left.eval
+	return node{typ: TypeBool, eval: func(env Env) any {
+		value := l(env)
+		for _, item := range items {
+			if equal(value, item(env)) {
+				return true
+			}
+		}
+		return false
+	}}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("+") && !p.accept("-") {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeNumber, left, right); err != nil {
+			return node{}, err
+		}
+		l, r, subtract := left.eval, right.eval, t.text == "-"
+		left = node{typ: TypeNumber, eval: func(env Env) any {
+			if subtract {
+				return l(env).(float64) - r(env).(float64)
+			}
+			return l(env).(float64) + r(env).(float64)
+		}}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("*") && !p.accept("/") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeNumber, left, right); err != nil {
+			return node{}, err
+		}
+		l, r, divide := left.eval, right.eval, t.text == "/"
+		left = node{typ: TypeNumber, eval: func(env Env) any {
+			if divide {
+				return l(env).(float64) / r(env).(float64)
+			}
+			return l(env).(float64) * r(env).(float64)
+		}}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeBool, operand); err != nil {
+			return node{}, err
+		}
+		eval := operand.eval
+		return node{typ: TypeBool, eval: func(env Env) any { return !eval(env).(bool) }}, nil
+	}
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if err := requireTypes(t, TypeNumber, operand); err != nil {
+			return node{}, err
+		}
+		eval := operand.eval
+		return node{typ: TypeNumber, eval: func(env Env) any { return -eval(env).(float64) }}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, fmt.Errorf("column %d: invalid number %q", t.pos, t.text)
+		}
+		return constant(TypeNumber, value), nil
+	case tokenString:
+		return constant(TypeString, t.value), nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return constant(TypeBool, t.text == "true"), nil
+		}
+		return p.variable(t)
+	case tokenOperator:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return node{}, err
+			}
+			return inner, p.expect(")")
+		}
+	}
+	return node{}, fmt.Errorf("column %d: unexpected %q", t.pos, t.text)
+}
+
+func (p *parser) variable(t token) (node, error) {
+	typ, ok := p.variables[t.text]
+	if !ok {
+		return node{}, fmt.Errorf("column %d: unknown variable %q", t.pos, t.text)
+	}
+
+	name, zero := t.text, zeroValue(typ)
+	return node{typ: typ, eval: func(env Env) any {
+		if value, ok := env[name]; ok {
+			return value
+		}
+		return zero
+	}}, nil
+}
+
+func constant(typ Type, value any) node {
+	return node{typ: typ, eval: func(env Env) any { return value }}
+}
+
+func requireTypes(t token, typ Type, operands ...node) error {
+	for _, operand := range operands {
+		if operand.typ != typ {
+			return fmt.Errorf("column %d: %q needs %s operands, got %s", t.pos, t.text, typ, operand.typ)
+		}
+	}
+	return nil
+}
+
+func zeroValue(typ Type) any {
+	switch typ {
+	case TypeNumber:
+		return 0.0
+	case TypeBool:
+		return false
+	default:
+		return ""
+	}
+}
+
+// equal compares strings ignoring case, since channels and types arrive in mixed case
+func equal(a any, b any) bool {
+	if as, ok := a.(string); ok {
+		bs, _ := b.(string)
+		return strings.EqualFold(as, bs)
+	}
+	return a == b
+}
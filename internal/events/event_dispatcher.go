@@ -1,45 +1,355 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
 )
 
+var (
+	// ErrUndeliverable is returned when a message could not be sent on any channel of its route.
+	ErrUndeliverable = errors.New("message could not be delivered on any channel")
+	// ErrUnreachable is returned along with ErrUndeliverable when every channel of the route failed in a way that
+	// sending again will not fix, such as the customer having no address on it or having opted out.
+	ErrUnreachable = errors.New("recipient cannot be reached on any channel")
+)
+
+// EventDispatcher sends customer notifications. Alerts return the channel they were delivered on, or an empty
+// channel when they were held back for quiet hours.
 type EventDispatcher interface {
-	DispatchFraudAlertEvent(transaction models.Transaction) error
-	DispatchFraudUpdateEvent(number string, body string) error
+	DispatchFraudAlertEvent(transaction models.Transaction) (string, error)
+	DispatchFraudUpdateEvent(number string, notification models.Notification) error
+	DispatchFraudEscalationEvent(transaction models.Transaction) (string, error)
+	DispatchFraudDigestEvent(transactions []models.Transaction) (string, error)
+}
+
+// PreferenceReader looks up how a customer wants to be contacted.
+type PreferenceReader interface {
+	GetPreferences(ctx context.Context, accountID string) (*models.AccountPreferences, error)
+}
+
+// MessageDeduplicator remembers the messages recently sent, so an identical message is not sent twice. A message is
+// claimed for a short lease before it is sent and confirmed once it is delivered.
+type MessageDeduplicator interface {
+	ClaimMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) (bool, error)
+	ConfirmMessage(ctx context.Context, key string, now time.Time, ttl time.Duration) error
+	ReleaseMessage(ctx context.Context, key string) error
 }
 
+// DeferredQueue holds messages back until they are due
+type DeferredQueue interface {
+	Defer(ctx context.Context, message messaging.DeferredMessage, now time.Time) error
+}
+
+// DeferredDispatcher sends messages that were held back once they are due
+type DeferredDispatcher interface {
+	DispatchDeferredEvent(message messaging.DeferredMessage) (string, error)
+}
+
+// GfEventDispatcher sends alerts on the customer's route. When Sent is set, an alert identical to one already
+// delivered to the same address is not sent again. When Deferred is set, messages that are not urgent are queued
+// there during the customer's QuietHours.
 type GfEventDispatcher struct {
-	SNSMessenger messaging.SNSMessenger
+	Channels    messaging.Channels
+	Templates   *templates.Catalog
+	Preferences PreferenceReader
+	Routing     RoutingPolicy
+	Sent        MessageDeduplicator
+	QuietHours  QuietHours
+	Deferred    DeferredQueue
 }
 
-func NewGfEventDispatcher(snsMessenger messaging.SNSMessenger) *GfEventDispatcher {
+func NewGfEventDispatcher(channels messaging.Channels, catalog *templates.Catalog, preferences PreferenceReader, sent MessageDeduplicator, deferred DeferredQueue) *GfEventDispatcher {
 	return &GfEventDispatcher{
-		SNSMessenger: snsMessenger,
+		Channels:    channels,
+		Templates:   catalog,
+		Preferences: preferences,
+		Routing:     NewRoutingPolicy(),
+		Sent:        sent,
+		QuietHours:  NewQuietHours(),
+		Deferred:    deferred,
 	}
 }
 
-// To be refactored later
-func (dispatcher *GfEventDispatcher) DispatchFraudAlertEvent(transaction models.Transaction) error {
+// DispatchFraudAlertEvent sends the alert on the first channel of the customer's route that delivers it
+func (dispatcher *GfEventDispatcher) DispatchFraudAlertEvent(transaction models.Transaction) (string, error) {
+	ctx := context.TODO()
+	preferences := dispatcher.preferences(ctx, transaction.AccountID)
+	notification := models.NewTransactionNotification(models.NotificationFraudAlert, transaction)
+	alert, err := dispatcher.render(notification, preferences)
+	if err != nil {
+		return "", err
+	}
+	alert.TransactionID = transaction.TransactionID
 
-	// _, err := dispatcher.SNSMessenger.SendEmailAlert(transaction)
-	err := dispatcher.SNSMessenger.SendTextAlert(transaction)
+	deferred := messaging.DeferredMessage{Route: dispatcher.Routing.Route(preferences), TransactionIDs: []string{transaction.TransactionID}, Recipient: messaging.RecipientOf(transaction), Message: alert}
+	if held, err := dispatcher.holdForQuietHours(ctx, deferred, preferences, notification.Location, dispatcher.QuietHours.IsUrgent(transaction.RiskScore)); held || err != nil {
+		return "", err
+	}
+
+	channel, err := dispatcher.deliver(ctx, deferred.Route, deferred.Recipient, alert)
 	if err != nil {
-		return fmt.Errorf("error sending text message for transaction: %s", err)
+		return "", fmt.Errorf("error sending alert for transaction %s: %w", transaction.TransactionID, err)
 	}
-	fmt.Printf("Fraud detected, successfully sent text to %s\n", transaction.PhoneNumber)
+	fmt.Printf("Fraud detected, successfully sent alert by %s for %s\n", channel, transaction.TransactionID)
+	return channel, nil
+}
 
-	return nil
+// DispatchFraudUpdateEvent texts a reply, holding it back during quiet hours. Replies to numbers that have since
+// opted out are dropped.
+func (dispatcher *GfEventDispatcher) DispatchFraudUpdateEvent(number string, notification models.Notification) error {
+	ctx := context.TODO()
+	preferences := dispatcher.preferences(ctx, notification.AccountID)
+	reply, err := dispatcher.render(notification, preferences)
+	if err != nil {
+		return err
+	}
+
+	deferred := messaging.DeferredMessage{Reply: true, Recipient: messaging.Recipient{AccountID: notification.AccountID, PhoneNumber: number}, Message: reply}
+	if held, err := dispatcher.holdForQuietHours(ctx, deferred, preferences, notification.Location, false); held || err != nil {
+		return err
+	}
+	return dispatcher.text(ctx, deferred.Recipient, reply)
 }
 
-func (dispatcher *GfEventDispatcher) DispatchFraudUpdateEvent(number string, body string) error {
-	err := dispatcher.SNSMessenger.SendTextUpdate(number, body)
+// text sends a reply by SMS
+func (dispatcher *GfEventDispatcher) text(ctx context.Context, recipient messaging.Recipient, reply messaging.Message) error {
+	err := dispatcher.Channels.Send(ctx, messaging.ChannelSMS, recipient, reply)
+	if errors.Is(err, messaging.ErrOptedOut) {
+		fmt.Printf("Fraud event updated: %s opted out of texts, reply not sent\n", recipient.PhoneNumber)
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("error sending text message for transaction: %s", err)
+		return fmt.Errorf("error sending text message for transaction: %w", err)
 	}
-	fmt.Printf("Fraud event updated: successfully sent replied to %s\n", number)
+	fmt.Printf("Fraud event updated: successfully sent replied to %s\n", recipient.PhoneNumber)
 	return nil
 }
+
+// DispatchFraudEscalationEvent resends an unanswered alert on the customer's route, leaving out the channel the
+// alert was first delivered on
+func (dispatcher *GfEventDispatcher) DispatchFraudEscalationEvent(transaction models.Transaction) (string, error) {
+	ctx := context.TODO()
+	preferences := dispatcher.preferences(ctx, transaction.AccountID)
+	notification := models.NewTransactionNotification(models.NotificationFraudAlert, transaction)
+	alert, err := dispatcher.render(notification, preferences)
+	if err != nil {
+		return "", err
+	}
+	alert.TransactionID = transaction.TransactionID
+
+	first := transaction.AlertChannel
+	if first == "" {
+		// Alerts sent before routing was recorded, or still held back for quiet hours, go out by text first
+		first = messaging.ChannelSMS
+	}
+	deferred := messaging.DeferredMessage{Route: without(dispatcher.Routing.Route(preferences), first), Recipient: messaging.RecipientOf(transaction), Message: alert}
+	if held, err := dispatcher.holdForQuietHours(ctx, deferred, preferences, notification.Location, dispatcher.QuietHours.IsUrgent(transaction.RiskScore)); held || err != nil {
+		return "", err
+	}
+
+	channel, err := dispatcher.deliver(ctx, deferred.Route, deferred.Recipient, alert)
+	if err != nil {
+		return "", fmt.Errorf("error sending escalation for transaction %s: %w", transaction.TransactionID, err)
+	}
+	fmt.Printf("Fraud alert escalated: successfully sent alert by %s for %s\n", channel, transaction.TransactionID)
+	return channel, nil
+}
+
+// DispatchFraudDigestEvent sends one alert about several flagged transactions of an account on the customer's route
+func (dispatcher *GfEventDispatcher) DispatchFraudDigestEvent(transactions []models.Transaction) (string, error) {
+	if len(transactions) == 0 {
+		return "", fmt.Errorf("digest has no transactions")
+	}
+
+	ctx := context.TODO()
+	preferences := dispatcher.preferences(ctx, transactions[0].AccountID)
+	notification := models.NewDigestNotification(transactions)
+	digest, err := dispatcher.render(notification, preferences)
+	if err != nil {
+		return "", err
+	}
+
+	urgent := false
+	transactionIDs := make([]string, 0, len(transactions))
+	for _, txn := range transactions {
+		urgent = urgent || dispatcher.QuietHours.IsUrgent(txn.RiskScore)
+		transactionIDs = append(transactionIDs, txn.TransactionID)
+	}
+	deferred := messaging.DeferredMessage{Route: dispatcher.Routing.Route(preferences), TransactionIDs: transactionIDs, Recipient: messaging.RecipientOf(transactions[0]), Message: digest}
+	if held, err := dispatcher.holdForQuietHours(ctx, deferred, preferences, notification.Location, urgent); held || err != nil {
+		return "", err
+	}
+
+	channel, err := dispatcher.deliver(ctx, deferred.Route, deferred.Recipient, digest)
+	if err != nil {
+		return "", fmt.Errorf("error sending digest of %d transactions for account %s: %w", len(transactions), transactions[0].AccountID, err)
+	}
+	fmt.Printf("Fraud detected, successfully sent digest of %d transactions by %s for account %s\n", len(transactions), channel, transactions[0].AccountID)
+	return channel, nil
+}
+
+// DispatchDeferredEvent sends a message that was held back for quiet hours, the way it would have been sent then,
+// and returns the channel it went out on. Replies return no channel.
+func (dispatcher *GfEventDispatcher) DispatchDeferredEvent(message messaging.DeferredMessage) (string, error) {
+	ctx := context.TODO()
+	if message.Reply {
+		return "", dispatcher.text(ctx, message.Recipient, message.Message)
+	}
+
+	channel, err := dispatcher.deliver(ctx, message.Route, message.Recipient, message.Message)
+	if err != nil {
+		return "", fmt.Errorf("error sending held %s for account %s: %w", message.Message.Type, message.Recipient.AccountID, err)
+	}
+	fmt.Printf("Quiet hours over, successfully sent held %s by %s for account %s\n", message.Message.Type, channel, message.Recipient.AccountID)
+	return channel, nil
+}
+
+// holdForQuietHours queues a message that is not urgent until the customer's quiet hours end, and reports whether
+// it did. Messages are never held when there is nowhere to queue them.
+func (dispatcher *GfEventDispatcher) holdForQuietHours(ctx context.Context, message messaging.DeferredMessage, preferences *models.AccountPreferences, location string, urgent bool) (bool, error) {
+	if urgent || dispatcher.Deferred == nil || !dispatcher.QuietHours.Enabled() {
+		return false, nil
+	}
+
+	now := time.Now()
+	deliverAt := dispatcher.QuietHours.NextAllowed(now, dispatcher.QuietHours.TimeZone(preferences.TimeZone, location))
+	if !deliverAt.After(now) {
+		return false, nil
+	}
+
+	message.DeliverAt = deliverAt.Unix()
+	if err := dispatcher.Deferred.Defer(ctx, message, now); err != nil {
+		return false, fmt.Errorf("error holding %s for account %s until quiet hours end: %w", message.Message.Type, message.Recipient.AccountID, err)
+	}
+	fmt.Printf("Quiet hours for account %s, holding %s until %s\n", message.Recipient.AccountID, message.Message.Type, deliverAt.Format(time.RFC3339))
+	return true, nil
+}
+
+// deliver sends a message on each channel of a route in turn until one delivers it. Any failure to deliver on a
+// channel, such as an opted out number, a missing address or a provider error, moves on to the next channel.
+// Channels that are not configured are skipped. A message already delivered on a channel counts as delivered. When no
+// channel could ever deliver it the error also wraps ErrUnreachable.
+func (dispatcher *GfEventDispatcher) deliver(ctx context.Context, route []string, recipient messaging.Recipient, message messaging.Message) (string, error) {
+	var failures []error
+	unreachable := len(route) > 0
+	for _, channel := range route {
+		key, duplicate := dispatcher.claim(ctx, channel, recipient, message)
+		if duplicate {
+			fmt.Printf("Not sending %s for account %s by %s again, an identical message was already sent\n", message.Type, recipient.AccountID, channel)
+			return channel, nil
+		}
+
+		err := dispatcher.Channels.Send(ctx, channel, recipient, message)
+		if err == nil {
+			dispatcher.confirm(ctx, key)
+			return channel, nil
+		}
+		dispatcher.release(ctx, key)
+		if !errors.Is(err, messaging.ErrChannelUnavailable) {
+			fmt.Printf("Error sending %s for account %s by %s, trying next channel: %s\n", message.Type, recipient.AccountID, channel, err)
+		}
+		if !errors.Is(err, messaging.ErrNoAddress) && !errors.Is(err, messaging.ErrOptedOut) && !errors.Is(err, messaging.ErrChannelUnavailable) {
+			unreachable = false
+		}
+		failures = append(failures, fmt.Errorf("%s: %w", channel, err))
+	}
+	if unreachable {
+		failures = append([]error{ErrUnreachable}, failures...)
+	}
+	return "", errors.Join(append([]error{ErrUndeliverable}, failures...)...)
+}
+
+// claim records a message as being sent on a channel for config.AlertLimitConfig.ClaimLease, and reports whether an
+// identical message was already sent to the same address or is being sent. A message whose sender stops before
+// sending it is sent again once the lease runs out. When the record cannot be checked the message is sent anyway,
+// since a duplicate alert is better than a missing one.
+func (dispatcher *GfEventDispatcher) claim(ctx context.Context, channel string, recipient messaging.Recipient, message messaging.Message) (string, bool) {
+	if dispatcher.Sent == nil || dispatcher.Channels[channel] == nil {
+		return "", false
+	}
+	address := addressOn(channel, recipient)
+	if address == "" {
+		return "", false
+	}
+
+	key := models.SentMessageKey(channel, address, message.Subject, message.Body)
+	claimed, err := dispatcher.Sent.ClaimMessage(ctx, key, time.Now(), config.AlertLimitConfig.ClaimLease)
+	if err != nil {
+		fmt.Printf("Error checking whether %s for account %s was already sent by %s: %s\n", message.Type, recipient.AccountID, channel, err)
+		return "", false
+	}
+	return key, !claimed
+}
+
+// confirm remembers a delivered message for config.AlertLimitConfig.DedupTTL. When that fails the claim still runs
+// out, and a retry within the dedup window may send the message again.
+func (dispatcher *GfEventDispatcher) confirm(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := dispatcher.Sent.ConfirmMessage(ctx, key, time.Now(), config.AlertLimitConfig.DedupTTL); err != nil {
+		fmt.Printf("Error confirming sent message %s: %s\n", key, err)
+	}
+}
+
+// release forgets a claimed message that failed to send, so a retry sends it
+func (dispatcher *GfEventDispatcher) release(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := dispatcher.Sent.ReleaseMessage(ctx, key); err != nil {
+		fmt.Printf("Error releasing unsent message %s: %s\n", key, err)
+	}
+}
+
+// addressOn is where a channel delivers to a recipient. Webhooks are addressed by account.
+func addressOn(channel string, recipient messaging.Recipient) string {
+	switch channel {
+	case messaging.ChannelSMS:
+		return recipient.PhoneNumber
+	case messaging.ChannelEmail:
+		return recipient.Email
+	}
+	return recipient.AccountID
+}
+
+// preferences returns how an account wants to be contacted. A failed lookup falls back to the defaults rather
+// than holding the message back.
+func (dispatcher *GfEventDispatcher) preferences(ctx context.Context, accountID string) *models.AccountPreferences {
+	if accountID == "" || dispatcher.Preferences == nil {
+		return models.NewAccountPreferences(accountID)
+	}
+	preferences, err := dispatcher.Preferences.GetPreferences(ctx, accountID)
+	if err != nil {
+		fmt.Printf("Error getting preferences for account %s: %s\n", accountID, err)
+		return models.NewAccountPreferences(accountID)
+	}
+	return preferences
+}
+
+// render writes a notification in the account's preferred language, or the notification's locale when the
+// account has none.
+func (dispatcher *GfEventDispatcher) render(notification models.Notification, preferences *models.AccountPreferences) (messaging.Message, error) {
+	locale := notification.Locale
+	if preferences.Language != "" {
+		locale = preferences.Language
+	}
+
+	rendered, err := dispatcher.Templates.Render(notification, locale)
+	if err != nil {
+		return messaging.Message{}, fmt.Errorf("error rendering %s message: %w", notification.Type, err)
+	}
+	return messaging.Message{
+		Type:    notification.Type,
+		Subject: rendered.Subject,
+		Body:    rendered.Body,
+		Locale:  rendered.Locale,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/geo"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+)
+
+// TimeZoneLocator looks up the time zone of a transaction's Location, such as a city
+type TimeZoneLocator interface {
+	LookupTimeZone(city string) (string, bool)
+}
+
+// QuietHours is the part of each night, in the customer's local time, when messages that are not urgent are held
+// back. Start and End are times of day, and quiet hours run past midnight when End is before Start.
+type QuietHours struct {
+	Start       time.Duration
+	End         time.Duration
+	DefaultZone *time.Location
+	UrgentScore int
+	Zones       TimeZoneLocator
+}
+
+// NewQuietHours reads the quiet hours from config.QuietHoursConfig. An invalid config turns quiet hours off rather
+// than holding messages back at the wrong times.
+func NewQuietHours() QuietHours {
+	quietHours, err := ParseQuietHours(config.QuietHoursConfig.Start, config.QuietHoursConfig.End, config.QuietHoursConfig.TimeZone)
+	if err != nil {
+		log.Printf("Warning: quiet hours are off: %s", err)
+		return QuietHours{DefaultZone: time.UTC}
+	}
+	quietHours.UrgentScore = config.QuietHoursConfig.UrgentScore
+	zones, err := geo.ConfiguredDataset()
+	if err != nil {
+		log.Printf("Warning: using bundled time zones: %s", err)
+		zones = geo.BundledDataset()
+	}
+	quietHours.Zones = zones
+	return quietHours
+}
+
+// ConfiguredDeferredQueue returns the queue set in config.QuietHoursConfig, or nil when none is set, in which
+// case nothing is held back for quiet hours
+func ConfiguredDeferredQueue(client messaging.SQSSender) DeferredQueue {
+	if config.QuietHoursConfig.QueueURL == "" {
+		return nil
+	}
+	return messaging.NewSQSDeferredQueue(client, config.QuietHoursConfig.QueueURL)
+}
+
+// ParseQuietHours reads quiet hours from "HH:MM" times of day and the IANA time zone of customers whose own is not known
+func ParseQuietHours(start string, end string, defaultZone string) (QuietHours, error) {
+	startTime, err := parseTimeOfDay(start)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	endTime, err := parseTimeOfDay(end)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+	zone, err := time.LoadLocation(defaultZone)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours time zone: %w", err)
+	}
+	return QuietHours{Start: startTime, End: endTime, DefaultZone: zone}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// Enabled reports whether there are any quiet hours
+func (q QuietHours) Enabled() bool {
+	return q.Start != q.End
+}
+
+// IsUrgent reports whether an alert with a risk score is sent even during quiet hours
+func (q QuietHours) IsUrgent(riskScore int) bool {
+	return riskScore >= q.UrgentScore
+}
+
+// TimeZone returns the customer's time zone: the one they chose, else the one of where they last used their card,
+// else the default.
+func (q QuietHours) TimeZone(preferred string, location string) *time.Location {
+	if preferred != "" {
+		if zone, err := time.LoadLocation(preferred); err == nil {
+			return zone
+		}
+	}
+	if location != "" && q.Zones != nil {
+		if name, ok := q.Zones.LookupTimeZone(location); ok {
+			if zone, err := time.LoadLocation(name); err == nil {
+				return zone
+			}
+		}
+	}
+	if q.DefaultZone == nil {
+		return time.UTC
+	}
+	return q.DefaultZone
+}
+
+// NextAllowed returns when a message that is not urgent may be sent in a time zone: now, unless it is quiet there,
+// in which case it is when the quiet hours end.
+func (q QuietHours) NextAllowed(now time.Time, zone *time.Location) time.Time {
+	if !q.Enabled() {
+		return now
+	}
+
+	local := now.In(zone)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+
+	var quiet, endsTomorrow bool
+	if q.Start < q.End {
+		quiet = sinceMidnight >= q.Start && sinceMidnight < q.End
+	} else {
+		quiet = sinceMidnight >= q.Start || sinceMidnight < q.End
+		endsTomorrow = sinceMidnight >= q.Start
+	}
+	if !quiet {
+		return now
+	}
+
+	day := local
+	if endsTomorrow {
+		day = local.AddDate(0, 0, 1)
+	}
+	// Built from the wall clock time so the end is right on days the clocks change
+	return time.Date(day.Year(), day.Month(), day.Day(), int(q.End/time.Hour), int(q.End%time.Hour/time.Minute), 0, 0, zone)
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// RoutingPolicy orders the channels a fraud alert is tried on. Customers who chose their own order get it,
+// everyone else gets Default.
+type RoutingPolicy struct {
+	Default []string
+}
+
+// NewRoutingPolicy reads the default order from config.RoutingConfig
+func NewRoutingPolicy() RoutingPolicy {
+	return RoutingPolicy{Default: config.RoutingConfig.Channels}
+}
+
+// Route returns the channels to try for a customer, most preferred first
+func (p RoutingPolicy) Route(preferences *models.AccountPreferences) []string {
+	if preferences != nil && len(preferences.Channels) > 0 {
+		return preferences.Channels
+	}
+	return p.Default
+}
+
+// without returns a route with a channel left out
+func without(route []string, channel string) []string {
+	remaining := make([]string, 0, len(route))
+	for _, name := range route {
+		if name != channel {
+			remaining = append(remaining, name)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,110 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// BaselineSource looks up an account's spending baselines, implemented by db.AccountProfileRepository
+type BaselineSource interface {
+	GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error)
+}
+
+// AccountProfileSource is everything the default engine reads from an account's profile
+type AccountProfileSource interface {
+	DeviceProfileSource
+	BaselineSource
+}
+
+// BaselineFeatures compares the transaction amount to the account's spending baseline. Segment is the profile key
+// of the baseline used, which is the account-wide one when the TransactionType and Channel has too few samples.
+// ColdStart is set when even the account-wide baseline has too few samples to be trusted.
+type BaselineFeatures struct {
+	Segment     string
+	SampleCount int64
+	Mean        float64
+	StdDev      float64
+	ZScore      float64
+	Percentile  float64
+	ColdStart   bool
+}
+
+type BaselineEnricher struct {
+	Baselines  BaselineSource
+	MinSamples int64
+}
+
+func NewBaselineEnricher(baselines BaselineSource, minSamples int64) *BaselineEnricher {
+	return &BaselineEnricher{
+		Baselines:  baselines,
+		MinSamples: minSamples,
+	}
+}
+
+func (e *BaselineEnricher) Enrich(ctx context.Context, signals *Signals) error {
+	txn := signals.Transaction
+	baselines, err := e.Baselines.GetSpendingBaselines(ctx, txn.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to get spending baselines: %w", err)
+	}
+
+	baseline, ok := baselines[models.BaselineKey(txn.TransactionType, txn.Channel)]
+	if !ok || baseline.Count < e.MinSamples {
+		baseline, ok = baselines[models.BaselineKey(models.AllSegments, models.AllSegments)]
+	}
+	if !ok {
+		signals.Baseline = &BaselineFeatures{ColdStart: true}
+		return nil
+	}
+
+	signals.Baseline = &BaselineFeatures{
+		Segment:     baseline.ProfileKey,
+		SampleCount: baseline.Count,
+		Mean:        baseline.Mean(),
+		StdDev:      baseline.StdDev(),
+		ZScore:      baseline.ZScore(txn.TransactionAmount),
+		Percentile:  baseline.PercentileRank(txn.TransactionAmount),
+		ColdStart:   baseline.Count < e.MinSamples,
+	}
+	return nil
+}
+
+// AmountAnomalyRule triggers when the amount is far above what the account normally spends, requiring both the
+// z-score and the percentile rank to agree. Until the account has a usable baseline it defers to the Fallback rule.
+type AmountAnomalyRule struct {
+	MaxZScore     float64
+	MaxPercentile float64
+	Weight        int
+	Fallback      FraudRule
+}
+
+func NewAmountAnomalyRule(maxZScore float64, maxPercentile float64, fallback FraudRule) *AmountAnomalyRule {
+	return &AmountAnomalyRule{
+		MaxZScore:     maxZScore,
+		MaxPercentile: maxPercentile,
+		Weight:        AmountAnomalyWeight,
+		Fallback:      fallback,
+	}
+}
+
+func (r *AmountAnomalyRule) Name() string {
+	return RuleAmountAnomaly
+}
+
+func (r *AmountAnomalyRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	baseline := signals.Baseline
+	if baseline == nil || baseline.ColdStart {
+		if r.Fallback == nil {
+			return RuleResult{Weight: r.Weight, ReasonCode: ReasonAmountAnomaly}, nil
+		}
+		return r.Fallback.Evaluate(ctx, signals)
+	}
+
+	return RuleResult{
+		Triggered:  baseline.ZScore >= r.MaxZScore && baseline.Percentile >= r.MaxPercentile,
+		Weight:     r.Weight,
+		ReasonCode: ReasonAmountAnomaly,
+	}, nil
+}
@@ -0,0 +1,241 @@
+package fraud
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/geo"
+)
+
+const (
+	RuleHighAmount          = "HighAmount"
+	RuleLoginAttempts       = "LoginAttempts"
+	RuleTransactionDuration = "TransactionDuration"
+	RuleHighRiskChannel     = "HighRiskChannel"
+	RuleHighRiskMerchant    = "HighRiskMerchant"
+	RuleAccountVelocity     = "AccountVelocity"
+	RuleDeviceVelocity      = "DeviceVelocity"
+	RuleImpossibleTravel    = "ImpossibleTravel"
+	RuleNewDevice           = "NewDevice"
+	RuleNewIP               = "NewIP"
+	RuleAmountAnomaly       = "AmountAnomaly"
+)
+
+// Machine-readable reason codes persisted with a transaction when a rule triggers
+const (
+	ReasonHighAmount          = "HIGH_AMOUNT"
+	ReasonExcessLoginAttempts = "EXCESS_LOGIN_ATTEMPTS"
+	ReasonUnusualDuration     = "UNUSUAL_DURATION"
+	ReasonHighRiskChannel     = "HIGH_RISK_CHANNEL"
+	ReasonHighRiskMerchant    = "HIGH_RISK_MERCHANT"
+	ReasonHighVelocity        = "HIGH_VELOCITY"
+	ReasonDeviceVelocity      = "DEVICE_VELOCITY"
+	ReasonImpossibleTravel    = "IMPOSSIBLE_TRAVEL"
+	ReasonNewDevice           = "NEW_DEVICE"
+	ReasonNewIP               = "NEW_IP_ADDRESS"
+	ReasonAmountAnomaly       = "AMOUNT_ANOMALY"
+)
+
+// Default weight each built-in rule contributes to the risk score when triggered
+const (
+	HighAmountWeight          = 400
+	LoginAttemptsWeight       = 600
+	TransactionDurationWeight = 200
+	HighRiskChannelWeight     = 200
+	HighRiskMerchantWeight    = 600
+	VelocityWeight            = 400
+	ImpossibleTravelWeight    = 600
+	NewDeviceWeight           = 200
+	NewDeviceHighAmountWeight = 500
+	NewIPWeight               = 100
+	AmountAnomalyWeight       = 400
+)
+
+// NewDefaultRuleEngine builds a RuleEngine with the built-in rules using thresholds from config.FraudConfig.
+// Account history, known devices and spending baselines are read from the given sources and locations from the configured geo dataset.
+// The fixed amount threshold only applies until an account has enough history for its spending baseline.
+func NewDefaultRuleEngine(history TransactionHistory, profiles AccountProfileSource) *RuleEngine {
+	return NewRuleEngine(config.FraudConfig.ScoreThreshold, DefaultRules()...).
+		WithEnrichers(DefaultEnrichers(history, profiles)...)
+}
+
+// DefaultRules returns the built-in rules configured from config.FraudConfig
+func DefaultRules() []FraudRule {
+	return []FraudRule{
+		NewAmountAnomalyRule(
+			config.FraudConfig.BaselineMaxZScore,
+			config.FraudConfig.BaselineMaxPercentile,
+			NewAmountThresholdRule(config.FraudConfig.AmountThreshold),
+		),
+		NewLoginAttemptsRule(config.FraudConfig.MaxLoginAttempts),
+		NewTransactionDurationRule(config.FraudConfig.MinTransactionDuration, config.FraudConfig.MaxTransactionDuration),
+		NewChannelRule(config.FraudConfig.HighRiskChannels),
+		NewMerchantRule(config.FraudConfig.HighRiskMerchants),
+		NewAccountVelocityRule(DefaultVelocityLimits()),
+		NewDeviceVelocityRule(DefaultVelocityLimits()),
+		NewImpossibleTravelRule(config.FraudConfig.MaxTravelSpeedKmh, config.FraudConfig.MinTravelDistanceKm),
+		NewNewDeviceRule(config.FraudConfig.NewDeviceHighAmount),
+		NewNewIPRule(),
+	}
+}
+
+// DefaultEnrichers returns the enrichers that populate every feature in Signals
+func DefaultEnrichers(history TransactionHistory, profiles AccountProfileSource) []Enricher {
+	locations, err := geo.ConfiguredDataset()
+	if err != nil {
+		log.Printf("Warning: using bundled locations for impossible travel: %s", err)
+		locations = geo.BundledDataset()
+	}
+	return []Enricher{
+		NewHistoryEnricher(history, DefaultHistoryLookback),
+		NewVelocityEnricher(),
+		NewTravelEnricher(locations),
+		NewDeviceEnricher(profiles),
+		NewBaselineEnricher(profiles, config.FraudConfig.BaselineMinSamples),
+	}
+}
+
+// AmountThresholdRule triggers when the transaction amount exceeds a fixed threshold.
+type AmountThresholdRule struct {
+	Threshold float64
+	Weight    int
+}
+
+func NewAmountThresholdRule(threshold float64) *AmountThresholdRule {
+	return &AmountThresholdRule{
+		Threshold: threshold,
+		Weight:    HighAmountWeight,
+	}
+}
+
+func (r *AmountThresholdRule) Name() string {
+	return RuleHighAmount
+}
+
+func (r *AmountThresholdRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	return RuleResult{
+		Triggered:  signals.Transaction.TransactionAmount > r.Threshold,
+		Weight:     r.Weight,
+		ReasonCode: ReasonHighAmount,
+	}, nil
+}
+
+// LoginAttemptsRule triggers when the customer needed more login attempts than allowed.
+type LoginAttemptsRule struct {
+	MaxAttempts int
+	Weight      int
+}
+
+func NewLoginAttemptsRule(maxAttempts int) *LoginAttemptsRule {
+	return &LoginAttemptsRule{
+		MaxAttempts: maxAttempts,
+		Weight:      LoginAttemptsWeight,
+	}
+}
+
+func (r *LoginAttemptsRule) Name() string {
+	return RuleLoginAttempts
+}
+
+func (r *LoginAttemptsRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	return RuleResult{
+		Triggered:  signals.Transaction.LoginAttempts > r.MaxAttempts,
+		Weight:     r.Weight,
+		ReasonCode: ReasonExcessLoginAttempts,
+	}, nil
+}
+
+// TransactionDurationRule triggers when a transaction was completed suspiciously fast (scripted)
+// or took unusually long. A duration of zero means it was not reported and is ignored.
+type TransactionDurationRule struct {
+	MinSeconds int
+	MaxSeconds int
+	Weight     int
+}
+
+func NewTransactionDurationRule(minSeconds int, maxSeconds int) *TransactionDurationRule {
+	return &TransactionDurationRule{
+		MinSeconds: minSeconds,
+		MaxSeconds: maxSeconds,
+		Weight:     TransactionDurationWeight,
+	}
+}
+
+func (r *TransactionDurationRule) Name() string {
+	return RuleTransactionDuration
+}
+
+func (r *TransactionDurationRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	duration := signals.Transaction.TransactionDuration
+	if duration <= 0 {
+		return RuleResult{Weight: r.Weight, ReasonCode: ReasonUnusualDuration}, nil
+	}
+
+	return RuleResult{
+		Triggered:  duration < r.MinSeconds || (r.MaxSeconds > 0 && duration > r.MaxSeconds),
+		Weight:     r.Weight,
+		ReasonCode: ReasonUnusualDuration,
+	}, nil
+}
+
+// ChannelRule triggers when the transaction came through a channel configured as high risk.
+type ChannelRule struct {
+	HighRiskChannels []string
+	Weight           int
+}
+
+func NewChannelRule(highRiskChannels []string) *ChannelRule {
+	return &ChannelRule{
+		HighRiskChannels: highRiskChannels,
+		Weight:           HighRiskChannelWeight,
+	}
+}
+
+func (r *ChannelRule) Name() string {
+	return RuleHighRiskChannel
+}
+
+func (r *ChannelRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	return RuleResult{
+		Triggered:  containsFold(r.HighRiskChannels, signals.Transaction.Channel),
+		Weight:     r.Weight,
+		ReasonCode: ReasonHighRiskChannel,
+	}, nil
+}
+
+// MerchantRule triggers when the transaction was made with a merchant configured as high risk.
+type MerchantRule struct {
+	HighRiskMerchants []string
+	Weight            int
+}
+
+func NewMerchantRule(highRiskMerchants []string) *MerchantRule {
+	return &MerchantRule{
+		HighRiskMerchants: highRiskMerchants,
+		Weight:            HighRiskMerchantWeight,
+	}
+}
+
+func (r *MerchantRule) Name() string {
+	return RuleHighRiskMerchant
+}
+
+func (r *MerchantRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	return RuleResult{
+		Triggered:  containsFold(r.HighRiskMerchants, signals.Transaction.MerchantID),
+		Weight:     r.Weight,
+		ReasonCode: ReasonHighRiskMerchant,
+	}, nil
+}
+
+func containsFold(values []string, target string) bool {
+	if target == "" {
+		return false
+	}
+	return slices.ContainsFunc(values, func(value string) bool {
+		return strings.EqualFold(value, target)
+	})
+}
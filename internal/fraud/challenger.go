@@ -0,0 +1,112 @@
+package fraud
+
+import (
+	"context"
+	"sync"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Challenger is a candidate Detector evaluated in shadow next to the live one. Its decisions are recorded
+// but never acted on.
+type Challenger struct {
+	Name     string
+	Detector Detector
+}
+
+func NewChallenger(name string, detector Detector) Challenger {
+	return Challenger{
+		Name:     name,
+		Detector: detector,
+	}
+}
+
+// ChallengerStats counts how often a challenger disagreed with the champion on whether a transaction is fraud.
+type ChallengerStats struct {
+	Evaluated     int
+	Disagreements int
+	Errors        int
+}
+
+// DisagreementRate is the fraction of successfully evaluated transactions where the challenger disagreed
+func (s ChallengerStats) DisagreementRate() float64 {
+	if s.Evaluated == 0 {
+		return 0
+	}
+	return float64(s.Disagreements) / float64(s.Evaluated)
+}
+
+// ChallengerMetrics accumulates ChallengerStats per challenger and is safe for concurrent use.
+type ChallengerMetrics struct {
+	mu    sync.Mutex
+	stats map[string]ChallengerStats
+}
+
+func NewChallengerMetrics() *ChallengerMetrics {
+	return &ChallengerMetrics{
+		stats: make(map[string]ChallengerStats),
+	}
+}
+
+func (m *ChallengerMetrics) Record(decision models.ShadowDecision) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats[decision.Challenger]
+	if decision.Error != "" {
+		stats.Errors++
+	} else {
+		stats.Evaluated++
+		if decision.Disagrees {
+			stats.Disagreements++
+		}
+	}
+	m.stats[decision.Challenger] = stats
+}
+
+// Snapshot returns a copy of the stats collected so far
+func (m *ChallengerMetrics) Snapshot() map[string]ChallengerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]ChallengerStats, len(m.stats))
+	for name, stats := range m.stats {
+		snapshot[name] = stats
+	}
+	return snapshot
+}
+
+// StartChallengers evaluates every challenger concurrently. The returned function waits for them and compares
+// their decisions to the champion's. A challenger that fails is recorded with its error rather than failing the transaction.
+func StartChallengers(ctx context.Context, challengers []Challenger, transaction models.Transaction) func(champion *Decision) []models.ShadowDecision {
+	decisions := make([]*Decision, len(challengers))
+	errs := make([]error, len(challengers))
+
+	var wg sync.WaitGroup
+	for i, challenger := range challengers {
+		wg.Add(1)
+		go func(i int, challenger Challenger) {
+			defer wg.Done()
+			decisions[i], errs[i] = challenger.Detector.Evaluate(ctx, transaction)
+		}(i, challenger)
+	}
+
+	return func(champion *Decision) []models.ShadowDecision {
+		wg.Wait()
+
+		var shadowDecisions []models.ShadowDecision
+		for i, challenger := range challengers {
+			shadow := models.ShadowDecision{Challenger: challenger.Name}
+			if errs[i] != nil {
+				shadow.Error = errs[i].Error()
+			} else {
+				shadow.Score = decisions[i].Score
+				shadow.IsFraud = decisions[i].IsFraud
+				shadow.ReasonCodes = decisions[i].ReasonCodes
+				shadow.Disagrees = decisions[i].IsFraud != champion.IsFraud
+			}
+			shadowDecisions = append(shadowDecisions, shadow)
+		}
+		return shadowDecisions
+	}
+}
@@ -0,0 +1,122 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// DeviceProfileSource looks up an account's known devices, implemented by db.AccountProfileRepository
+type DeviceProfileSource interface {
+	GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error)
+}
+
+// DeviceFeatures describes whether the transaction's device and IP address are known for the account
+// and how long ago they were last seen. HasHistory is false for accounts with no known devices or IPs.
+type DeviceFeatures struct {
+	HasHistory        bool
+	IsNewDevice       bool
+	DeviceLastSeenAgo time.Duration
+	IsNewIP           bool
+	IPLastSeenAgo     time.Duration
+}
+
+type DeviceEnricher struct {
+	Profiles DeviceProfileSource
+}
+
+func NewDeviceEnricher(profiles DeviceProfileSource) *DeviceEnricher {
+	return &DeviceEnricher{
+		Profiles: profiles,
+	}
+}
+
+func (e *DeviceEnricher) Enrich(ctx context.Context, signals *Signals) error {
+	txn := signals.Transaction
+	profile, err := e.Profiles.GetDeviceProfile(ctx, txn.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to get device profile: %w", err)
+	}
+
+	features := &DeviceFeatures{
+		HasHistory: !profile.IsEmpty(),
+	}
+	features.IsNewDevice, features.DeviceLastSeenAgo = lastSeen(profile.Devices, txn.DeviceID, signals.TransactionTime)
+	features.IsNewIP, features.IPLastSeenAgo = lastSeen(profile.IPAddresses, txn.IPAddress, signals.TransactionTime)
+
+	signals.Device = features
+	return nil
+}
+
+// lastSeen reports whether value is new and, if not, how long before now it was last seen.
+// An empty value is never considered new since there is nothing to compare.
+func lastSeen(sightings map[string]models.Sighting, value string, now time.Time) (bool, time.Duration) {
+	if value == "" {
+		return false, 0
+	}
+	sighting, ok := sightings[value]
+	if !ok {
+		return true, 0
+	}
+	return false, max(0, now.Sub(sighting.LastSeenTime()))
+}
+
+// NewDeviceRule triggers when an account with known devices transacts from an unknown one,
+// weighing it more heavily when the amount is also high.
+type NewDeviceRule struct {
+	HighAmount       float64
+	Weight           int
+	HighAmountWeight int
+}
+
+func NewNewDeviceRule(highAmount float64) *NewDeviceRule {
+	return &NewDeviceRule{
+		HighAmount:       highAmount,
+		Weight:           NewDeviceWeight,
+		HighAmountWeight: NewDeviceHighAmountWeight,
+	}
+}
+
+func (r *NewDeviceRule) Name() string {
+	return RuleNewDevice
+}
+
+func (r *NewDeviceRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	result := RuleResult{Weight: r.Weight, ReasonCode: ReasonNewDevice}
+	device := signals.Device
+	if device == nil || !device.HasHistory || !device.IsNewDevice {
+		return result, nil
+	}
+
+	result.Triggered = true
+	if signals.Transaction.TransactionAmount > r.HighAmount {
+		result.Weight = r.HighAmountWeight
+	}
+	return result, nil
+}
+
+// NewIPRule triggers when an account with known IP addresses transacts from an unknown one.
+type NewIPRule struct {
+	Weight int
+}
+
+func NewNewIPRule() *NewIPRule {
+	return &NewIPRule{
+		Weight: NewIPWeight,
+	}
+}
+
+func (r *NewIPRule) Name() string {
+	return RuleNewIP
+}
+
+func (r *NewIPRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	device := signals.Device
+	return RuleResult{
+		Triggered:  device != nil && device.HasHistory && device.IsNewIP,
+		Weight:     r.Weight,
+		ReasonCode: ReasonNewIP,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Risk scores are always reported on a 0 - MaxRiskScore scale
+const (
+	MinRiskScore = 0
+	MaxRiskScore = 1000
+)
+
+// Decision is the combined result of running every rule in a RuleEngine.
+type Decision struct {
+	IsFraud        bool
+	Score          int
+	ReasonCodes    []string
+	TriggeredRules []string
+}
+
+// Detector is a fraud scoring backend used by the fraud service, such as a RuleEngine or a ModelDetector.
+type Detector interface {
+	Evaluate(ctx context.Context, transaction models.Transaction) (*Decision, error)
+}
+
+type RuleEngine struct {
+	Enrichers []Enricher
+	Rules     []FraudRule
+	Threshold int
+}
+
+func NewRuleEngine(threshold int, rules ...FraudRule) *RuleEngine {
+	return &RuleEngine{
+		Rules:     rules,
+		Threshold: threshold,
+	}
+}
+
+// WithEnrichers sets the enrichers that run before the rules
+func (re *RuleEngine) WithEnrichers(enrichers ...Enricher) *RuleEngine {
+	re.Enrichers = enrichers
+	return re
+}
+
+// Evaluate enriches the transaction then runs every rule in order and sums the weights of the ones that triggered into a risk score
+// capped at MaxRiskScore. The transaction is considered fraudulent once the score reaches the engine threshold.
+func (re *RuleEngine) Evaluate(ctx context.Context, transaction models.Transaction) (*Decision, error) {
+	signals := NewSignals(transaction)
+	decision := &Decision{}
+
+	for _, enricher := range re.Enrichers {
+		if err := enricher.Enrich(ctx, signals); err != nil {
+			return nil, fmt.Errorf("failed to enrich transaction %s: %w", transaction.TransactionID, err)
+		}
+	}
+
+	for _, rule := range re.Rules {
+		result, err := rule.Evaluate(ctx, signals)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s failed for transaction %s: %w", rule.Name(), transaction.TransactionID, err)
+		}
+
+		if result.Triggered {
+			decision.Score += result.Weight
+			decision.TriggeredRules = append(decision.TriggeredRules, rule.Name())
+			if result.ReasonCode != "" && !slices.Contains(decision.ReasonCodes, result.ReasonCode) {
+				decision.ReasonCodes = append(decision.ReasonCodes, result.ReasonCode)
+			}
+		}
+	}
+
+	decision.Score = ClampRiskScore(decision.Score)
+	decision.IsFraud = decision.Score >= re.Threshold
+
+	return decision, nil
+}
+
+// ClampRiskScore bounds a score to the MinRiskScore - MaxRiskScore range
+func ClampRiskScore(score int) int {
+	return max(MinRiskScore, min(MaxRiskScore, score))
+}
@@ -0,0 +1,59 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Default amount of history loaded for history based features such as velocity and travel
+const DefaultHistoryLookback = 24 * time.Hour
+
+// TransactionHistory looks up recent transactions, implemented by db.TransactionRepository
+type TransactionHistory interface {
+	GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error)
+	GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error)
+}
+
+// HistoryEnricher loads the account's and device's recent transactions once so every
+// history based enricher after it can share them.
+type HistoryEnricher struct {
+	History  TransactionHistory
+	Lookback time.Duration
+}
+
+func NewHistoryEnricher(history TransactionHistory, lookback time.Duration) *HistoryEnricher {
+	return &HistoryEnricher{
+		History:  history,
+		Lookback: lookback,
+	}
+}
+
+func (e *HistoryEnricher) Enrich(ctx context.Context, signals *Signals) error {
+	txn := signals.Transaction
+	start := signals.TransactionTime.Add(-e.Lookback)
+
+	accountHistory, err := e.History.GetTransactionsByAccountAndTimeRange(ctx, txn.AccountID, start, signals.TransactionTime)
+	if err != nil {
+		return fmt.Errorf("failed to get account history: %w", err)
+	}
+	signals.AccountHistory = accountHistory
+
+	if txn.DeviceID != "" {
+		deviceHistory, err := e.History.GetTransactionsByDeviceAndTimeRange(ctx, txn.DeviceID, start, signals.TransactionTime)
+		if err != nil {
+			return fmt.Errorf("failed to get device history: %w", err)
+		}
+		signals.DeviceHistory = deviceHistory
+	}
+
+	return nil
+}
+
+// isSameTransaction reports whether a history entry is the transaction being evaluated, which is
+// already in the table by the time the fraud pipeline sees it.
+func isSameTransaction(a models.Transaction, b models.Transaction) bool {
+	return a.AccountID == b.AccountID && a.TransactionID == b.TransactionID
+}
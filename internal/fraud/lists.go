@@ -0,0 +1,75 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+const (
+	ReasonAllowlist = "ALLOWLIST"
+	ReasonDenylist  = "DENYLIST"
+)
+
+// ListSource looks up allowlist and denylist entries, implemented by db.ListRepository
+type ListSource interface {
+	GetListEntries(ctx context.Context, listKeys []string) ([]models.ListEntry, error)
+}
+
+// MatchList finds the unexpired list entry that decides the transaction, or nil if none applies.
+// A denylist entry wins over an allowlist entry so a trusted merchant cannot launder a known-bad device, and an entry
+// that forces the decision wins over one that only suppresses alerts.
+func MatchList(ctx context.Context, lists ListSource, transaction models.Transaction, now time.Time) (*models.ListEntry, error) {
+	entries, err := lists.GetListEntries(ctx, models.TransactionListKeys(transaction))
+	if err != nil {
+		return nil, err
+	}
+
+	var match *models.ListEntry
+	for i := range entries {
+		entry := &entries[i]
+		if entry.IsExpired(now) {
+			continue
+		}
+		if match == nil || listPrecedence(*entry) > listPrecedence(*match) {
+			match = entry
+		}
+	}
+	return match, nil
+}
+
+func listPrecedence(entry models.ListEntry) int {
+	switch {
+	case entry.List == models.ListDeny:
+		return 2
+	case SuppressesAlerts(entry.Match()):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// SuppressesAlerts reports whether a matched entry only keeps alerts from being sent. A test account on the
+// allowlist is still scored so its decisions can be checked, while every other entry forces the decision.
+func SuppressesAlerts(match *models.ListMatch) bool {
+	return match != nil && match.List == models.ListAllow && match.Kind == models.ListKindAccount
+}
+
+// ListDecision is the forced decision for a matched entry: the maximum score for a denylist entry and the minimum for an allowlist entry.
+func ListDecision(entry *models.ListEntry) *Decision {
+	if entry.List == models.ListDeny {
+		return &Decision{
+			IsFraud:        true,
+			Score:          MaxRiskScore,
+			ReasonCodes:    []string{fmt.Sprintf("%s_%s", ReasonDenylist, entry.Kind)},
+			TriggeredRules: []string{entry.ListKey},
+		}
+	}
+	return &Decision{
+		Score:          MinRiskScore,
+		ReasonCodes:    []string{fmt.Sprintf("%s_%s", ReasonAllowlist, entry.Kind)},
+		TriggeredRules: []string{entry.ListKey},
+	}
+}
@@ -0,0 +1,42 @@
+package fraud
+
+import (
+	"context"
+	"math"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/ml"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+const (
+	DetectorModel    = "Model"
+	ReasonModelScore = "MODEL_SCORE"
+)
+
+// ModelDetector scores transactions with a trained model, scaling its fraud probability to the risk score range.
+type ModelDetector struct {
+	Model     ml.Model
+	Threshold int
+}
+
+func NewModelDetector(model ml.Model, threshold int) *ModelDetector {
+	return &ModelDetector{
+		Model:     model,
+		Threshold: threshold,
+	}
+}
+
+func (d *ModelDetector) Evaluate(ctx context.Context, transaction models.Transaction) (*Decision, error) {
+	probability := d.Model.Predict(ml.ExtractFeatures(transaction, d.Model.FeatureNames()))
+	score := ClampRiskScore(int(math.Round(probability * MaxRiskScore)))
+
+	decision := &Decision{
+		Score:   score,
+		IsFraud: score >= d.Threshold,
+	}
+	if decision.IsFraud {
+		decision.ReasonCodes = []string{ReasonModelScore}
+		decision.TriggeredRules = []string{DetectorModel}
+	}
+	return decision, nil
+}
@@ -0,0 +1,52 @@
+package fraud
+
+import (
+	"context"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// FraudRule is a single check run by the RuleEngine against a transaction.
+type FraudRule interface {
+	Name() string
+	Evaluate(ctx context.Context, signals *Signals) (RuleResult, error)
+}
+
+// RuleResult is the outcome of a single FraudRule evaluation.
+type RuleResult struct {
+	Triggered  bool
+	Weight     int
+	ReasonCode string
+}
+
+// Enricher gathers data about a transaction before the rules run, such as account history.
+type Enricher interface {
+	Enrich(ctx context.Context, signals *Signals) error
+}
+
+// Signals holds the transaction being evaluated along with any data gathered about it
+// that rules may need. Feature fields are nil when no enricher populated them.
+type Signals struct {
+	Transaction     models.Transaction
+	TransactionTime time.Time
+	AccountHistory  []models.Transaction
+	DeviceHistory   []models.Transaction
+	Velocity        *VelocityFeatures
+	Travel          *TravelFeatures
+	Device          *DeviceFeatures
+	Baseline        *BaselineFeatures
+}
+
+// NewSignals anchors the evaluation at the transaction's own time, or now if it has none.
+func NewSignals(transaction models.Transaction) *Signals {
+	transactionTime := transaction.GetTransactionTime()
+	if transactionTime.IsZero() {
+		transactionTime = time.Now().UTC()
+	}
+
+	return &Signals{
+		Transaction:     transaction,
+		TransactionTime: transactionTime,
+	}
+}
@@ -0,0 +1,124 @@
+package fraud
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/geo"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// TravelFeatures describes the move from the account's previous transaction to the current one.
+// SpeedKmh is +Inf when both happened at the same instant in different places.
+type TravelFeatures struct {
+	PreviousTransactionID string
+	DistanceKm            float64
+	Elapsed               time.Duration
+	SpeedKmh              float64
+}
+
+// TravelEnricher resolves the current and previous transaction's Location and IPAddress and
+// computes the implied travel speed between them.
+type TravelEnricher struct {
+	Locator geo.Locator
+}
+
+func NewTravelEnricher(locator geo.Locator) *TravelEnricher {
+	return &TravelEnricher{
+		Locator: locator,
+	}
+}
+
+func (e *TravelEnricher) Enrich(ctx context.Context, signals *Signals) error {
+	previous, ok := previousTransaction(signals)
+	if !ok {
+		return nil
+	}
+
+	// Compare city to city and IP to IP, using whichever implies the fastest travel
+	distance := math.Max(
+		e.distance(e.Locator.LookupCity, previous.Location, signals.Transaction.Location),
+		e.distance(e.Locator.LookupIP, previous.IPAddress, signals.Transaction.IPAddress),
+	)
+	if distance < 0 {
+		return nil
+	}
+
+	elapsed := signals.TransactionTime.Sub(previous.GetTransactionTime())
+	speed := math.Inf(1)
+	if elapsed > 0 {
+		speed = distance / elapsed.Hours()
+	} else if distance == 0 {
+		speed = 0
+	}
+
+	signals.Travel = &TravelFeatures{
+		PreviousTransactionID: previous.TransactionID,
+		DistanceKm:            distance,
+		Elapsed:               elapsed,
+		SpeedKmh:              speed,
+	}
+	return nil
+}
+
+// distance returns -1 when either side cannot be resolved
+func (e *TravelEnricher) distance(lookup func(string) (geo.Coordinates, bool), from string, to string) float64 {
+	fromCoordinates, fromOk := lookup(from)
+	toCoordinates, toOk := lookup(to)
+	if !fromOk || !toOk {
+		return -1
+	}
+	return geo.DistanceKm(fromCoordinates, toCoordinates)
+}
+
+// previousTransaction finds the account's most recent transaction at or before the current one.
+func previousTransaction(signals *Signals) (models.Transaction, bool) {
+	var previous models.Transaction
+	var previousTime time.Time
+	found := false
+
+	for _, txn := range signals.AccountHistory {
+		if isSameTransaction(txn, signals.Transaction) {
+			continue
+		}
+		txnTime := txn.GetTransactionTime()
+		if txnTime.After(signals.TransactionTime) {
+			continue
+		}
+		if !found || txnTime.After(previousTime) {
+			previous, previousTime, found = txn, txnTime, true
+		}
+	}
+
+	return previous, found
+}
+
+// ImpossibleTravelRule triggers when the account would have had to move faster than is physically
+// plausible between consecutive transactions. Short hops are ignored to absorb IP geolocation error.
+type ImpossibleTravelRule struct {
+	MaxSpeedKmh   float64
+	MinDistanceKm float64
+	Weight        int
+}
+
+func NewImpossibleTravelRule(maxSpeedKmh float64, minDistanceKm float64) *ImpossibleTravelRule {
+	return &ImpossibleTravelRule{
+		MaxSpeedKmh:   maxSpeedKmh,
+		MinDistanceKm: minDistanceKm,
+		Weight:        ImpossibleTravelWeight,
+	}
+}
+
+func (r *ImpossibleTravelRule) Name() string {
+	return RuleImpossibleTravel
+}
+
+func (r *ImpossibleTravelRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	travel := signals.Travel
+	return RuleResult{
+		Triggered:  travel != nil && travel.DistanceKm >= r.MinDistanceKm && travel.SpeedKmh > r.MaxSpeedKmh,
+		Weight:     r.Weight,
+		ReasonCode: ReasonImpossibleTravel,
+	}, nil
+}
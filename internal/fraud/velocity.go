@@ -0,0 +1,142 @@
+package fraud
+
+import (
+	"context"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Sliding windows velocity features are computed over
+var VelocityWindows = []time.Duration{5 * time.Minute, time.Hour, 24 * time.Hour}
+
+// VelocityWindow is the number and summed amount of transactions within a window, including the current one.
+type VelocityWindow struct {
+	Count  int
+	Amount float64
+}
+
+// VelocityFeatures holds per window activity for the transaction's account and device.
+type VelocityFeatures struct {
+	Account map[time.Duration]VelocityWindow
+	Device  map[time.Duration]VelocityWindow
+}
+
+// VelocityEnricher buckets the account and device history gathered by the HistoryEnricher into each window.
+type VelocityEnricher struct {
+	Windows []time.Duration
+}
+
+func NewVelocityEnricher() *VelocityEnricher {
+	return &VelocityEnricher{
+		Windows: VelocityWindows,
+	}
+}
+
+func (e *VelocityEnricher) Enrich(ctx context.Context, signals *Signals) error {
+	features := &VelocityFeatures{
+		Account: e.aggregate(signals.Transaction, signals.AccountHistory, signals.TransactionTime),
+	}
+	if signals.Transaction.DeviceID != "" {
+		features.Device = e.aggregate(signals.Transaction, signals.DeviceHistory, signals.TransactionTime)
+	}
+
+	signals.Velocity = features
+	return nil
+}
+
+func (e *VelocityEnricher) aggregate(current models.Transaction, history []models.Transaction, anchor time.Time) map[time.Duration]VelocityWindow {
+	windows := make(map[time.Duration]VelocityWindow, len(e.Windows))
+
+	for _, window := range e.Windows {
+		result := VelocityWindow{Count: 1, Amount: current.TransactionAmount}
+		for _, txn := range history {
+			if isSameTransaction(txn, current) {
+				continue
+			}
+			txnTime := txn.GetTransactionTime()
+			if txnTime.Before(anchor.Add(-window)) || txnTime.After(anchor) {
+				continue
+			}
+			result.Count++
+			result.Amount += txn.TransactionAmount
+		}
+		windows[window] = result
+	}
+
+	return windows
+}
+
+// VelocityLimit is the most activity allowed within a window, a zero limit is not checked.
+type VelocityLimit struct {
+	Window    time.Duration
+	MaxCount  int
+	MaxAmount float64
+}
+
+// DefaultVelocityLimits builds the velocity limits from config.FraudConfig
+func DefaultVelocityLimits() []VelocityLimit {
+	return []VelocityLimit{
+		{Window: 5 * time.Minute, MaxCount: config.FraudConfig.VelocityMaxCount5m},
+		{Window: time.Hour, MaxCount: config.FraudConfig.VelocityMaxCount1h, MaxAmount: config.FraudConfig.VelocityMaxAmount1h},
+		{Window: 24 * time.Hour, MaxCount: config.FraudConfig.VelocityMaxCount24h, MaxAmount: config.FraudConfig.VelocityMaxAmount24h},
+	}
+}
+
+// VelocityRule triggers when either the account's or the device's activity exceeds any limit.
+type VelocityRule struct {
+	Limits   []VelocityLimit
+	Weight   int
+	ByDevice bool
+}
+
+func NewAccountVelocityRule(limits []VelocityLimit) *VelocityRule {
+	return &VelocityRule{
+		Limits: limits,
+		Weight: VelocityWeight,
+	}
+}
+
+func NewDeviceVelocityRule(limits []VelocityLimit) *VelocityRule {
+	return &VelocityRule{
+		Limits:   limits,
+		Weight:   VelocityWeight,
+		ByDevice: true,
+	}
+}
+
+func (r *VelocityRule) Name() string {
+	if r.ByDevice {
+		return RuleDeviceVelocity
+	}
+	return RuleAccountVelocity
+}
+
+func (r *VelocityRule) Evaluate(ctx context.Context, signals *Signals) (RuleResult, error) {
+	result := RuleResult{Weight: r.Weight, ReasonCode: ReasonHighVelocity}
+	if r.ByDevice {
+		result.ReasonCode = ReasonDeviceVelocity
+	}
+	if signals.Velocity == nil {
+		return result, nil
+	}
+
+	windows := signals.Velocity.Account
+	if r.ByDevice {
+		windows = signals.Velocity.Device
+	}
+
+	for _, limit := range r.Limits {
+		window, ok := windows[limit.Window]
+		if !ok {
+			continue
+		}
+		if (limit.MaxCount > 0 && window.Count > limit.MaxCount) || (limit.MaxAmount > 0 && window.Amount > limit.MaxAmount) {
+			result.Triggered = true
+			break
+		}
+	}
+
+	return result, nil
+}
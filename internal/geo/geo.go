@@ -0,0 +1,250 @@
+package geo
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Lambda runtimes do not ship zoneinfo, so time zones are loaded from the binary
+	_ "time/tzdata"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+)
+
+const earthRadiusKm = 6371.0
+
+// Bundled dataset of the cities we can resolve without network access. Rows are "city,<name>,<lat>,<lon>,<timezone>"
+// or "cidr,<network>,<lat>,<lon>,".
+//
+//go:embed data/locations.csv
+var bundledLocations []byte
+
+// Bundled IP ranges, generated from the DB-IP Lite city database by "make geodata" (cmd/geodata). DB-IP Lite is
+// licensed under CC BY 4.0, see https://db-ip.com.
+//
+//go:embed data/networks.csv
+var bundledNetworks []byte
+
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Locator resolves city names and IP addresses to coordinates.
+type Locator interface {
+	LookupCity(city string) (Coordinates, bool)
+	LookupIP(ip string) (Coordinates, bool)
+}
+
+// Dataset is an in-memory Locator loaded from a locations file.
+type Dataset struct {
+	cities   map[string]Coordinates
+	zones    map[string]string
+	networks map[netip.Prefix]Coordinates
+	// Distinct prefix lengths in networks, longest first, so lookups try the most specific network first
+	prefixLengths []int
+}
+
+var (
+	bundledDataset        *Dataset
+	bundledDatasetOnce    sync.Once
+	configuredDataset     *Dataset
+	configuredDatasetErr  error
+	configuredDatasetOnce sync.Once
+)
+
+// LoadDataset parses a locations CSV with a type,key,latitude,longitude header. An optional fifth timezone column
+// gives the IANA time zone of each city.
+func LoadDataset(reader io.Reader) (*Dataset, error) {
+	dataset := &Dataset{
+		cities:   make(map[string]Coordinates),
+		zones:    make(map[string]string),
+		networks: make(map[netip.Prefix]Coordinates),
+	}
+	if err := dataset.load(reader); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+func (d *Dataset) load(reader io.Reader) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read locations header: %w", err)
+	}
+	if len(header) < 4 || len(header) > 5 || strings.ToLower(header[0]) != "type" {
+		return fmt.Errorf("unexpected locations header: %v", header)
+	}
+	csvReader.FieldsPerRecord = len(header)
+
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read locations line %d: %w", line, err)
+		}
+
+		latitude, latErr := strconv.ParseFloat(record[2], 64)
+		longitude, lonErr := strconv.ParseFloat(record[3], 64)
+		if latErr != nil || lonErr != nil {
+			return fmt.Errorf("invalid coordinates on locations line %d", line)
+		}
+		coordinates := Coordinates{Latitude: latitude, Longitude: longitude}
+
+		switch record[0] {
+		case "city":
+			d.cities[normalizeCity(record[1])] = coordinates
+			if len(record) == 5 && record[4] != "" {
+				if _, err := time.LoadLocation(record[4]); err != nil {
+					return fmt.Errorf("invalid time zone on locations line %d: %w", line, err)
+				}
+				d.zones[normalizeCity(record[1])] = record[4]
+			}
+		case "cidr":
+			prefix, err := netip.ParsePrefix(record[1])
+			if err != nil {
+				return fmt.Errorf("invalid network on locations line %d: %w", line, err)
+			}
+			d.addNetwork(prefix, coordinates)
+		default:
+			return fmt.Errorf("unknown location type %q on line %d", record[0], line)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dataset) addNetwork(prefix netip.Prefix, coordinates Coordinates) {
+	// IPv4 networks are stored as IPv4 so IPv4-mapped IPv6 addresses resolve to them
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	prefix = prefix.Masked()
+	if _, ok := d.networks[prefix]; !ok {
+		index := sort.Search(len(d.prefixLengths), func(i int) bool { return d.prefixLengths[i] <= prefix.Bits() })
+		if index == len(d.prefixLengths) || d.prefixLengths[index] != prefix.Bits() {
+			d.prefixLengths = append(d.prefixLengths, 0)
+			copy(d.prefixLengths[index+1:], d.prefixLengths[index:])
+			d.prefixLengths[index] = prefix.Bits()
+		}
+	}
+	d.networks[prefix] = coordinates
+}
+
+// LoadDatasetFile loads a locations CSV from local disk.
+func LoadDatasetFile(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open locations file: %w", err)
+	}
+	defer file.Close()
+
+	return LoadDataset(file)
+}
+
+// BundledDataset returns the cities and IP ranges shipped with the binary. Both files are checked by the tests, so
+// an invalid one is a build error and panics.
+func BundledDataset() *Dataset {
+	bundledDatasetOnce.Do(func() {
+		dataset, err := LoadDataset(bytes.NewReader(bundledLocations))
+		if err == nil {
+			err = dataset.load(bytes.NewReader(bundledNetworks))
+		}
+		if err != nil {
+			panic(fmt.Sprintf("bundled locations dataset is invalid: %s", err))
+		}
+		if len(dataset.networks) == 0 {
+			log.Printf("Warning: the bundled locations dataset has no IP ranges, run \"make geodata\" to generate them")
+		}
+		bundledDataset = dataset
+	})
+	return bundledDataset
+}
+
+// ConfiguredDataset loads the file at config.GeoConfig.DatasetPath once, or returns the bundled dataset when no
+// path is set. A file that cannot be loaded is an error, and callers fall back to the bundled dataset.
+func ConfiguredDataset() (*Dataset, error) {
+	configuredDatasetOnce.Do(func() {
+		path := config.GeoConfig.DatasetPath
+		if path == "" {
+			configuredDataset = BundledDataset()
+			return
+		}
+		configuredDataset, configuredDatasetErr = LoadDatasetFile(path)
+		if configuredDatasetErr != nil {
+			configuredDatasetErr = fmt.Errorf("locations dataset %s could not be loaded: %w", path, configuredDatasetErr)
+			return
+		}
+		if len(configuredDataset.networks) == 0 {
+			log.Printf("Warning: locations dataset %s has no IP ranges, impossible travel is only checked between cities", path)
+		}
+	})
+	return configuredDataset, configuredDatasetErr
+}
+
+func (d *Dataset) LookupCity(city string) (Coordinates, bool) {
+	coordinates, ok := d.cities[normalizeCity(city)]
+	return coordinates, ok
+}
+
+// LookupTimeZone returns the IANA time zone of a city, when the dataset has one
+func (d *Dataset) LookupTimeZone(city string) (string, bool) {
+	zone, ok := d.zones[normalizeCity(city)]
+	return zone, ok
+}
+
+func (d *Dataset) LookupIP(ip string) (Coordinates, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return Coordinates{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	// Prefer the most specific network containing the address
+	for _, bits := range d.prefixLengths {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if coordinates, ok := d.networks[prefix]; ok {
+			return coordinates, true
+		}
+	}
+	return Coordinates{}, false
+}
+
+// DistanceKm returns the great-circle distance between two points using the haversine formula.
+func DistanceKm(from Coordinates, to Coordinates) float64 {
+	lat1 := toRadians(from.Latitude)
+	lat2 := toRadians(to.Latitude)
+	deltaLat := toRadians(to.Latitude - from.Latitude)
+	deltaLon := toRadians(to.Longitude - from.Longitude)
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func normalizeCity(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
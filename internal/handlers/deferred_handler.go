@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type DeferredHandler interface {
+	ProcessDeferredEvent(ctx context.Context, event events.SQSEvent) (*models.BatchResult, error)
+}
+
+// DeferredSender sends a held message and returns the channel it went out on, as events.GfEventDispatcher does
+type DeferredSender interface {
+	DispatchDeferredEvent(message messaging.DeferredMessage) (string, error)
+}
+
+// DeferredRequeuer holds a message back again, as messaging.SQSDeferredQueue does
+type DeferredRequeuer interface {
+	Defer(ctx context.Context, message messaging.DeferredMessage, now time.Time) error
+}
+
+// AlertChannelRecorder records the channel a transaction's alert went out on, as db.TransactionRepository does
+type AlertChannelRecorder interface {
+	UpdateAlertChannel(ctx context.Context, accountID, transactionID string, channel string) error
+}
+
+type GfDeferredHandler struct {
+	sender       DeferredSender
+	queue        DeferredRequeuer
+	transactions AlertChannelRecorder
+}
+
+func NewDeferredHandler(sender DeferredSender, queue DeferredRequeuer, transactions AlertChannelRecorder) *GfDeferredHandler {
+	return &GfDeferredHandler{
+		sender:       sender,
+		queue:        queue,
+		transactions: transactions,
+	}
+}
+
+// ProcessDeferredEvent sends the messages in a batch that are due and queues the rest again, since SQS cannot delay
+// a message for as long as quiet hours last. A held alert's channel is recorded on its transactions, so escalation
+// leaves that channel out. Only the messages that failed are redelivered, and an alert redelivered because its
+// channel was not recorded is not sent again. Messages that cannot be read are dropped, since retrying them cannot
+// make them readable.
+func (dh *GfDeferredHandler) ProcessDeferredEvent(ctx context.Context, event events.SQSEvent) (*models.BatchResult, error) {
+	now := time.Now()
+	var failedRIDs []string
+	for _, record := range event.Records {
+		var message messaging.DeferredMessage
+		if err := json.Unmarshal([]byte(record.Body), &message); err != nil {
+			fmt.Printf("Dropping unreadable deferred message %s: %s\n", record.MessageId, err)
+			continue
+		}
+
+		var err error
+		if message.IsDue(now) {
+			err = dh.send(ctx, message)
+		} else {
+			err = dh.queue.Defer(ctx, message, now)
+		}
+		if err != nil {
+			fmt.Printf("Error processing deferred message %s: %s\n", record.MessageId, err)
+			failedRIDs = append(failedRIDs, record.MessageId)
+		}
+	}
+
+	batchResultInput := &middleware.GetBatchResultInput{
+		FailedRIDs: failedRIDs,
+	}
+
+	return middleware.GetBatchResult(batchResultInput)
+}
+
+// send delivers a due message and records the channel a held alert or digest went out on for each of its transactions
+func (dh *GfDeferredHandler) send(ctx context.Context, message messaging.DeferredMessage) error {
+	channel, err := dh.sender.DispatchDeferredEvent(message)
+	if err != nil || channel == "" {
+		return err
+	}
+	var failures []error
+	for _, transactionID := range message.TransactionIDs {
+		if err := dh.transactions.UpdateAlertChannel(ctx, message.Recipient.AccountID, transactionID, channel); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
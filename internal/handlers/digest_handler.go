@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type DigestHandler interface {
+	ProcessDigestEvent(ctx context.Context, event events.CloudWatchEvent) error
+}
+
+type GfDigestHandler struct {
+	digestService services.DigestService
+}
+
+func NewDigestHandler(digestService services.DigestService) *GfDigestHandler {
+	return &GfDigestHandler{
+		digestService: digestService,
+	}
+}
+
+// ProcessDigestEvent sends the digests due at the scheduled time of the event
+func (dh *GfDigestHandler) ProcessDigestEvent(ctx context.Context, event events.CloudWatchEvent) error {
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	summary, err := dh.digestService.FlushDigests(ctx, now)
+	if summary != nil {
+		fmt.Printf("Sent %d held alerts covering %d transactions, failed windows: %d\n", summary.Alerts, summary.Transactions, summary.Failed)
+	}
+	return err
+}
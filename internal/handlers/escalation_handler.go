@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type EscalationHandler interface {
+	ProcessEscalationEvent(ctx context.Context, event events.CloudWatchEvent) error
+}
+
+type GfEscalationHandler struct {
+	escalationService services.EscalationService
+}
+
+func NewEscalationHandler(escalationService services.EscalationService) *GfEscalationHandler {
+	return &GfEscalationHandler{
+		escalationService: escalationService,
+	}
+}
+
+// ProcessEscalationEvent runs one escalation pass at the scheduled time of the event
+func (eh *GfEscalationHandler) ProcessEscalationEvent(ctx context.Context, event events.CloudWatchEvent) error {
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	summary, err := eh.escalationService.EscalateAlerts(ctx, now)
+	if summary != nil {
+		fmt.Printf("Escalated alerts: %v, failed: %d\n", summary.Steps, summary.Failed)
+	}
+	return err
+}
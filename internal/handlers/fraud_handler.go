@@ -100,23 +100,38 @@ func (fh *GfFraudHandler) ProcessFraudEvent(ctx context.Context, event events.Dy
 		var fraudIDs []string
 		var fraudEmails []string
 		var fraudAmounts []float64
+		var fraudScores []int
+		listMatches := make(map[string]*models.ListMatch)
 
 		for _, txn := range fraudulentTransactions {
 			fraudIDs = append(fraudIDs, txn.TransactionID)
 			fraudEmails = append(fraudEmails, txn.Email)
 			fraudAmounts = append(fraudAmounts, txn.TransactionAmount)
+			fraudScores = append(fraudScores, txn.RiskScore)
+			if txn.ListMatch != nil {
+				listMatches[txn.TransactionID] = txn.ListMatch
+			}
 		}
 
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudDetected, true)
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudulentTransactionIDs, fraudIDs)
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudulentEmails, fraudEmails)
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudulentAmounts, fraudAmounts)
+		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudRiskScores, fraudScores)
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudCount, len(fraudulentTransactions))
+		if len(listMatches) > 0 {
+			observability.SafeAddMetadata(fraudSeg, observability.KeyFraudListMatches, listMatches)
+		}
 	} else {
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudDetected, false)
 		observability.SafeAddMetadata(fraudSeg, observability.KeyFraudCount, 0)
 	}
 
+	// Shadow challenger disagreement seen by this container. Challengers are compared on the CloudWatch metrics.
+	if challengerStats := fh.FraudService.ChallengerStats(); len(challengerStats) > 0 {
+		observability.SafeAddMetadata(fraudSeg, observability.KeyChallengerStats, challengerStats)
+	}
+
 	if err != nil {
 		errorResults = append(errorResults, err)
 		observability.SafeAddError(fraudSeg, err)
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type RelayHandler interface {
+	ProcessOutboxEvent(ctx context.Context, event events.DynamoDBEvent) (*models.BatchResult, error)
+	ProcessSweepEvent(ctx context.Context, event events.CloudWatchEvent) error
+}
+
+type GfRelayHandler struct {
+	relayService services.RelayService
+}
+
+func NewRelayHandler(relayService services.RelayService) *GfRelayHandler {
+	return &GfRelayHandler{
+		relayService: relayService,
+	}
+}
+
+// ProcessOutboxEvent sends the notifications written to the outbox in a batch of stream records and reports the
+// ones that failed, so only they are retried. Notifications the stream gives up on are left to the sweep.
+func (rh *GfRelayHandler) ProcessOutboxEvent(ctx context.Context, event events.DynamoDBEvent) (*models.BatchResult, error) {
+	var outboxIDs []string
+	ridsByOutboxID := make(map[string]string)
+	for _, record := range event.Records {
+		if record.EventName != "INSERT" {
+			continue
+		}
+		key, ok := record.Change.Keys[config.OutboxDBConfig.Keys.PartitionKey]
+		if !ok || key.DataType() != events.DataTypeString {
+			fmt.Printf("Skipping outbox record %s without a %s\n", record.EventID, config.OutboxDBConfig.Keys.PartitionKey)
+			continue
+		}
+		outboxID := key.String()
+		outboxIDs = append(outboxIDs, outboxID)
+		ridsByOutboxID[outboxID] = record.Change.SequenceNumber
+	}
+	if len(outboxIDs) == 0 {
+		return &models.BatchResult{}, nil
+	}
+
+	failed, err := rh.relayService.RelayNotifications(ctx, outboxIDs)
+	if err != nil {
+		// Returning the error would make Lambda retry the whole batch, so the failures are only reported per record
+		fmt.Printf("Error relaying outbox notifications: %s\n", err)
+	}
+
+	var failedRIDs []string
+	for _, outboxID := range failed {
+		failedRIDs = append(failedRIDs, ridsByOutboxID[outboxID])
+	}
+
+	batchResultInput := &middleware.GetBatchResultInput{
+		FailedRIDs: failedRIDs,
+	}
+
+	return middleware.GetBatchResult(batchResultInput)
+}
+
+// ProcessSweepEvent sends the notifications still pending at the scheduled time of the event
+func (rh *GfRelayHandler) ProcessSweepEvent(ctx context.Context, event events.CloudWatchEvent) error {
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	summary, err := rh.relayService.RelayPendingNotifications(ctx, now)
+	if summary != nil {
+		fmt.Printf("Swept outbox, sent %d notifications, undeliverable: %d, failed: %d\n", summary.Sent, summary.Undeliverable, summary.Failed)
+	}
+	return err
+}
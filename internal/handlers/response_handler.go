@@ -3,36 +3,127 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
 	"github.com/aws/aws-lambda-go/events"
 )
 
+// maxSecurityEventPayload caps how much of a rejected message is kept with its security event
+const maxSecurityEventPayload = 4096
+
 type ResponseHandler interface {
-	ProcessResponseEvent(ctx context.Context, event events.SQSEvent) error
+	ProcessResponseEvent(ctx context.Context, event events.SQSEvent) (*models.BatchResult, error)
+}
+
+// SignatureValidator checks a Twilio webhook signature, as twilio-go's client.RequestValidator does.
+type SignatureValidator interface {
+	Validate(url string, params map[string]string, expectedSignature string) bool
 }
 
 type GfResponseHandler struct {
 	responseService services.ResponseService
+	validator       SignatureValidator
+	securityEvents  db.SecurityEventRepository
 }
 
-func NewResponseHandler(responseService services.ResponseService) *GfResponseHandler {
+func NewResponseHandler(responseService services.ResponseService, validator SignatureValidator, securityEvents db.SecurityEventRepository) *GfResponseHandler {
 	return &GfResponseHandler{
 		responseService: responseService,
+		validator:       validator,
+		securityEvents:  securityEvents,
 	}
 }
 
-func (rh *GfResponseHandler) ProcessResponseEvent(ctx context.Context, event events.SQSEvent) error {
+// ProcessResponseEvent handles the SMS replies in a batch and reports the ones that failed, so only they are
+// redelivered. Messages that are not a webhook signed by Twilio are dropped and recorded as security events, since
+// retrying them cannot make them valid.
+func (rh *GfResponseHandler) ProcessResponseEvent(ctx context.Context, event events.SQSEvent) (*models.BatchResult, error) {
 	var messages []models.TwilioMessage
+	messageIdsByMessageSid := make(map[string][]string)
 	for _, record := range event.Records {
-		result, err := models.UnmarshalResponseSQS(record.Body)
-		if err != nil {
-			fmt.Printf("error Unmarshalling: %s", err)
-			return err
+		message, reason := rh.verify(record.Body)
+		if message == nil {
+			rh.reject(ctx, record, reason)
+			continue
+		}
+		messages = append(messages, *message)
+		messageIdsByMessageSid[message.MessageSid] = append(messageIdsByMessageSid[message.MessageSid], record.MessageId)
+	}
+	if len(messages) == 0 {
+		return &models.BatchResult{}, nil
+	}
+
+	failedMessages, err := rh.responseService.RsUpdateTransaction(ctx, messages)
+
+	// A redelivered copy in the same batch shares its MessageSid, so both copies are retried and the idempotency
+	// store sorts out which one was handled
+	var failedRIDs []string
+	seen := make(map[string]bool)
+	for _, msg := range failedMessages {
+		if seen[msg.MessageSid] {
+			continue
 		}
-		messages = append(messages, *result)
+		seen[msg.MessageSid] = true
+		failedRIDs = append(failedRIDs, messageIdsByMessageSid[msg.MessageSid]...)
+	}
+
+	if err != nil {
+		// Returning the error would make Lambda retry the whole batch, so the failures are only reported per message
+		fmt.Printf("Error processing responses: %s\n", err)
+	}
+
+	batchResultInput := &middleware.GetBatchResultInput{
+		FailedRIDs: failedRIDs,
+	}
+
+	return middleware.GetBatchResult(batchResultInput)
+}
+
+// verify returns the message in a queued webhook, or nil and why it cannot be trusted
+func (rh *GfResponseHandler) verify(body string) (*models.TwilioMessage, string) {
+	webhook, err := models.UnmarshalResponseSQS(body)
+	if err != nil {
+		return nil, fmt.Sprintf("malformed webhook: %s", err)
+	}
+	if webhook.Signature == "" {
+		return nil, "missing signature"
+	}
+	if !rh.validator.Validate(webhook.URL, webhook.Params, webhook.Signature) {
+		return nil, "invalid signature"
+	}
+
+	message, err := webhook.Message()
+	if err != nil {
+		return nil, fmt.Sprintf("malformed webhook parameters: %s", err)
+	}
+	return message, ""
+}
+
+// reject records a security event for an untrusted message. The message is dropped even if recording fails.
+func (rh *GfResponseHandler) reject(ctx context.Context, record events.SQSMessage, reason string) {
+	now := time.Now()
+	securityEvent := &models.SecurityEvent{
+		EventType:  models.SecurityEventWebhookRejected,
+		EventID:    fmt.Sprintf("%d#%s", now.UnixNano(), record.MessageId),
+		Reason:     reason,
+		Payload:    record.Body,
+		OccurredAt: now.Unix(),
+	}
+	if len(securityEvent.Payload) > maxSecurityEventPayload {
+		securityEvent.Payload = securityEvent.Payload[:maxSecurityEventPayload]
+	}
+	if webhook, err := models.UnmarshalResponseSQS(record.Body); err == nil {
+		securityEvent.URL = webhook.URL
+		securityEvent.Source = webhook.Params["From"]
+		securityEvent.MessageSid = webhook.Params["MessageSid"]
+	}
+
+	fmt.Printf("Rejected response message %s from %q: %s\n", record.MessageId, securityEvent.Source, reason)
+	if err := rh.securityEvents.RecordSecurityEvent(ctx, securityEvent); err != nil {
+		fmt.Printf("Error recording security event for message %s: %s\n", record.MessageId, err)
 	}
-	_, err := rh.responseService.RsUpdateTransaction(ctx, messages)
-	return err
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ResponseRetryHandler replays SMS replies from the response dead letter queue. They are verified again, since
+// anything on the queue is no more trusted than on the response queue.
+type ResponseRetryHandler struct {
+	responseHandler *GfResponseHandler
+}
+
+func NewResponseRetryHandler(responseService services.ResponseService, validator SignatureValidator, securityEvents db.SecurityEventRepository) *ResponseRetryHandler {
+	return &ResponseRetryHandler{
+		responseHandler: NewResponseHandler(responseService, validator, securityEvents),
+	}
+}
+
+func (rrh *ResponseRetryHandler) ProcessDLQResponseEvent(ctx context.Context, event events.SQSEvent) (*models.BatchResult, error) {
+	return rrh.responseHandler.ProcessResponseEvent(ctx, event)
+}
@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Notification channel names
+const (
+	ChannelSMS     = "sms"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+var (
+	// ErrNoAddress is returned when a recipient has no address on the channel, such as no email for email.
+	ErrNoAddress = errors.New("recipient has no address on this channel")
+	// ErrChannelUnavailable is returned when a channel is not configured.
+	ErrChannelUnavailable = errors.New("notification channel is not configured")
+)
+
+// Recipient is the customer a message is for. Each channel uses the address it needs.
+type Recipient struct {
+	AccountID   string
+	PhoneNumber string
+	Email       string
+}
+
+// RecipientOf addresses a message to the customer behind a transaction
+func RecipientOf(transaction models.Transaction) Recipient {
+	return Recipient{
+		AccountID:   transaction.AccountID,
+		PhoneNumber: transaction.PhoneNumber,
+		Email:       transaction.Email,
+	}
+}
+
+// Message is a rendered notification. Channels without subjects send only the body.
+type Message struct {
+	Type          string
+	Subject       string
+	Body          string
+	Locale        string
+	TransactionID string
+}
+
+// NotificationChannel delivers messages to customers over one medium.
+type NotificationChannel interface {
+	Name() string
+	Send(ctx context.Context, recipient Recipient, message Message) error
+}
+
+// Channels are the notification channels in use, by name.
+type Channels map[string]NotificationChannel
+
+// Send delivers a message over the named channel
+func (c Channels) Send(ctx context.Context, name string, recipient Recipient, message Message) error {
+	channel, ok := c[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelUnavailable, name)
+	}
+	return channel.Send(ctx, recipient, message)
+}
+
+// CheckRoute returns an error unless a route names at least one of the channels, since otherwise nothing routed
+// on it could be sent
+func (c Channels) CheckRoute(route []string) error {
+	for _, name := range route {
+		if _, ok := c[name]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the alert route %v has none of the configured channels", ErrChannelUnavailable, route)
+}
@@ -0,0 +1,76 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// MaxSQSDelay is the longest SQS delays a message. Messages due later are delayed again each time they arrive
+// until they are due.
+const MaxSQSDelay = 15 * time.Minute
+
+// DeferredMessage is a rendered message held back until DeliverAt. Replies are sent by text only, like they are
+// when sent right away, and everything else is tried on each channel of Route. TransactionIDs are set on first
+// alerts and digests, whose channel is recorded on each of the transactions once it is delivered.
+type DeferredMessage struct {
+	Route          []string  `json:"route,omitempty"`
+	Reply          bool      `json:"reply,omitempty"`
+	TransactionIDs []string  `json:"transactionIds,omitempty"`
+	Recipient      Recipient `json:"recipient"`
+	Message        Message   `json:"message"`
+	DeliverAt      int64     `json:"deliverAt"`
+}
+
+// IsDue reports whether a deferred message may be sent at now
+func (m DeferredMessage) IsDue(now time.Time) bool {
+	return now.Unix() >= m.DeliverAt
+}
+
+// SQSSender sends a message to a queue, as *sqs.Client does
+type SQSSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SQSDeferredQueue holds deferred messages on an SQS queue using message delays
+type SQSDeferredQueue struct {
+	Client   SQSSender
+	QueueURL string
+}
+
+func NewSQSDeferredQueue(client SQSSender, queueURL string) *SQSDeferredQueue {
+	return &SQSDeferredQueue{
+		Client:   client,
+		QueueURL: queueURL,
+	}
+}
+
+// Defer queues a message to arrive when it is due, or after MaxSQSDelay if that is sooner
+func (q *SQSDeferredQueue) Defer(ctx context.Context, message DeferredMessage, now time.Time) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deferred message: %w", err)
+	}
+
+	delay := time.Unix(message.DeliverAt, 0).Sub(now)
+	if delay > MaxSQSDelay {
+		delay = MaxSQSDelay
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	_, err = q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:     aws.String(q.QueueURL),
+		MessageBody:  aws.String(string(body)),
+		DelaySeconds: int32(delay.Seconds()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue %s for account %s: %w", message.Message.Type, message.Recipient.AccountID, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package messaging
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// Built in channel providers
+const (
+	ProviderTwilio  = "twilio"
+	ProviderSNS     = "sns"
+	ProviderWebhook = "webhook"
+)
+
+// ChannelEnvironment is what providers may need to build their channel
+type ChannelEnvironment struct {
+	SNSClient *sns.Client
+	TopicArn  string
+	OptOuts   OptOutChecker
+}
+
+// ChannelProvider builds a notification channel, reading its settings from config.
+type ChannelProvider func(env ChannelEnvironment) (NotificationChannel, error)
+
+// ChannelRegistry holds the channel providers that can be picked by name.
+type ChannelRegistry struct {
+	providers map[string]ChannelProvider
+}
+
+// NewChannelRegistry returns a registry of the built in providers
+func NewChannelRegistry() *ChannelRegistry {
+	registry := &ChannelRegistry{providers: make(map[string]ChannelProvider)}
+	registry.Register(ProviderTwilio, func(env ChannelEnvironment) (NotificationChannel, error) {
+		return NewTwilioSMSChannel(config.SNSMessengerConfig.TwilioUsername, config.SNSMessengerConfig.TwilioPassword, config.ChannelConfig.TwilioFrom, env.OptOuts), nil
+	})
+	registry.Register(ProviderSNS, func(env ChannelEnvironment) (NotificationChannel, error) {
+		return NewSNSEmailChannel(env.SNSClient, env.TopicArn), nil
+	})
+	registry.Register(ProviderWebhook, func(env ChannelEnvironment) (NotificationChannel, error) {
+		return NewWebhookChannel(config.ChannelConfig.WebhookURL, config.ChannelConfig.WebhookSecret, &http.Client{Timeout: config.ChannelConfig.WebhookTimeout})
+	})
+	return registry
+}
+
+// Register adds a provider, replacing any with the same name
+func (r *ChannelRegistry) Register(name string, provider ChannelProvider) {
+	r.providers[name] = provider
+}
+
+// Providers returns the names of every registered provider
+func (r *ChannelRegistry) Providers() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates the channels of the named providers. Each channel may only have one provider.
+func (r *ChannelRegistry) Build(env ChannelEnvironment, providers []string) (Channels, error) {
+	channels := make(Channels)
+	for _, name := range providers {
+		provider, ok := r.providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown notification channel provider %q, expected one of %v", name, r.Providers())
+		}
+		channel, err := provider(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s channel: %w", name, err)
+		}
+		if _, ok := channels[channel.Name()]; ok {
+			return nil, fmt.Errorf("more than one provider configured for the %s channel", channel.Name())
+		}
+		channels[channel.Name()] = channel
+	}
+	return channels, nil
+}
+
+// ConfiguredChannels builds the channels of the providers in config.ChannelConfig.Providers. It fails when the
+// default alert route in config.RoutingConfig has none of them, so a misconfigured function does not start.
+func ConfiguredChannels(env ChannelEnvironment) (Channels, error) {
+	channels, err := NewChannelRegistry().Build(env, config.ChannelConfig.Providers)
+	if err != nil {
+		return nil, err
+	}
+	if err := channels.CheckRoute(config.RoutingConfig.Channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
@@ -3,79 +3,93 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
-	"github.com/twilio/twilio-go"
-	api "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
-type SNSMessenger interface {
-	SendEmailAlert(transaction models.Transaction) (*sns.PublishOutput, error)
-	SendTextAlert(transaction models.Transaction) error
-	SendTextUpdate(number string, body string) error
+// ErrSubscriptionNotReady is returned when an email address cannot be sent to over SNS yet, because its owner has
+// not confirmed the subscription or the subscription does not filter on their account.
+var ErrSubscriptionNotReady = errors.New("email subscription is not ready to receive messages")
+
+// SNSEmailClient is the part of the SNS API the email channel uses
+type SNSEmailClient interface {
+	Subscribe(ctx context.Context, params *sns.SubscribeInput, optFns ...func(*sns.Options)) (*sns.SubscribeOutput, error)
+	GetSubscriptionAttributes(ctx context.Context, params *sns.GetSubscriptionAttributesInput, optFns ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error)
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
 }
 
-type GfSNSMessenger struct {
-	Client         *sns.Client
-	TopicName      string
-	TopicArn       string
-	TwilioUsername string
-	TwilioPassword string
+// SNSEmailChannel emails customers by subscribing their address to the alert topic and publishing to it. Every
+// customer shares the topic, so each subscription filters on the AccountID message attribute and only receives
+// its own account's messages. A message is only published once the recipient's subscription is confirmed and
+// filtered, since SNS accepts messages no subscriber receives.
+type SNSEmailChannel struct {
+	Client   SNSEmailClient
+	TopicArn string
 }
 
-func NewGfSNSMessenger(snsClient *sns.Client, topicName string, topicArn string, twilioUsernmae string, twilioPassword string) *GfSNSMessenger {
-	return &GfSNSMessenger{
-		Client:         snsClient,
-		TopicName:      topicName,
-		TopicArn:       topicArn,
-		TwilioUsername: twilioUsernmae,
-		TwilioPassword: twilioPassword,
+func NewSNSEmailChannel(snsClient SNSEmailClient, topicArn string) *SNSEmailChannel {
+	return &SNSEmailChannel{
+		Client:   snsClient,
+		TopicArn: topicArn,
 	}
 }
 
-func CreateTopic(client *sns.Client, topicName string) (string, error) {
-	input := &sns.CreateTopicInput{
-		Name: aws.String(topicName),
+func (channel *SNSEmailChannel) Name() string {
+	return ChannelEmail
+}
+
+func (channel *SNSEmailChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if recipient.Email == "" {
+		return ErrNoAddress
+	}
+	if recipient.AccountID == "" {
+		return errors.New("an email needs an AccountID to filter its subscription on")
 	}
 
-	result, err := client.CreateTopic(context.TODO(), input)
+	subscription, err := channel.SubscribeToSNSTopic("email", recipient.Email, recipient.AccountID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create SNS topic: %v", err)
+		return fmt.Errorf("Failed to subscribe %s SNS topic: %s\n", recipient.Email, err)
+	}
+	if err := channel.checkSubscription(ctx, aws.ToString(subscription.SubscriptionArn), recipient.AccountID); err != nil {
+		return err
 	}
 
-	return *result.TopicArn, nil
+	_, err = channel.PublishEmailMessage(recipient, message)
+	if err != nil {
+		return fmt.Errorf("Failed to publish transaction with id %s SNS topic: %s\n", message.TransactionID, err)
+	}
+
+	return nil
 }
 
-func (messenger *GfSNSMessenger) SendEmailAlert(transaction models.Transaction) (*sns.PublishOutput, error) {
-	_, err := messenger.SubscribeToSNSTopic("email", transaction.Email, transaction.AccountID)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to subscribe %s SNS topic: %s\n", transaction.Email, err)
+func CreateTopic(client *sns.Client, topicName string) (string, error) {
+	input := &sns.CreateTopicInput{
+		Name: aws.String(topicName),
 	}
 
-	publishOutput, err := messenger.PublishEmailMessage(transaction)
+	result, err := client.CreateTopic(context.TODO(), input)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to publish transaction with id %s SNS topic: %s\n", transaction.TransactionID, err)
+		return "", fmt.Errorf("failed to create SNS topic: %v", err)
 	}
 
-	return publishOutput, nil
+	return *result.TopicArn, nil
 }
 
-func (messenger *GfSNSMessenger) PublishEmailMessage(transaction models.Transaction) (*sns.PublishOutput, error) {
-	subject, message := transaction.GetFraudEmailContent()
-	messageAttributes := GetMessageAttributes(transaction)
+func (channel *SNSEmailChannel) PublishEmailMessage(recipient Recipient, message Message) (*sns.PublishOutput, error) {
+	messageAttributes := GetMessageAttributes(recipient)
 
 	input := &sns.PublishInput{
-		Message:           aws.String(message),
-		Subject:           aws.String(subject),
-		TopicArn:          aws.String(messenger.TopicArn),
+		Message:           aws.String(message.Body),
+		Subject:           aws.String(message.Subject),
+		TopicArn:          aws.String(channel.TopicArn),
 		MessageAttributes: messageAttributes,
 	}
 
-	publishOutput, err := messenger.Client.Publish(context.TODO(), input)
+	publishOutput, err := channel.Client.Publish(context.TODO(), input)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to send SNS email: %v", err)
 	}
@@ -83,43 +97,56 @@ func (messenger *GfSNSMessenger) PublishEmailMessage(transaction models.Transact
 	return publishOutput, nil
 }
 
-func (messenger *GfSNSMessenger) SubscribeToSNSTopic(protocol string, endpoint string, accountId string) (*sns.SubscribeOutput, error) {
-	// filterPolicy, err := GetFilterPolicy(accountId)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("Failed to get filter policy: %s\n", err)
-	// }
+// SubscribeToSNSTopic subscribes an endpoint to the topic with a filter policy on the account, so it is only sent
+// the messages published for that account
+func (channel *SNSEmailChannel) SubscribeToSNSTopic(protocol string, endpoint string, accountId string) (*sns.SubscribeOutput, error) {
+	filterPolicy, err := GetFilterPolicy(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get filter policy: %s\n", err)
+	}
 
 	input := &sns.SubscribeInput{
-		TopicArn: aws.String(messenger.TopicArn),
+		TopicArn: aws.String(channel.TopicArn),
 		Protocol: aws.String(protocol), // "email", "sms", "lambda", etc.
 		Endpoint: aws.String(endpoint), // email address or phone number
-		// Attributes: map[string]string{
-		// 	"FilterPolicy": *filterPolicy,
-		// },
+		Attributes: map[string]string{
+			"FilterPolicy": *filterPolicy,
+		},
 		ReturnSubscriptionArn: true,
 	}
 
-	subscribeOutput, err := messenger.Client.Subscribe(context.TODO(), input)
+	subscribeOutput, err := channel.Client.Subscribe(context.TODO(), input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to SNS topic: %v", err)
 	}
 
-	// _, err = messenger.Client.SetSubscriptionAttributes(context.TODO(), &sns.SetSubscriptionAttributesInput{
-	// 	SubscriptionArn: aws.String(*subscribeOutput.SubscriptionArn),
-	// 	AttributeName:   aws.String("FilterPolicy"),
-	// 	AttributeValue:  aws.String(*filterPolicy),
-	// })
+	return subscribeOutput, nil
+}
 
+// checkSubscription returns ErrSubscriptionNotReady unless a subscription is confirmed and only receives the
+// account's messages
+func (channel *SNSEmailChannel) checkSubscription(ctx context.Context, subscriptionArn string, accountID string) error {
+	output, err := channel.Client.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: aws.String(subscriptionArn),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to set subscription attributes: %s", err)
+		return fmt.Errorf("failed to get SNS subscription attributes: %w", err)
 	}
 
-	return subscribeOutput, nil
+	if output.Attributes["PendingConfirmation"] != "false" {
+		return fmt.Errorf("%w: account %s has not confirmed it", ErrSubscriptionNotReady, accountID)
+	}
+	var filterPolicy map[string][]string
+	if err := json.Unmarshal([]byte(output.Attributes["FilterPolicy"]), &filterPolicy); err != nil ||
+		len(filterPolicy) != 1 || len(filterPolicy["AccountID"]) != 1 || filterPolicy["AccountID"][0] != accountID {
+		return fmt.Errorf("%w: it does not filter on account %s", ErrSubscriptionNotReady, accountID)
+	}
+	return nil
 }
 
-func GetMessageAttributes(transaction models.Transaction) map[string]types.MessageAttributeValue {
+func GetMessageAttributes(recipient Recipient) map[string]types.MessageAttributeValue {
 	return map[string]types.MessageAttributeValue{
-		"AccountID": NewMessageAttributeValue("String", transaction.AccountID),
+		"AccountID": NewMessageAttributeValue("String", recipient.AccountID),
 	}
 }
 
@@ -144,48 +171,3 @@ func NewMessageAttributeValue(dataType string, stringValue string) types.Message
 		StringValue: aws.String(stringValue),
 	}
 }
-
-func (messenger *GfSNSMessenger) SendTextAlert(transaction models.Transaction) error {
-
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: messenger.TwilioUsername,
-		Password: messenger.TwilioPassword,
-	})
-
-	params := &api.CreateMessageParams{}
-	_, body := transaction.GetFraudEmailContent()
-	params.SetBody(body)
-
-	//ASSIGNED TWILIO PHONE NUMBER
-	params.SetFrom("+18333981458")
-	number := transaction.PhoneNumber
-	params.SetTo(number)
-
-	_, err := client.Api.CreateMessage(params)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	return nil
-}
-
-func (messenger *GfSNSMessenger) SendTextUpdate(number string, body string) error {
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: messenger.TwilioUsername,
-		Password: messenger.TwilioPassword,
-	})
-
-	params := &api.CreateMessageParams{}
-	params.SetBody(body)
-
-	//ASSIGNED TWILIO PHONE NUMBER
-	params.SetFrom("+18333981458")
-	params.SetTo(number)
-
-	_, err := client.Api.CreateMessage(params)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	return nil
-}
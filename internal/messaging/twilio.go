@@ -0,0 +1,78 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/twilio/twilio-go"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// ErrOptedOut is returned instead of texting a phone number that has opted out of text messages.
+var ErrOptedOut = errors.New("phone number has opted out of text messages")
+
+// OptOutChecker looks up whether a phone number has opted out of text messages.
+type OptOutChecker interface {
+	IsOptedOut(ctx context.Context, phoneNumber string) (bool, error)
+}
+
+// TwilioSMSChannel texts customers through Twilio from the assigned number, skipping numbers that opted out.
+type TwilioSMSChannel struct {
+	Username string
+	Password string
+	From     string
+	OptOuts  OptOutChecker
+}
+
+func NewTwilioSMSChannel(username string, password string, from string, optOuts OptOutChecker) *TwilioSMSChannel {
+	return &TwilioSMSChannel{
+		Username: username,
+		Password: password,
+		From:     from,
+		OptOuts:  optOuts,
+	}
+}
+
+func (channel *TwilioSMSChannel) Name() string {
+	return ChannelSMS
+}
+
+func (channel *TwilioSMSChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if recipient.PhoneNumber == "" {
+		return ErrNoAddress
+	}
+	if err := channel.checkOptOut(ctx, recipient.PhoneNumber); err != nil {
+		return err
+	}
+
+	client := twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: channel.Username,
+		Password: channel.Password,
+	})
+
+	params := &api.CreateMessageParams{}
+	params.SetBody(message.Body)
+	params.SetFrom(channel.From)
+	params.SetTo(recipient.PhoneNumber)
+
+	_, err := client.Api.CreateMessage(params)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	return nil
+}
+
+// checkOptOut returns ErrOptedOut if the number is in the opt-out registry. A failed lookup is returned too,
+// since texting a number that may have opted out is not allowed.
+func (channel *TwilioSMSChannel) checkOptOut(ctx context.Context, number string) error {
+	optedOut, err := channel.OptOuts.IsOptedOut(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to check SMS opt-out: %w", err)
+	}
+	if optedOut {
+		return ErrOptedOut
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with the webhook secret
+const WebhookSignatureHeader = "X-GreenFlag-Signature"
+
+// WebhookPayload is the JSON body posted to a notification webhook
+type WebhookPayload struct {
+	Type          string `json:"type"`
+	AccountID     string `json:"accountId"`
+	PhoneNumber   string `json:"phoneNumber,omitempty"`
+	Email         string `json:"email,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	Locale        string `json:"locale,omitempty"`
+	Subject       string `json:"subject,omitempty"`
+	Body          string `json:"body"`
+	SentAt        int64  `json:"sentAt"`
+}
+
+// WebhookChannel posts messages to an HTTPS endpoint, such as a push notification gateway, which delivers them
+// to the customer.
+type WebhookChannel struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookChannel checks that the endpoint is HTTPS. The body of each request is signed when secret is set.
+func NewWebhookChannel(endpoint string, secret string, client *http.Client) (*WebhookChannel, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("webhook URL %q must be an https URL", endpoint)
+	}
+
+	return &WebhookChannel{
+		URL:    endpoint,
+		Secret: secret,
+		Client: client,
+	}, nil
+}
+
+func (channel *WebhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+func (channel *WebhookChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if recipient.AccountID == "" {
+		return ErrNoAddress
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Type:          message.Type,
+		AccountID:     recipient.AccountID,
+		PhoneNumber:   recipient.PhoneNumber,
+		Email:         recipient.Email,
+		TransactionID: message.TransactionID,
+		Locale:        message.Locale,
+		Subject:       message.Subject,
+		Body:          message.Body,
+		SentAt:        time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if channel.Secret != "" {
+		request.Header.Set(WebhookSignatureHeader, SignWebhook(channel.Secret, body))
+	}
+
+	response, err := channel.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to call notification webhook: %w", err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("notification webhook returned %s", response.Status)
+	}
+	return nil
+}
+
+// SignWebhook returns the signature of a webhook body, for receivers to check it came from us
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
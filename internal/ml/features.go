@@ -0,0 +1,76 @@
+package ml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// Numeric features read straight from, or derived from, a transaction. These match the columns of
+// bank_transactions_data.csv so models trained on it can be exported without renaming.
+var numericFeatures = map[string]func(txn models.Transaction) float64{
+	"TransactionAmount":   func(txn models.Transaction) float64 { return txn.TransactionAmount },
+	"TransactionDuration": func(txn models.Transaction) float64 { return float64(txn.TransactionDuration) },
+	"LoginAttempts":       func(txn models.Transaction) float64 { return float64(txn.LoginAttempts) },
+	"AccountBalance":      func(txn models.Transaction) float64 { return txn.AccountBalance },
+	"CustomerAge":         func(txn models.Transaction) float64 { return float64(txn.CustomerAge) },
+	"AmountToBalanceRatio": func(txn models.Transaction) float64 {
+		if txn.AccountBalance <= 0 {
+			return 0
+		}
+		return txn.TransactionAmount / txn.AccountBalance
+	},
+	"TransactionHour": func(txn models.Transaction) float64 {
+		return float64(txn.GetTransactionTime().Hour())
+	},
+	"HoursSincePreviousTransaction": func(txn models.Transaction) float64 {
+		previous, err := time.Parse(time.RFC3339, txn.PreviousTransactionDate)
+		transactionTime := txn.GetTransactionTime()
+		if err != nil || transactionTime.IsZero() {
+			return 0
+		}
+		return max(0, transactionTime.Sub(previous).Hours())
+	},
+}
+
+// Categorical fields that can be one-hot encoded with a "Field=Value" feature name
+var categoricalFeatures = map[string]func(txn models.Transaction) string{
+	"TransactionType":    func(txn models.Transaction) string { return txn.TransactionType },
+	"Channel":            func(txn models.Transaction) string { return txn.Channel },
+	"CustomerOccupation": func(txn models.Transaction) string { return txn.CustomerOccupation },
+	"Location":           func(txn models.Transaction) string { return txn.Location },
+	"MerchantID":         func(txn models.Transaction) string { return txn.MerchantID },
+}
+
+// ValidateFeature checks a feature name can be extracted from a transaction.
+func ValidateFeature(name string) error {
+	if _, ok := numericFeatures[name]; ok {
+		return nil
+	}
+	if field, _, ok := strings.Cut(name, "="); ok {
+		if _, ok := categoricalFeatures[field]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown model feature %q", name)
+}
+
+// ExtractFeatures builds the feature vector for a transaction in the order of the given names.
+// One-hot features are 1 when the field matches the value ignoring case and 0 otherwise.
+func ExtractFeatures(txn models.Transaction, names []string) []float64 {
+	features := make([]float64, len(names))
+	for i, name := range names {
+		if extract, ok := numericFeatures[name]; ok {
+			features[i] = extract(txn)
+			continue
+		}
+
+		field, value, _ := strings.Cut(name, "=")
+		if extract, ok := categoricalFeatures[field]; ok && strings.EqualFold(extract(txn), value) {
+			features[i] = 1
+		}
+	}
+	return features
+}
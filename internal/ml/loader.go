@@ -0,0 +1,67 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const s3Scheme = "s3://"
+
+// ObjectGetter is the part of the S3 client used to download models, so any S3-compatible store works.
+type ObjectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// NewS3ObjectGetter creates an S3 client for downloading models. When endpoint is set requests go to that
+// S3-compatible store using path style addressing instead of AWS.
+func NewS3ObjectGetter(cfg aws.Config, endpoint string) ObjectGetter {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+}
+
+// LoadModel reads a model from a local file or from an s3://bucket/key path using the given getter.
+func LoadModel(ctx context.Context, path string, getter ObjectGetter) (Model, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(path, s3Scheme) {
+		data, err = readS3Object(ctx, path, getter)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model %s: %w", path, err)
+	}
+
+	return ParseModel(data)
+}
+
+func readS3Object(ctx context.Context, path string, getter ObjectGetter) ([]byte, error) {
+	if getter == nil {
+		return nil, fmt.Errorf("no S3 client configured")
+	}
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(path, s3Scheme), "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("expected %sbucket/key", s3Scheme)
+	}
+
+	output, err := getter.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
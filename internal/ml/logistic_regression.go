@@ -0,0 +1,47 @@
+package ml
+
+import "fmt"
+
+// LogisticRegression scores sigmoid(intercept + coefficients · x). When Means and Scales are set the features
+// are standardized the same way they were during training before being weighted.
+type LogisticRegression struct {
+	Features     []string  `json:"-"`
+	Intercept    float64   `json:"intercept"`
+	Coefficients []float64 `json:"coefficients"`
+	Means        []float64 `json:"means"`
+	Scales       []float64 `json:"scales"`
+}
+
+func (m *LogisticRegression) FeatureNames() []string {
+	return m.Features
+}
+
+func (m *LogisticRegression) Predict(features []float64) float64 {
+	logit := m.Intercept
+	for i, coefficient := range m.Coefficients {
+		value := features[i]
+		if len(m.Means) > 0 {
+			value = (value - m.Means[i]) / m.Scales[i]
+		}
+		logit += coefficient * value
+	}
+	return sigmoid(logit)
+}
+
+func (m *LogisticRegression) validate(featureCount int) error {
+	if len(m.Coefficients) != featureCount {
+		return fmt.Errorf("expected %d coefficients, got %d", featureCount, len(m.Coefficients))
+	}
+	if len(m.Means) == 0 && len(m.Scales) == 0 {
+		return nil
+	}
+	if len(m.Means) != featureCount || len(m.Scales) != featureCount {
+		return fmt.Errorf("expected %d means and scales, got %d and %d", featureCount, len(m.Means), len(m.Scales))
+	}
+	for i, scale := range m.Scales {
+		if scale == 0 {
+			return fmt.Errorf("scale for feature %s is zero", m.Features[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Model types supported in an exported model file
+const (
+	TypeLogisticRegression = "logistic_regression"
+	TypeTreeEnsemble       = "tree_ensemble"
+)
+
+// Model is a trained fraud model that turns a feature vector into a probability of fraud between 0 and 1.
+type Model interface {
+	Predict(features []float64) float64
+	FeatureNames() []string
+}
+
+// modelFile is the JSON envelope written by the training pipeline. The type decides which of the
+// model specific sections is read.
+type modelFile struct {
+	Type               string              `json:"type"`
+	Version            string              `json:"version"`
+	Features           []string            `json:"features"`
+	LogisticRegression *LogisticRegression `json:"logistic_regression"`
+	TreeEnsemble       *TreeEnsemble       `json:"tree_ensemble"`
+}
+
+// ParseModel decodes an exported model and checks it is consistent with its feature list.
+func ParseModel(data []byte) (Model, error) {
+	var file modelFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode model: %w", err)
+	}
+	if len(file.Features) == 0 {
+		return nil, fmt.Errorf("model has no features")
+	}
+	for _, feature := range file.Features {
+		if err := ValidateFeature(feature); err != nil {
+			return nil, err
+		}
+	}
+
+	var model interface {
+		Model
+		validate(featureCount int) error
+	}
+	switch file.Type {
+	case TypeLogisticRegression:
+		if file.LogisticRegression == nil {
+			return nil, fmt.Errorf("model of type %s is missing its %s section", file.Type, TypeLogisticRegression)
+		}
+		file.LogisticRegression.Features = file.Features
+		model = file.LogisticRegression
+	case TypeTreeEnsemble:
+		if file.TreeEnsemble == nil {
+			return nil, fmt.Errorf("model of type %s is missing its %s section", file.Type, TypeTreeEnsemble)
+		}
+		file.TreeEnsemble.Features = file.Features
+		model = file.TreeEnsemble
+	default:
+		return nil, fmt.Errorf("unsupported model type %q", file.Type)
+	}
+
+	if err := model.validate(len(file.Features)); err != nil {
+		return nil, fmt.Errorf("invalid %s model: %w", file.Type, err)
+	}
+	return model, nil
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,94 @@
+package ml
+
+import "fmt"
+
+// Objectives for a tree ensemble. With ObjectiveLogistic the summed leaf values are a logit, as exported by
+// gradient boosting libraries for binary classification. With ObjectiveProbability they are averaged, as in
+// a random forest whose leaves hold fraud probabilities.
+const (
+	ObjectiveLogistic    = "binary:logistic"
+	ObjectiveProbability = "probability"
+)
+
+// TreeEnsemble is a set of binary decision trees whose leaf values are combined into a probability.
+type TreeEnsemble struct {
+	Features  []string `json:"-"`
+	Objective string   `json:"objective"`
+	BaseScore float64  `json:"base_score"`
+	Trees     []Tree   `json:"trees"`
+}
+
+// Tree is stored as a flat list of nodes with the root first.
+type Tree struct {
+	Nodes []TreeNode `json:"nodes"`
+}
+
+// TreeNode either splits on Feature, going Left when the value is below Threshold and Right otherwise,
+// or is a leaf holding Value.
+type TreeNode struct {
+	Leaf      bool    `json:"leaf"`
+	Value     float64 `json:"value"`
+	Feature   int     `json:"feature"`
+	Threshold float64 `json:"threshold"`
+	Left      int     `json:"left"`
+	Right     int     `json:"right"`
+}
+
+func (m *TreeEnsemble) FeatureNames() []string {
+	return m.Features
+}
+
+func (m *TreeEnsemble) Predict(features []float64) float64 {
+	total := m.BaseScore
+	for _, tree := range m.Trees {
+		total += tree.predict(features)
+	}
+
+	if m.Objective == ObjectiveProbability {
+		return min(1, max(0, total/float64(len(m.Trees))))
+	}
+	return sigmoid(total)
+}
+
+func (t Tree) predict(features []float64) float64 {
+	node := t.Nodes[0]
+	for !node.Leaf {
+		if features[node.Feature] < node.Threshold {
+			node = t.Nodes[node.Left]
+		} else {
+			node = t.Nodes[node.Right]
+		}
+	}
+	return node.Value
+}
+
+func (m *TreeEnsemble) validate(featureCount int) error {
+	if m.Objective == "" {
+		m.Objective = ObjectiveLogistic
+	}
+	if m.Objective != ObjectiveLogistic && m.Objective != ObjectiveProbability {
+		return fmt.Errorf("unsupported objective %q", m.Objective)
+	}
+	if len(m.Trees) == 0 {
+		return fmt.Errorf("ensemble has no trees")
+	}
+
+	for t, tree := range m.Trees {
+		if len(tree.Nodes) == 0 {
+			return fmt.Errorf("tree %d has no nodes", t)
+		}
+		for n, node := range tree.Nodes {
+			if node.Leaf {
+				continue
+			}
+			if node.Feature < 0 || node.Feature >= featureCount {
+				return fmt.Errorf("tree %d node %d splits on unknown feature %d", t, n, node.Feature)
+			}
+			// Children always come after their parent, which rules out cycles
+			if node.Left <= n || node.Left >= len(tree.Nodes) || node.Right <= n || node.Right >= len(tree.Nodes) {
+				return fmt.Errorf("tree %d node %d has invalid children %d and %d", t, n, node.Left, node.Right)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+// ProfilePreferences is the kind and key of the account profile item holding the customer's preferences
+const ProfilePreferences = "PREFERENCES"
+
+// AccountPreferences is how a customer wants to be contacted. An empty Language uses the template catalog's default,
+// and empty Channels the default alert routing. Channels are tried in order. TimeZone is an IANA time zone for quiet
+// hours, inferred from where the card is used when empty.
+type AccountPreferences struct {
+	AccountID  string   `json:"accountId" dynamodbav:"AccountID"`
+	ProfileKey string   `json:"profileKey" dynamodbav:"ProfileKey"`
+	Kind       string   `json:"kind" dynamodbav:"Kind"`
+	Language   string   `json:"language,omitempty" dynamodbav:"Language,omitempty"`
+	Channels   []string `json:"channels,omitempty" dynamodbav:"Channels,omitempty"`
+	TimeZone   string   `json:"timeZone,omitempty" dynamodbav:"TimeZone,omitempty"`
+	UpdatedAt  int64    `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
+
+func NewAccountPreferences(accountID string) *AccountPreferences {
+	return &AccountPreferences{
+		AccountID:  accountID,
+		ProfileKey: ProfilePreferences,
+		Kind:       ProfilePreferences,
+	}
+}
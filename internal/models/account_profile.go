@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of sightings stored in an account's profile
+const (
+	SightingDevice = "DEVICE"
+	SightingIP     = "IP"
+)
+
+// Sighting records when an account first and last used a device or IP address.
+type Sighting struct {
+	AccountID  string `json:"accountId" dynamodbav:"AccountID"`
+	ProfileKey string `json:"profileKey" dynamodbav:"ProfileKey"`
+	Kind       string `json:"kind" dynamodbav:"Kind"`
+	Value      string `json:"value" dynamodbav:"Value"`
+	FirstSeen  int64  `json:"firstSeen" dynamodbav:"FirstSeen"`
+	LastSeen   int64  `json:"lastSeen" dynamodbav:"LastSeen"`
+	SeenCount  int    `json:"seenCount" dynamodbav:"SeenCount"`
+}
+
+// DeviceProfile is every device and IP address an account has been approved on, keyed by value.
+type DeviceProfile struct {
+	AccountID   string
+	Devices     map[string]Sighting
+	IPAddresses map[string]Sighting
+}
+
+func NewDeviceProfile(accountID string) *DeviceProfile {
+	return &DeviceProfile{
+		AccountID:   accountID,
+		Devices:     make(map[string]Sighting),
+		IPAddresses: make(map[string]Sighting),
+	}
+}
+
+// ProfileKey builds the sort key for an item in the account profile table
+func ProfileKey(kind string, value string) string {
+	return fmt.Sprintf("%s#%s", kind, value)
+}
+
+func (s Sighting) LastSeenTime() time.Time {
+	return time.Unix(s.LastSeen, 0).UTC()
+}
+
+// IsEmpty reports whether the account has no known devices or IP addresses yet
+func (p *DeviceProfile) IsEmpty() bool {
+	return len(p.Devices) == 0 && len(p.IPAddresses) == 0
+}
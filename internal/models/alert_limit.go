@@ -0,0 +1,95 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// Outcomes of admitting a fraud alert to its recipient's digest window
+const (
+	AlertSendNow = "SEND_NOW"
+	AlertHeld    = "HELD"
+)
+
+// DigestPending marks a window holding alerts that have not been sent yet
+const DigestPending = "PENDING"
+
+// HeldAlert is a fraud alert waiting in a window to be sent with the window's digest
+type HeldAlert struct {
+	TransactionID string `json:"transactionId" dynamodbav:"TransactionID"`
+	AccountID     string `json:"accountId" dynamodbav:"AccountID"`
+	HeldAt        int64  `json:"heldAt" dynamodbav:"HeldAt"`
+}
+
+// AlertWindow is one recipient's digest window. The alert that opens a window, FirstID, is sent right away. Alerts
+// flagged before WindowEnd are held and sent together once it passes. DigestState is only set while alerts are held,
+// so the digest index holds just the windows with something to send.
+type AlertWindow struct {
+	LimitKey    string               `json:"limitKey" dynamodbav:"LimitKey"`
+	FirstID     string               `json:"firstId" dynamodbav:"FirstID"`
+	WindowStart int64                `json:"windowStart" dynamodbav:"WindowStart"`
+	WindowEnd   int64                `json:"windowEnd" dynamodbav:"WindowEnd"`
+	DigestState string               `json:"digestState,omitempty" dynamodbav:"DigestState,omitempty"`
+	Held        map[string]HeldAlert `json:"held" dynamodbav:"Held"`
+	ExpiresAt   int64                `json:"expiresAt" dynamodbav:"ExpiresAt"`
+}
+
+// NewAlertWindow opens a window with the alert about a transaction. The window is kept for ttl after it ends.
+func NewAlertWindow(limitKey string, firstID string, now time.Time, window time.Duration, ttl time.Duration) *AlertWindow {
+	return &AlertWindow{
+		LimitKey:    limitKey,
+		FirstID:     firstID,
+		WindowStart: now.Unix(),
+		WindowEnd:   now.Add(window).Unix(),
+		Held:        map[string]HeldAlert{},
+		ExpiresAt:   now.Add(window + ttl).Unix(),
+	}
+}
+
+// Accepts reports whether an alert flagged at now joins the window. A window that has ended still takes alerts
+// while its digest waits to be sent, so they go out with it.
+func (w *AlertWindow) Accepts(now time.Time) bool {
+	return now.Unix() < w.WindowEnd || w.DigestState == DigestPending
+}
+
+// HeldAlerts returns the held alerts ordered by transaction ID
+func (w *AlertWindow) HeldAlerts() []HeldAlert {
+	held := make([]HeldAlert, 0, len(w.Held))
+	for _, alert := range w.Held {
+		held = append(held, alert)
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].TransactionID < held[j].TransactionID })
+	return held
+}
+
+// AlertWindowKey is the limit key of the window for a transaction's alerts: its phone number, or its email when it
+// has none. It is empty when the transaction has neither.
+func AlertWindowKey(txn Transaction) string {
+	switch {
+	case txn.PhoneNumber != "":
+		return "WINDOW#" + txn.PhoneNumber
+	case txn.Email != "":
+		return "WINDOW#" + txn.Email
+	}
+	return ""
+}
+
+// SentMessageKey is the limit key recording that a message was sent to an address on a channel. Identical messages
+// get the same key.
+func SentMessageKey(channel string, address string, subject string, body string) string {
+	hash := sha256.New()
+	for _, part := range []string{channel, address, subject, body} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return "SENT#" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// SentMessage remembers a message being sent until ExpiresAt, which is the end of its claim until it is delivered
+type SentMessage struct {
+	LimitKey  string `json:"limitKey" dynamodbav:"LimitKey"`
+	SentAt    int64  `json:"sentAt" dynamodbav:"SentAt"`
+	ExpiresAt int64  `json:"expiresAt" dynamodbav:"ExpiresAt"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// ConfigItem is a named document stored in the config table, such as a fraud rule file.
+type ConfigItem struct {
+	ConfigID  string `json:"configId" dynamodbav:"ConfigID"`
+	Content   string `json:"content" dynamodbav:"Content"`
+	Version   string `json:"version" dynamodbav:"Version"`
+	UpdatedAt int64  `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Conversation states. A conversation waits for a reply until it is resolved by one or expires.
+const (
+	ConversationAwaitingReply       = "AWAITING_REPLY"
+	ConversationConfirmedFraud      = "CONFIRMED_FRAUD"
+	ConversationConfirmedLegitimate = "CONFIRMED_LEGITIMATE"
+	ConversationExpired             = "EXPIRED"
+)
+
+// Replies a customer can give to a fraud alert
+const (
+	ReplyYes = "YES"
+	ReplyNo  = "NO"
+)
+
+// ReplyCodeSpace is how many distinct reply codes there are. Codes are four digits.
+const ReplyCodeSpace = 10000
+
+// Conversation tracks one fraud alert sent to a phone number and the transactions it asks about,
+// so a reply resolves exactly the alert it answers.
+type Conversation struct {
+	PhoneNumber    string   `json:"phoneNumber" dynamodbav:"PhoneNumber"`
+	AlertID        string   `json:"alertId" dynamodbav:"AlertID"`
+	AccountID      string   `json:"accountId" dynamodbav:"AccountID"`
+	Location       string   `json:"location,omitempty" dynamodbav:"Location,omitempty"`
+	TransactionIDs []string `json:"transactionIds" dynamodbav:"TransactionIDs"`
+	ReplyCode      string   `json:"replyCode" dynamodbav:"ReplyCode"`
+	State          string   `json:"state" dynamodbav:"State"`
+	PromptCount    int      `json:"promptCount" dynamodbav:"PromptCount"`
+	LastReply      string   `json:"lastReply,omitempty" dynamodbav:"LastReply,omitempty"`
+	Escalation     string   `json:"escalation,omitempty" dynamodbav:"Escalation,omitempty"`
+	CreatedAt      int64    `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt      int64    `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	ExpiresAt      int64    `json:"expiresAt" dynamodbav:"ExpiresAt"`
+}
+
+// NewConversation starts a conversation for the alert about a transaction. The alert ID is the transaction ID
+// so retrying the same transaction does not open a second conversation.
+func NewConversation(transaction Transaction, replyCode string, now time.Time, ttl time.Duration) *Conversation {
+	return &Conversation{
+		PhoneNumber:    transaction.PhoneNumber,
+		AlertID:        transaction.TransactionID,
+		AccountID:      transaction.AccountID,
+		Location:       transaction.Location,
+		TransactionIDs: []string{transaction.TransactionID},
+		ReplyCode:      replyCode,
+		State:          ConversationAwaitingReply,
+		PromptCount:    1,
+		CreatedAt:      now.Unix(),
+		UpdatedAt:      now.Unix(),
+		ExpiresAt:      now.Add(ttl).Unix(),
+	}
+}
+
+// NewDigestConversation starts a conversation for a digest alert about several transactions of one account, which
+// a single reply answers for all of them. The alert ID is derived from the transaction IDs so resending the same
+// digest does not open a second conversation.
+func NewDigestConversation(transactions []Transaction, replyCode string, now time.Time, ttl time.Duration) *Conversation {
+	ids := make([]string, 0, len(transactions))
+	for _, txn := range transactions {
+		ids = append(ids, txn.TransactionID)
+	}
+	sort.Strings(ids)
+
+	hash := fnv.New64a()
+	for _, id := range ids {
+		hash.Write([]byte(id))
+		hash.Write([]byte{0})
+	}
+
+	conversation := &Conversation{
+		AlertID:        fmt.Sprintf("digest-%016x", hash.Sum64()),
+		TransactionIDs: ids,
+		ReplyCode:      replyCode,
+		State:          ConversationAwaitingReply,
+		PromptCount:    1,
+		CreatedAt:      now.Unix(),
+		UpdatedAt:      now.Unix(),
+		ExpiresAt:      now.Add(ttl).Unix(),
+	}
+	if len(transactions) > 0 {
+		conversation.PhoneNumber = transactions[0].PhoneNumber
+		conversation.AccountID = transactions[0].AccountID
+		conversation.Location = transactions[0].Location
+	}
+	return conversation
+}
+
+func (c *Conversation) IsOpen() bool {
+	return c.State == ConversationAwaitingReply
+}
+
+func (c *Conversation) IsExpired(now time.Time) bool {
+	return now.Unix() >= c.ExpiresAt
+}
+
+// Resolve moves an open conversation to the state for the customer's reply: NO means they did not make the transaction.
+func (c *Conversation) Resolve(reply string, now time.Time) error {
+	if !c.IsOpen() {
+		return fmt.Errorf("conversation %s for alert %s is already %s", c.PhoneNumber, c.AlertID, c.State)
+	}
+
+	switch reply {
+	case ReplyYes:
+		c.State = ConversationConfirmedLegitimate
+	case ReplyNo:
+		c.State = ConversationConfirmedFraud
+	default:
+		return fmt.Errorf("reply %q does not resolve a conversation", reply)
+	}
+	c.LastReply = reply
+	c.UpdatedAt = now.Unix()
+	return nil
+}
+
+// Expire closes an open conversation that was never answered
+func (c *Conversation) Expire(now time.Time) error {
+	if !c.IsOpen() {
+		return fmt.Errorf("conversation %s for alert %s is already %s", c.PhoneNumber, c.AlertID, c.State)
+	}
+	c.State = ConversationExpired
+	c.UpdatedAt = now.Unix()
+	return nil
+}
+
+// Escalate records the latest escalation step taken because the customer has not replied. A final step closes the conversation.
+func (c *Conversation) Escalate(step string, now time.Time) error {
+	if !c.IsOpen() {
+		return fmt.Errorf("conversation %s for alert %s is already %s", c.PhoneNumber, c.AlertID, c.State)
+	}
+	c.Escalation = step
+	if IsFinalEscalation(step) {
+		c.State = ConversationExpired
+	}
+	c.UpdatedAt = now.Unix()
+	return nil
+}
+
+// Prompt records another message asking the customer to reply
+func (c *Conversation) Prompt(reply string, now time.Time) {
+	c.PromptCount++
+	c.LastReply = reply
+	c.UpdatedAt = now.Unix()
+}
+
+// NewReplyCode derives a reply code from an alert ID, moving to the next free code if it is already used by another
+// open alert for the same phone number. The same alert and codes in use always give the same code.
+func NewReplyCode(alertID string, inUse map[string]bool) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(alertID))
+	start := hash.Sum32() % ReplyCodeSpace
+
+	for offset := uint32(0); offset < ReplyCodeSpace; offset++ {
+		code := fmt.Sprintf("%04d", (start+offset)%ReplyCodeSpace)
+		if !inUse[code] {
+			return code
+		}
+	}
+	return fmt.Sprintf("%04d", start)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CSVColumns maps each column name in a CSV header row to its index
+func CSVColumns(header []string) map[string]int {
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[column] = i
+	}
+	return columns
+}
+
+// ParseCSVTransaction converts a row of the bank transactions CSV export to a Transaction using a column map
+func ParseCSVTransaction(record []string, colMap map[string]int) (Transaction, error) {
+	// Parse numeric fields
+	customerAge, _ := strconv.Atoi(record[colMap["CustomerAge"]])
+	transactionDuration, _ := strconv.Atoi(record[colMap["TransactionDuration"]])
+	loginAttempts, _ := strconv.Atoi(record[colMap["LoginAttempts"]])
+	accountBalance, _ := strconv.ParseFloat(record[colMap["AccountBalance"]], 64)
+	amount, _ := strconv.ParseFloat(record[colMap["TransactionAmount"]], 64)
+
+	// Format phone number - add "+" prefix if not present
+	phoneNumber := record[colMap["PhoneNumber"]]
+	if phoneNumber != "" && !strings.HasPrefix(phoneNumber, "+") {
+		phoneNumber = "+" + phoneNumber
+	}
+
+	transaction := Transaction{
+		TransactionID:           record[colMap["TransactionID"]],
+		AccountID:               record[colMap["AccountID"]],
+		TransactionAmount:       amount,
+		TransactionDate:         record[colMap["TransactionDate"]],
+		TransactionType:         record[colMap["TransactionType"]],
+		Location:                record[colMap["Location"]],
+		DeviceID:                record[colMap["DeviceID"]],
+		IPAddress:               record[colMap["IPAddress"]],
+		MerchantID:              record[colMap["MerchantID"]],
+		Channel:                 record[colMap["Channel"]],
+		CustomerAge:             customerAge,
+		CustomerOccupation:      record[colMap["CustomerOccupation"]],
+		TransactionDuration:     transactionDuration,
+		LoginAttempts:           loginAttempts,
+		AccountBalance:          accountBalance,
+		PreviousTransactionDate: record[colMap["PreviousTransactionDate"]],
+		PhoneNumber:             phoneNumber,
+		Email:                   record[colMap["Email"]],
+		TransactionStatus:       record[colMap["TransactionStatus"]],
+	}
+
+	return transaction, nil
+}
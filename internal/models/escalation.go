@@ -0,0 +1,53 @@
+package models
+
+// Escalation steps taken on a fraud alert the customer has not answered, in the order they happen. The last step
+// is the policy's final action: declining the transaction or holding it for review.
+const (
+	EscalationReminder         = "REMINDER"
+	EscalationSecondaryChannel = "SECONDARY_CHANNEL"
+	EscalationDecline          = "AUTO_DECLINE"
+	EscalationHold             = "AUTO_HOLD"
+)
+
+// Transaction statuses set by an escalation's final action
+const (
+	StatusDeclined = "DECLINED"
+	StatusOnHold   = "ON_HOLD"
+)
+
+// IsResolvedStatus reports whether a transaction status is final: approved or confirmed fraud by the fraud service
+// or the customer, or declined or held by an escalation's final action
+func IsResolvedStatus(status string) bool {
+	switch status {
+	case "APPROVED", "FRAUD", StatusDeclined, StatusOnHold:
+		return true
+	default:
+		return false
+	}
+}
+
+// EscalationStep records one escalation step on the transactions of the alert it was taken for.
+type EscalationStep struct {
+	Step   string `json:"step" dynamodbav:"Step"`
+	At     int64  `json:"at" dynamodbav:"At"`
+	Detail string `json:"detail,omitempty" dynamodbav:"Detail,omitempty"`
+}
+
+// EscalationRank orders escalation steps. No escalation yet ranks 0 and both final actions rank highest.
+func EscalationRank(step string) int {
+	switch step {
+	case EscalationReminder:
+		return 1
+	case EscalationSecondaryChannel:
+		return 2
+	case EscalationDecline, EscalationHold:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// IsFinalEscalation reports whether a step ends the alert
+func IsFinalEscalation(step string) bool {
+	return step == EscalationDecline || step == EscalationHold
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lists that force a fraud decision before any scoring, except that an allowlisted account only has its alerts suppressed
+const (
+	ListAllow = "ALLOW"
+	ListDeny  = "DENY"
+)
+
+// What a list entry matches on a transaction
+const (
+	ListKindMerchant = "MERCHANT"
+	ListKindDevice   = "DEVICE"
+	ListKindIP       = "IP"
+	ListKindAccount  = "ACCOUNT"
+)
+
+// auditTimeFormat is fixed width so audit record keys sort in time order
+const auditTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Record keys in the list table. The active entry and its audit records share a ListKey.
+const (
+	ListEntryRecord  = "ENTRY"
+	ListAuditPrefix  = "AUDIT#"
+	ListActionAdd    = "ADD"
+	ListActionRemove = "REMOVE"
+)
+
+// ListEntry puts a merchant, device, IP address or account on the allowlist or denylist until ExpiresAt.
+type ListEntry struct {
+	ListKey   string `json:"listKey" dynamodbav:"ListKey"`
+	RecordKey string `json:"recordKey" dynamodbav:"RecordKey"`
+	List      string `json:"list" dynamodbav:"List"`
+	Kind      string `json:"kind" dynamodbav:"Kind"`
+	Value     string `json:"value" dynamodbav:"Value"`
+	Reason    string `json:"reason" dynamodbav:"Reason"`
+	CreatedBy string `json:"createdBy" dynamodbav:"CreatedBy"`
+	CreatedAt int64  `json:"createdAt" dynamodbav:"CreatedAt"`
+	ExpiresAt int64  `json:"expiresAt,omitempty" dynamodbav:"ExpiresAt,omitempty"`
+}
+
+// ListAuditRecord is an immutable record of an entry being added or removed, kept after the entry expires.
+type ListAuditRecord struct {
+	ListKey   string `json:"listKey" dynamodbav:"ListKey"`
+	RecordKey string `json:"recordKey" dynamodbav:"RecordKey"`
+	Action    string `json:"action" dynamodbav:"Action"`
+	List      string `json:"list" dynamodbav:"List"`
+	Kind      string `json:"kind" dynamodbav:"Kind"`
+	Value     string `json:"value" dynamodbav:"Value"`
+	Reason    string `json:"reason" dynamodbav:"Reason"`
+	Actor     string `json:"actor" dynamodbav:"Actor"`
+	At        int64  `json:"at" dynamodbav:"At"`
+	// Stored under a different name than ListEntry.ExpiresAt so the table's TTL never deletes audit records
+	EntryExpiresAt int64 `json:"entryExpiresAt,omitempty" dynamodbav:"EntryExpiresAt,omitempty"`
+}
+
+// ListMatch is the list entry that decided a transaction, stored on the transaction.
+type ListMatch struct {
+	List      string `json:"list" dynamodbav:"List"`
+	Kind      string `json:"kind" dynamodbav:"Kind"`
+	Value     string `json:"value" dynamodbav:"Value"`
+	Reason    string `json:"reason" dynamodbav:"Reason"`
+	CreatedBy string `json:"createdBy" dynamodbav:"CreatedBy"`
+}
+
+// NewListEntry validates and builds an entry. A zero ttl never expires.
+func NewListEntry(list string, kind string, value string, reason string, createdBy string, ttl time.Duration, now time.Time) (*ListEntry, error) {
+	list, kind = strings.ToUpper(list), strings.ToUpper(kind)
+	if list != ListAllow && list != ListDeny {
+		return nil, fmt.Errorf("unknown list %q, expected %s or %s", list, ListAllow, ListDeny)
+	}
+	switch kind {
+	case ListKindMerchant, ListKindDevice, ListKindIP, ListKindAccount:
+	default:
+		return nil, fmt.Errorf("unknown list kind %q", kind)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("list entry value cannot be empty")
+	}
+	if createdBy == "" {
+		return nil, fmt.Errorf("list entry needs the person adding it for the audit trail")
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("list entry ttl cannot be negative")
+	}
+
+	entry := &ListEntry{
+		ListKey:   ListKey(kind, value),
+		RecordKey: ListEntryRecord,
+		List:      list,
+		Kind:      kind,
+		Value:     value,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		CreatedAt: now.Unix(),
+	}
+	if ttl > 0 {
+		entry.ExpiresAt = now.Add(ttl).Unix()
+	}
+	return entry, nil
+}
+
+// ListKey builds the partition key for a list entry
+func ListKey(kind string, value string) string {
+	return fmt.Sprintf("%s#%s", strings.ToUpper(kind), value)
+}
+
+// TransactionListKeys are the list keys a transaction can match, skipping fields it does not have
+func TransactionListKeys(txn Transaction) []string {
+	var keys []string
+	for kind, value := range map[string]string{
+		ListKindMerchant: txn.MerchantID,
+		ListKindDevice:   txn.DeviceID,
+		ListKindIP:       txn.IPAddress,
+		ListKindAccount:  txn.AccountID,
+	} {
+		if value != "" {
+			keys = append(keys, ListKey(kind, value))
+		}
+	}
+	return keys
+}
+
+// IsExpired checks the expiry directly since DynamoDB can take a while to delete expired items
+func (e ListEntry) IsExpired(now time.Time) bool {
+	return e.ExpiresAt != 0 && now.Unix() >= e.ExpiresAt
+}
+
+func (e ListEntry) Match() *ListMatch {
+	return &ListMatch{
+		List:      e.List,
+		Kind:      e.Kind,
+		Value:     e.Value,
+		Reason:    e.Reason,
+		CreatedBy: e.CreatedBy,
+	}
+}
+
+// AuditRecord records an action on the entry, keyed by time so the trail reads in order
+func (e ListEntry) AuditRecord(action string, actor string, reason string, at time.Time) ListAuditRecord {
+	return ListAuditRecord{
+		ListKey:        e.ListKey,
+		RecordKey:      fmt.Sprintf("%s%s", ListAuditPrefix, at.UTC().Format(auditTimeFormat)),
+		Action:         action,
+		List:           e.List,
+		Kind:           e.Kind,
+		Value:          e.Value,
+		Reason:         reason,
+		Actor:          actor,
+		At:             at.Unix(),
+		EntryExpiresAt: e.ExpiresAt,
+	}
+}
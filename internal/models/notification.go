@@ -0,0 +1,83 @@
+package models
+
+import "fmt"
+
+// Notification types. Each is rendered from the template catalog in the customer's language.
+const (
+	NotificationFraudAlert           = "fraud_alert"
+	NotificationFraudDigest          = "fraud_digest"
+	NotificationReminder             = "reminder"
+	NotificationFraudConfirmed       = "fraud_confirmed"
+	NotificationFraudRejected        = "fraud_rejected"
+	NotificationInvalidResponse      = "invalid_response"
+	NotificationUnknown              = "unknown"
+	NotificationConversationExpired  = "conversation_expired"
+	NotificationConversationResolved = "conversation_resolved"
+	NotificationChooseAlert          = "choose_alert"
+	NotificationHelp                 = "help"
+	NotificationOptedIn              = "opted_in"
+)
+
+// NotificationTypes lists every notification type a template catalog must define
+var NotificationTypes = []string{
+	NotificationFraudAlert, NotificationFraudDigest, NotificationReminder, NotificationFraudConfirmed, NotificationFraudRejected,
+	NotificationInvalidResponse, NotificationUnknown, NotificationConversationExpired,
+	NotificationConversationResolved, NotificationChooseAlert, NotificationHelp, NotificationOptedIn,
+}
+
+// NotificationData fills a template's placeholders. Date is the transaction's RFC3339 date, formatted for the
+// customer's locale when rendered.
+type NotificationData struct {
+	Amount    string `json:"amount,omitempty" dynamodbav:"Amount,omitempty"`
+	Merchant  string `json:"merchant,omitempty" dynamodbav:"Merchant,omitempty"`
+	Date      string `json:"date,omitempty" dynamodbav:"Date,omitempty"`
+	Last4     string `json:"last4,omitempty" dynamodbav:"Last4,omitempty"`
+	ReplyCode string `json:"replyCode,omitempty" dynamodbav:"ReplyCode,omitempty"`
+	Codes     string `json:"codes,omitempty" dynamodbav:"Codes,omitempty"`
+	Count     int    `json:"count,omitempty" dynamodbav:"Count,omitempty"`
+}
+
+// Notification is a customer-facing message before it is rendered. The language preference of AccountID decides
+// the language, and Locale is used when the account has none or is not known. Location is where the card was last
+// used, for the customer's time zone when they have not set one.
+type Notification struct {
+	Type      string           `json:"type" dynamodbav:"Type"`
+	AccountID string           `json:"accountId,omitempty" dynamodbav:"AccountID,omitempty"`
+	Locale    string           `json:"locale,omitempty" dynamodbav:"Locale,omitempty"`
+	Location  string           `json:"location,omitempty" dynamodbav:"Location,omitempty"`
+	Data      NotificationData `json:"data" dynamodbav:"Data"`
+}
+
+// NewTransactionNotification builds a notification about a flagged transaction, such as its alert or a reminder.
+func NewTransactionNotification(notificationType string, txn Transaction) Notification {
+	return Notification{
+		Type:      notificationType,
+		AccountID: txn.AccountID,
+		Location:  txn.Location,
+		Data: NotificationData{
+			Amount:    fmt.Sprintf("%.2f", txn.TransactionAmount),
+			Merchant:  txn.MerchantID,
+			Date:      txn.TransactionDate,
+			Last4:     last4(txn.AccountID),
+			ReplyCode: txn.ReplyCode,
+		},
+	}
+}
+
+// NewDigestNotification builds the alert about several flagged transactions of one account, sent together as a
+// digest. Amount is their total.
+func NewDigestNotification(transactions []Transaction) Notification {
+	var total float64
+	for _, txn := range transactions {
+		total += txn.TransactionAmount
+	}
+
+	notification := Notification{Type: NotificationFraudDigest, Data: NotificationData{Amount: fmt.Sprintf("%.2f", total), Count: len(transactions)}}
+	if len(transactions) > 0 {
+		notification.AccountID = transactions[0].AccountID
+		notification.Location = transactions[0].Location
+		notification.Data.Last4 = last4(transactions[0].AccountID)
+		notification.Data.ReplyCode = transactions[0].ReplyCode
+	}
+	return notification
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Carrier keywords a phone number can text to manage SMS. Every reply is checked for them before anything else.
+const (
+	SMSKeywordStop  = "STOP"
+	SMSKeywordStart = "START"
+	SMSKeywordHelp  = "HELP"
+)
+
+var smsKeywords = map[string]string{
+	"STOP":        SMSKeywordStop,
+	"STOPALL":     SMSKeywordStop,
+	"UNSUBSCRIBE": SMSKeywordStop,
+	"CANCEL":      SMSKeywordStop,
+	"END":         SMSKeywordStop,
+	"QUIT":        SMSKeywordStop,
+	"START":       SMSKeywordStart,
+	"UNSTOP":      SMSKeywordStart,
+	"HELP":        SMSKeywordHelp,
+	"INFO":        SMSKeywordHelp,
+}
+
+// SMSOptOut records whether a phone number has opted out of text messages. Opting back in keeps the record so the
+// last keyword and when it was sent are still known.
+type SMSOptOut struct {
+	PhoneNumber string `json:"phoneNumber" dynamodbav:"PhoneNumber"`
+	OptedOut    bool   `json:"optedOut" dynamodbav:"OptedOut"`
+	Keyword     string `json:"keyword" dynamodbav:"Keyword"`
+	UpdatedAt   int64  `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
+
+// ParseSMSKeyword returns the carrier keyword a message consists of, or an empty string. Like carriers, only a
+// message that is the keyword alone counts, so "please stop charging me" is not an opt-out.
+func ParseSMSKeyword(body string) string {
+	word := strings.TrimFunc(strings.ToUpper(body), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return smsKeywords[word]
+}
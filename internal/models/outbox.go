@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// OutboxPending marks an outbox notification that has not been sent yet
+const OutboxPending = "PENDING"
+
+// OutboxNotification is a notification written in the same transaction as the change it is about, so it is sent
+// exactly when the change is stored. PendingState is only set until it is sent, so the pending index holds just
+// the notifications left to send. Notifications that could not be delivered are taken out of it too, with FailedAt
+// and FailureReason recording why. Both expire at ExpiresAt.
+type OutboxNotification struct {
+	OutboxID      string `json:"outboxId" dynamodbav:"OutboxID"`
+	Type          string `json:"type" dynamodbav:"Type"`
+	AccountID     string `json:"accountId" dynamodbav:"AccountID"`
+	TransactionID string `json:"transactionId" dynamodbav:"TransactionID"`
+	PendingState  string `json:"pendingState,omitempty" dynamodbav:"PendingState,omitempty"`
+	Channel       string `json:"channel,omitempty" dynamodbav:"Channel,omitempty"`
+	CreatedAt     int64  `json:"createdAt" dynamodbav:"CreatedAt"`
+	SentAt        int64  `json:"sentAt,omitempty" dynamodbav:"SentAt,omitempty"`
+	FailedAt      int64  `json:"failedAt,omitempty" dynamodbav:"FailedAt,omitempty"`
+	FailureReason string `json:"failureReason,omitempty" dynamodbav:"FailureReason,omitempty"`
+	ExpiresAt     int64  `json:"expiresAt,omitempty" dynamodbav:"ExpiresAt,omitempty"`
+}
+
+// NewAlertOutboxNotification records the fraud alert about a transaction. Its OutboxID is derived from the
+// transaction, so flagging the same transaction again finds the alert already recorded.
+func NewAlertOutboxNotification(txn Transaction, now time.Time) *OutboxNotification {
+	return &OutboxNotification{
+		OutboxID:      AlertOutboxID(txn.AccountID, txn.TransactionID),
+		Type:          NotificationFraudAlert,
+		AccountID:     txn.AccountID,
+		TransactionID: txn.TransactionID,
+		PendingState:  OutboxPending,
+		CreatedAt:     now.Unix(),
+	}
+}
+
+// AlertOutboxID is the OutboxID of the fraud alert about a transaction
+func AlertOutboxID(accountID string, transactionID string) string {
+	return "ALERT#" + accountID + "#" + transactionID
+}
+
+// IsPending reports whether the notification is still to be sent
+func (n *OutboxNotification) IsPending() bool {
+	return n.PendingState == OutboxPending
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Processing states of an inbound SMS reply
+const (
+	ReplyProcessing = "PROCESSING"
+	ReplyProcessed  = "PROCESSED"
+	ReplyUnsent     = "UNSENT"
+)
+
+// ProcessedReply records an inbound SMS by its Twilio MessageSid so a redelivered copy is not handled twice.
+// Outcome is the answer sent back, kept so an answer that failed to send can be resent without reprocessing.
+// ExpiresAt is the table's TTL attribute.
+type ProcessedReply struct {
+	MessageSid  string        `json:"messageSid" dynamodbav:"MessageSid"`
+	PhoneNumber string        `json:"phoneNumber" dynamodbav:"PhoneNumber"`
+	Status      string        `json:"status" dynamodbav:"Status"`
+	Outcome     *Notification `json:"outcome,omitempty" dynamodbav:"Outcome,omitempty"`
+	ClaimedAt   int64         `json:"claimedAt" dynamodbav:"ClaimedAt"`
+	UpdatedAt   int64         `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	ExpiresAt   int64         `json:"expiresAt" dynamodbav:"ExpiresAt"`
+}
+
+func NewProcessedReply(msg TwilioMessage, now time.Time, ttl time.Duration) *ProcessedReply {
+	return &ProcessedReply{
+		MessageSid:  msg.MessageSid,
+		PhoneNumber: msg.From,
+		Status:      ReplyProcessing,
+		ClaimedAt:   now.Unix(),
+		UpdatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(ttl).Unix(),
+	}
+}
+
+// Complete records the answer sent back and whether it was delivered. A notification with no Type means the
+// message was not answered.
+func (r *ProcessedReply) Complete(outcome Notification, sent bool, now time.Time) {
+	r.Outcome = nil
+	if outcome.Type != "" {
+		r.Outcome = &outcome
+	}
+	r.Status = ReplyProcessed
+	if !sent {
+		r.Status = ReplyUnsent
+	}
+	r.UpdatedAt = now.Unix()
+}
+
+// Answer returns the answer recorded for the message, or an empty notification when it was not answered
+func (r *ProcessedReply) Answer() Notification {
+	if r.Outcome == nil {
+		return Notification{}
+	}
+	return *r.Outcome
+}
@@ -28,8 +28,16 @@ type TwilioMessage struct {
 	FromState           string `json:"FromState"`
 }
 
-func UnmarshalResponseSQS(message string) (*TwilioMessage, error) {
-	var result TwilioMessage
+// TwilioWebhook is an inbound SMS webhook as Twilio sent it: the X-Twilio-Signature header, the full request URL
+// and the form parameters, so the signature can be checked before the message is trusted.
+type TwilioWebhook struct {
+	Signature string            `json:"signature"`
+	URL       string            `json:"url"`
+	Params    map[string]string `json:"params"`
+}
+
+func UnmarshalResponseSQS(message string) (*TwilioWebhook, error) {
+	var result TwilioWebhook
 	err := json.Unmarshal([]byte(message), &result)
 	if err != nil {
 		return nil, err
@@ -38,6 +46,20 @@ func UnmarshalResponseSQS(message string) (*TwilioMessage, error) {
 
 }
 
+// Message reads the SMS out of the webhook's form parameters
+func (w TwilioWebhook) Message() (*TwilioMessage, error) {
+	params, err := json.Marshal(w.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result TwilioMessage
+	if err := json.Unmarshal(params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (msg TwilioMessage) ParseUserResponse() string {
 	return strings.ToUpper(strings.TrimSpace(msg.Body))
 }
@@ -0,0 +1,19 @@
+package models
+
+// Security event types
+const (
+	SecurityEventWebhookRejected = "WEBHOOK_REJECTED"
+)
+
+// SecurityEvent records an inbound request that was refused because it could not be trusted. EventID orders events
+// of a type by when they happened.
+type SecurityEvent struct {
+	EventType  string `json:"eventType" dynamodbav:"EventType"`
+	EventID    string `json:"eventId" dynamodbav:"EventID"`
+	Reason     string `json:"reason" dynamodbav:"Reason"`
+	Source     string `json:"source,omitempty" dynamodbav:"Source,omitempty"`
+	MessageSid string `json:"messageSid,omitempty" dynamodbav:"MessageSid,omitempty"`
+	URL        string `json:"url,omitempty" dynamodbav:"URL,omitempty"`
+	Payload    string `json:"payload,omitempty" dynamodbav:"Payload,omitempty"`
+	OccurredAt int64  `json:"occurredAt" dynamodbav:"OccurredAt"`
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ProfileBaseline is the kind of account profile item holding a spending baseline
+const ProfileBaseline = "BASELINE"
+
+// AllSegments stands in for TransactionType or Channel in the account-wide baseline
+const AllSegments = "*"
+
+// BaselineBucketBounds are the upper bounds of the amount histogram buckets. Amounts above the last bound fall
+// into a final overflow bucket, so a histogram has len(BaselineBucketBounds)+1 buckets.
+var BaselineBucketBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 20000, 50000, 100000}
+
+// SpendingBaseline summarizes an account's past transaction amounts for one TransactionType and Channel.
+// Count, Sum, SumSquares and the histogram are plain counters so the baseline can be updated incrementally.
+type SpendingBaseline struct {
+	AccountID       string  `json:"accountId" dynamodbav:"AccountID"`
+	ProfileKey      string  `json:"profileKey" dynamodbav:"ProfileKey"`
+	Kind            string  `json:"kind" dynamodbav:"Kind"`
+	TransactionType string  `json:"transactionType" dynamodbav:"TransactionType"`
+	Channel         string  `json:"channel" dynamodbav:"Channel"`
+	Count           int64   `json:"count" dynamodbav:"Count"`
+	Sum             float64 `json:"sum" dynamodbav:"Sum"`
+	SumSquares      float64 `json:"sumSquares" dynamodbav:"SumSquares"`
+	Histogram       []int64 `json:"histogram" dynamodbav:"-"`
+}
+
+func NewSpendingBaseline(accountID string, transactionType string, channel string) *SpendingBaseline {
+	transactionType, channel = normalizeSegment(transactionType), normalizeSegment(channel)
+	return &SpendingBaseline{
+		AccountID:       accountID,
+		ProfileKey:      BaselineKey(transactionType, channel),
+		Kind:            ProfileBaseline,
+		TransactionType: transactionType,
+		Channel:         channel,
+		Histogram:       make([]int64, len(BaselineBucketBounds)+1),
+	}
+}
+
+// BaselineKey builds the profile sort key for the baseline of a TransactionType and Channel
+func BaselineKey(transactionType string, channel string) string {
+	return ProfileKey(ProfileBaseline, normalizeSegment(transactionType)+"#"+normalizeSegment(channel))
+}
+
+// BaselineBucketAttribute is the item attribute holding the count of a histogram bucket
+func BaselineBucketAttribute(bucket int) string {
+	return fmt.Sprintf("Bucket%02d", bucket)
+}
+
+// BaselineBucket returns the histogram bucket an amount falls into
+func BaselineBucket(amount float64) int {
+	return sort.SearchFloat64s(BaselineBucketBounds, amount)
+}
+
+// Add folds an amount into the baseline
+func (b *SpendingBaseline) Add(amount float64) {
+	if len(b.Histogram) != len(BaselineBucketBounds)+1 {
+		b.Histogram = append(b.Histogram, make([]int64, len(BaselineBucketBounds)+1-len(b.Histogram))...)
+	}
+	b.Count++
+	b.Sum += amount
+	b.SumSquares += amount * amount
+	b.Histogram[BaselineBucket(amount)]++
+}
+
+func (b *SpendingBaseline) Mean() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return b.Sum / float64(b.Count)
+}
+
+// Variance is the sample variance of the amounts seen so far
+func (b *SpendingBaseline) Variance() float64 {
+	if b.Count < 2 {
+		return 0
+	}
+	mean := b.Mean()
+	// Guard against small negative values from floating point cancellation
+	return max(0, (b.SumSquares-float64(b.Count)*mean*mean)/float64(b.Count-1))
+}
+
+func (b *SpendingBaseline) StdDev() float64 {
+	return math.Sqrt(b.Variance())
+}
+
+// ZScore is how many standard deviations an amount is from the mean. When every amount seen was identical
+// any different amount is infinitely far away.
+func (b *SpendingBaseline) ZScore(amount float64) float64 {
+	deviation := amount - b.Mean()
+	stdDev := b.StdDev()
+	if stdDev == 0 {
+		if deviation == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), deviation)
+	}
+	return deviation / stdDev
+}
+
+// PercentileRank estimates the fraction of past amounts below the given amount from the histogram,
+// counting half of the amount's own bucket.
+func (b *SpendingBaseline) PercentileRank(amount float64) float64 {
+	if b.Count == 0 {
+		return 0
+	}
+
+	bucket := BaselineBucket(amount)
+	var below int64
+	for i := 0; i < bucket && i < len(b.Histogram); i++ {
+		below += b.Histogram[i]
+	}
+	var within int64
+	if bucket < len(b.Histogram) {
+		within = b.Histogram[bucket]
+	}
+	return (float64(below) + float64(within)/2) / float64(b.Count)
+}
+
+func normalizeSegment(segment string) string {
+	segment = strings.ToUpper(strings.TrimSpace(segment))
+	if segment == "" {
+		return AllSegments
+	}
+	return segment
+}
@@ -16,25 +16,44 @@ import (
 
 // Transaction represents a record in DynamoDB.
 type Transaction struct {
-	TransactionID           string  `json:"transactionId" dynamodbav:"TransactionID" validate:"required"`
-	AccountID               string  `json:"accountId" dynamodbav:"AccountID" validate:"required"`
-	TransactionAmount       float64 `json:"amount" dynamodbav:"TransactionAmount" validate:"gte=0"`
-	TransactionDate         string  `json:"transactionDate" dynamodbav:"TransactionDate"`
-	TransactionType         string  `json:"transactionType" dynamodbav:"TransactionType"`
-	Location                string  `json:"location" dynamodbav:"Location"`
-	DeviceID                string  `json:"deviceId" dynamodbav:"DeviceID"`
-	IPAddress               string  `json:"ipAddress" dynamodbav:"IPAddress"`
-	MerchantID              string  `json:"merchantId" dynamodbav:"MerchantID"`
-	Channel                 string  `json:"channel" dynamodbav:"Channel"`
-	CustomerAge             int     `json:"customerAge" dynamodbav:"CustomerAge" validate:"gte=18"`
-	CustomerOccupation      string  `json:"customerOccupation" dynamodbav:"CustomerOccupation"`
-	TransactionDuration     int     `json:"transactionDuration" dynamodbav:"TransactionDuration"`
-	LoginAttempts           int     `json:"loginAttempts" dynamodbav:"LoginAttempts"`
-	AccountBalance          float64 `json:"accountBalance" dynamodbav:"AccountBalance"`
-	PreviousTransactionDate string  `json:"previousTransactionDate" dynamodbav:"PreviousTransactionDate"`
-	PhoneNumber             string  `json:"phoneNumber" dynamodbav:"PhoneNumber" validate:"required,e164"`
-	Email                   string  `json:"email" dynamodbav:"Email" validate:"required,email"`
-	TransactionStatus       string  `json:"transactionStatus" dynamodbav:"TransactionStatus"`
+	TransactionID           string           `json:"transactionId" dynamodbav:"TransactionID" validate:"required"`
+	AccountID               string           `json:"accountId" dynamodbav:"AccountID" validate:"required"`
+	TransactionAmount       float64          `json:"amount" dynamodbav:"TransactionAmount" validate:"gte=0"`
+	TransactionDate         string           `json:"transactionDate" dynamodbav:"TransactionDate"`
+	TransactionType         string           `json:"transactionType" dynamodbav:"TransactionType"`
+	Location                string           `json:"location" dynamodbav:"Location"`
+	DeviceID                string           `json:"deviceId" dynamodbav:"DeviceID,omitempty"`
+	IPAddress               string           `json:"ipAddress" dynamodbav:"IPAddress"`
+	MerchantID              string           `json:"merchantId" dynamodbav:"MerchantID"`
+	Channel                 string           `json:"channel" dynamodbav:"Channel"`
+	CustomerAge             int              `json:"customerAge" dynamodbav:"CustomerAge" validate:"gte=18"`
+	CustomerOccupation      string           `json:"customerOccupation" dynamodbav:"CustomerOccupation"`
+	TransactionDuration     int              `json:"transactionDuration" dynamodbav:"TransactionDuration"`
+	LoginAttempts           int              `json:"loginAttempts" dynamodbav:"LoginAttempts"`
+	AccountBalance          float64          `json:"accountBalance" dynamodbav:"AccountBalance"`
+	PreviousTransactionDate string           `json:"previousTransactionDate" dynamodbav:"PreviousTransactionDate"`
+	PhoneNumber             string           `json:"phoneNumber" dynamodbav:"PhoneNumber" validate:"required,e164"`
+	Email                   string           `json:"email" dynamodbav:"Email" validate:"required,email"`
+	TransactionStatus       string           `json:"transactionStatus" dynamodbav:"TransactionStatus"`
+	RiskScore               int              `json:"riskScore" dynamodbav:"RiskScore"`
+	ReasonCodes             []string         `json:"reasonCodes" dynamodbav:"ReasonCodes"`
+	TransactionTimestamp    int64            `json:"transactionTimestamp" dynamodbav:"TransactionTimestamp"`
+	ShadowDecisions         []ShadowDecision `json:"shadowDecisions" dynamodbav:"ShadowDecisions"`
+	ListMatch               *ListMatch       `json:"listMatch,omitempty" dynamodbav:"ListMatch,omitempty"`
+	ReplyCode               string           `json:"replyCode,omitempty" dynamodbav:"ReplyCode,omitempty"`
+	Escalations             []EscalationStep `json:"escalations,omitempty" dynamodbav:"Escalations,omitempty"`
+	AlertChannel            string           `json:"alertChannel,omitempty" dynamodbav:"AlertChannel,omitempty"`
+	AlertedAt               int64            `json:"alertedAt,omitempty" dynamodbav:"AlertedAt,omitempty"`
+}
+
+// ShadowDecision is a challenger detector's verdict on a transaction, stored next to the live decision without being acted on.
+type ShadowDecision struct {
+	Challenger  string   `json:"challenger" dynamodbav:"Challenger"`
+	Score       int      `json:"score" dynamodbav:"Score"`
+	IsFraud     bool     `json:"isFraud" dynamodbav:"IsFraud"`
+	ReasonCodes []string `json:"reasonCodes" dynamodbav:"ReasonCodes"`
+	Disagrees   bool     `json:"disagrees" dynamodbav:"Disagrees"`
+	Error       string   `json:"error,omitempty" dynamodbav:"Error,omitempty"`
 }
 
 // MarshalDynamoDB marshals a Transaction into a DynamoDB attribute map.
@@ -111,8 +130,8 @@ func (t *Transaction) TransactionUpdatePayload() (map[string]interface{}, error)
 			continue
 		}
 
-		// If it's effectively empty, skip it
-		if isEmpty(av) {
+		// If it's effectively empty, skip it. A risk score of 0 is a real score and is written.
+		if isEmpty(av) && field != "RiskScore" {
 			continue
 		}
 
@@ -164,8 +183,18 @@ func (txn *Transaction) ToDynamoDBAttributeValueMap() map[string]events.DynamoDB
 			switch field.Kind() {
 			case reflect.String:
 				avMap[fieldName] = events.NewStringAttribute(field.String())
-			case reflect.Float64, reflect.Int:
+			case reflect.Float64, reflect.Int, reflect.Int64:
 				avMap[fieldName] = events.NewNumberAttribute(fmt.Sprintf("%v", field.Interface()))
+			case reflect.Slice:
+				if field.Len() == 0 {
+					avMap[fieldName] = events.NewNullAttribute()
+					continue
+				}
+				var list []events.DynamoDBAttributeValue
+				for j := 0; j < field.Len(); j++ {
+					list = append(list, events.NewStringAttribute(fmt.Sprintf("%v", field.Index(j).Interface())))
+				}
+				avMap[fieldName] = events.NewListAttribute(list)
 			default:
 				avMap[fieldName] = events.NewNullAttribute()
 			}
@@ -175,28 +204,24 @@ func (txn *Transaction) ToDynamoDBAttributeValueMap() map[string]events.DynamoDB
 	return avMap
 }
 
-func last4(accountId string) string {
-	if len(accountId) >= 4 {
-		return accountId[len(accountId)-4:]
+// GetTransactionTime returns when the transaction happened, preferring the sortable TransactionTimestamp
+// and falling back to an RFC3339 TransactionDate. The zero time is returned when neither is usable.
+func (txn *Transaction) GetTransactionTime() time.Time {
+	if txn.TransactionTimestamp > 0 {
+		return time.Unix(txn.TransactionTimestamp, 0).UTC()
 	}
-	return accountId
-}
-
-func formatDateTime(dt string) string {
-	t, err := time.Parse(time.RFC3339, dt)
+	t, err := time.Parse(time.RFC3339, txn.TransactionDate)
 	if err != nil {
-		return dt // fallback to raw string
+		return time.Time{}
 	}
-	return t.Format("Jan 2 at 3:04 PM")
+	return t.UTC()
 }
 
-// Get subject, message for an email fraud alert
-func (txn *Transaction) GetFraudEmailContent() (string, string) {
-	return "Suspicious Activity on Your Card", fmt.Sprintf("CAPITAL ONE: We detected a suspicious transaction on your card ending in 1234 for $%.2f at %s on %s. If this was you, reply YES. If not, reply NO or call us immediately.", txn.TransactionAmount,
-		txn.MerchantID,
-		formatDateTime(txn.TransactionDate),
-	)
-
+func last4(accountId string) string {
+	if len(accountId) >= 4 {
+		return accountId[len(accountId)-4:]
+	}
+	return accountId
 }
 
 // Converts AWS Lambda event DynamoDBAttributeValue to AWS SDK v2 AttributeValue
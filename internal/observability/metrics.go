@@ -2,8 +2,12 @@ package observability
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
 	"github.com/aws/aws-xray-sdk-go/xray"
 )
 
@@ -15,6 +19,9 @@ const (
 	KeyFraudulentTransactionIDs = "FraudulentTransactionIDs"
 	KeyFraudulentEmails         = "FraudulentEmails"
 	KeyFraudulentAmounts        = "FraudulentAmounts"
+	KeyFraudRiskScores          = "FraudRiskScores"
+	KeyChallengerStats          = "ChallengerStats"
+	KeyFraudListMatches         = "FraudListMatches"
 	KeyEmailsChecked            = "EmailsChecked"
 
 	// Transaction-related metadata keys
@@ -25,6 +32,14 @@ const (
 	KeyTransaction       = "Transaction-"
 )
 
+// CloudWatch metric names and dimensions
+const (
+	MetricChallengerEvaluated     = "ChallengerEvaluated"
+	MetricChallengerDisagreements = "ChallengerDisagreements"
+	MetricChallengerErrors        = "ChallengerErrors"
+	DimensionChallenger           = "Challenger"
+)
+
 // SafeAddMetadata adds metadata to an X-Ray segment with error handling
 func SafeAddMetadata(seg *xray.Segment, key string, value interface{}) {
 	if err := seg.AddMetadata(key, value); err != nil {
@@ -45,3 +60,43 @@ func SafeAddAnnotation(ctx context.Context, key string, value string) {
 		fmt.Printf("Failed to add annotation [%s]: %v\n", key, err)
 	}
 }
+
+// WriteChallengerMetrics writes a challenger's shadow decision as CloudWatch metrics in embedded metric format.
+// Lambda turns the log line into metrics summed across every container, so a challenger's disagreement rate
+// survives cold starts and can be compared over any period before promoting it.
+func WriteChallengerMetrics(w io.Writer, namespace string, decision models.ShadowDecision, now time.Time) error {
+	var evaluated, disagreements, errors int
+	if decision.Error != "" {
+		errors = 1
+	} else {
+		evaluated = 1
+		if decision.Disagrees {
+			disagreements = 1
+		}
+	}
+
+	document := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"Timestamp": now.UnixMilli(),
+			"CloudWatchMetrics": []map[string]interface{}{{
+				"Namespace":  namespace,
+				"Dimensions": [][]string{{DimensionChallenger}},
+				"Metrics": []map[string]string{
+					{"Name": MetricChallengerEvaluated, "Unit": "Count"},
+					{"Name": MetricChallengerDisagreements, "Unit": "Count"},
+					{"Name": MetricChallengerErrors, "Unit": "Count"},
+				},
+			}},
+		},
+		DimensionChallenger:           decision.Challenger,
+		MetricChallengerEvaluated:     evaluated,
+		MetricChallengerDisagreements: disagreements,
+		MetricChallengerErrors:        errors,
+	}
+	line, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal challenger metrics: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(line))
+	return err
+}
@@ -0,0 +1,320 @@
+// Package replies classifies free text SMS replies to fraud alerts, so "yep", "not me!" or a thumbs up are
+// understood the same way as YES and NO.
+package replies
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundled synonym table used when config.ReplyConfig.SynonymsPath is not set.
+//
+//go:embed data/synonyms.yaml
+var bundledSynonyms []byte
+
+// Intents a reply can express
+const (
+	IntentConfirm = "confirm"
+	IntentDeny    = "deny"
+	IntentHelp    = "help"
+	IntentStop    = "stop"
+	IntentUnknown = "unknown"
+)
+
+var intents = []string{IntentConfirm, IntentDeny, IntentHelp, IntentStop}
+
+// Confidence is how sure the classifier is of an intent. Higher levels compare greater.
+type Confidence int
+
+const (
+	ConfidenceNone Confidence = iota
+	ConfidenceLow
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// ParseConfidence reads a confidence level by name
+func ParseConfidence(value string) (Confidence, error) {
+	for c := ConfidenceNone; c <= ConfidenceHigh; c++ {
+		if strings.EqualFold(strings.TrimSpace(value), c.String()) {
+			return c, nil
+		}
+	}
+	return ConfidenceNone, fmt.Errorf("unknown confidence level %q", value)
+}
+
+// Classification is what a reply means. Code is the alert reply code if the reply included one, and Locale is
+// the locale whose synonyms matched.
+type Classification struct {
+	Intent     string
+	Confidence Confidence
+	Code       string
+	Locale     string
+}
+
+// SynonymFile is the document format of a synonym table: the phrases for each intent, by locale.
+type SynonymFile struct {
+	DefaultLocale string                         `yaml:"default_locale"`
+	Locales       map[string]map[string][]string `yaml:"locales"`
+}
+
+type phrase struct {
+	intent string
+	locale string
+	words  []string
+}
+
+// Classifier matches replies against a synonym table. A reply that is exactly a synonym is high confidence, one
+// that starts with a synonym or contains a synonym of several words is medium, and one that only contains a single
+// word synonym is low. Synonyms from a locale other than the requested one count one level lower, and a reply
+// matching two intents is low confidence.
+type Classifier struct {
+	defaultLocale string
+	locales       map[string]bool
+	phrases       []phrase
+}
+
+var (
+	configuredClassifier     *Classifier
+	configuredClassifierOnce sync.Once
+)
+
+// LoadSynonyms parses a synonym table. Unknown intents or fields reject the whole table.
+func LoadSynonyms(reader io.Reader) (*Classifier, error) {
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var file SynonymFile
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("synonym table is empty")
+		}
+		return nil, fmt.Errorf("failed to decode synonym table: %w", err)
+	}
+	if _, ok := file.Locales[file.DefaultLocale]; !ok {
+		return nil, fmt.Errorf("default locale %q has no synonyms", file.DefaultLocale)
+	}
+
+	classifier := &Classifier{defaultLocale: file.DefaultLocale, locales: make(map[string]bool)}
+	for locale, synonyms := range file.Locales {
+		classifier.locales[locale] = true
+		for intent, values := range synonyms {
+			if !isIntent(intent) {
+				return nil, fmt.Errorf("unknown intent %q in locale %s", intent, locale)
+			}
+			for _, value := range values {
+				words, _ := tokenize(value)
+				if len(words) == 0 {
+					return nil, fmt.Errorf("synonym %q for %s in locale %s has no words", value, intent, locale)
+				}
+				classifier.phrases = append(classifier.phrases, phrase{intent: intent, locale: locale, words: words})
+			}
+		}
+	}
+	return classifier, nil
+}
+
+// LoadSynonymsFile loads a synonym table from local disk.
+func LoadSynonymsFile(path string) (*Classifier, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open synonym table: %w", err)
+	}
+	defer file.Close()
+
+	return LoadSynonyms(file)
+}
+
+// BundledClassifier returns a classifier using the synonym table shipped with the binary.
+func BundledClassifier() *Classifier {
+	classifier, err := LoadSynonyms(bytes.NewReader(bundledSynonyms))
+	if err != nil {
+		panic(fmt.Sprintf("bundled synonym table is invalid: %s", err))
+	}
+	return classifier
+}
+
+// ConfiguredClassifier loads the table at config.ReplyConfig.SynonymsPath once, falling back to the bundled table
+// when no path is set or the file cannot be loaded.
+func ConfiguredClassifier() *Classifier {
+	configuredClassifierOnce.Do(func() {
+		if path := config.ReplyConfig.SynonymsPath; path != "" {
+			classifier, err := LoadSynonymsFile(path)
+			if err == nil {
+				configuredClassifier = classifier
+				return
+			}
+			log.Printf("Warning: using bundled synonym table, could not load %s: %s", path, err)
+		}
+		configuredClassifier = BundledClassifier()
+	})
+	return configuredClassifier
+}
+
+type match struct {
+	phrase
+	start      int
+	confidence Confidence
+}
+
+func (m match) overlaps(other match) bool {
+	return m.start < other.start+len(other.words) && other.start < m.start+len(m.words)
+}
+
+// Classify reads a reply in the given locale, or the table's default locale when it is empty or unknown.
+func (c *Classifier) Classify(body string, locale string) Classification {
+	words, code := tokenize(body)
+	if !c.locales[locale] {
+		locale = c.defaultLocale
+	}
+
+	var matches []match
+	for _, p := range c.phrases {
+		for start := 0; start+len(p.words) <= len(words); start++ {
+			if !sameWords(words[start:start+len(p.words)], p.words) {
+				continue
+			}
+
+			confidence := ConfidenceLow
+			switch {
+			case start == 0 && len(p.words) == len(words):
+				confidence = ConfidenceHigh
+			case start == 0 || len(p.words) > 1:
+				confidence = ConfidenceMedium
+			}
+			if p.locale != locale && confidence > ConfidenceLow {
+				confidence--
+			}
+			matches = append(matches, match{phrase: p, start: start, confidence: confidence})
+		}
+	}
+	if len(matches) == 0 {
+		return Classification{Intent: IntentUnknown, Confidence: ConfidenceNone, Code: code}
+	}
+
+	// The strongest, then longest, match wins. Any other intent outside the words it covers makes the reply ambiguous.
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].confidence != matches[j].confidence {
+			return matches[i].confidence > matches[j].confidence
+		}
+		if len(matches[i].words) != len(matches[j].words) {
+			return len(matches[i].words) > len(matches[j].words)
+		}
+		return matches[i].locale == locale && matches[j].locale != locale
+	})
+	best := matches[0]
+	for _, other := range matches[1:] {
+		if other.intent != best.intent && !other.overlaps(best) {
+			best.confidence = ConfidenceLow
+			break
+		}
+	}
+
+	return Classification{Intent: best.intent, Confidence: best.confidence, Code: code, Locale: best.locale}
+}
+
+// tokenize lowercases a reply and splits it into words and emoji, dropping punctuation. The first four digit
+// number is taken out as the alert reply code.
+func tokenize(text string) ([]string, string) {
+	var words []string
+	code := ""
+	var word strings.Builder
+	flush := func() {
+		value := strings.Trim(word.String(), "'")
+		word.Reset()
+		if value == "" {
+			return
+		}
+		if code == "" && isReplyCode(value) {
+			code = value
+			return
+		}
+		words = append(words, value)
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '’' || r == '\'':
+			word.WriteRune('\'')
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Sk, r) || r == '\u200d':
+			// Variation selectors, skin tones and joiners only change how an emoji is drawn
+		case unicode.IsSymbol(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words, code
+}
+
+func sameWords(words []string, synonym []string) bool {
+	for i := range synonym {
+		if words[i] != synonym[i] && squeeze(words[i]) != squeeze(synonym[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// squeeze collapses letters repeated for emphasis, so "yesss" and "noooo" read as "yes" and "no"
+func squeeze(word string) string {
+	var result strings.Builder
+	var previous rune
+	for i, r := range word {
+		if i == 0 || r != previous {
+			result.WriteRune(r)
+		}
+		previous = r
+	}
+	return result.String()
+}
+
+func isReplyCode(word string) bool {
+	if len(word) != 4 {
+		return false
+	}
+	for _, r := range word {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIntent(intent string) bool {
+	for _, known := range intents {
+		if intent == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+// ErrNoRuleSet is returned when scoring before any valid rule file was loaded.
+var ErrNoRuleSet = errors.New("no valid rule file loaded")
+
+type loadedRuleSet struct {
+	engine  *fraud.RuleEngine
+	version string
+}
+
+// ReloadingDetector scores transactions with the rules from a rule file and checks the Source for changes
+// at most once per Interval, so new rules are picked up without a deploy. A changed file that does not
+// compile is rejected and the last good version stays active.
+type ReloadingDetector struct {
+	Source           Source
+	Interval         time.Duration
+	DefaultThreshold int
+	Enrichers        []fraud.Enricher
+
+	current   atomic.Pointer[loadedRuleSet]
+	reloading sync.Mutex
+	lastCheck time.Time
+}
+
+// NewReloadingDetector loads the rule file once and fails if it is invalid, since there is no last good version to fall back to.
+func NewReloadingDetector(ctx context.Context, source Source, interval time.Duration, defaultThreshold int, enrichers ...fraud.Enricher) (*ReloadingDetector, error) {
+	detector := &ReloadingDetector{
+		Source:           source,
+		Interval:         interval,
+		DefaultThreshold: defaultThreshold,
+		Enrichers:        enrichers,
+	}
+	if err := detector.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// Reload fetches the rule file and swaps in the new rules when the file changed and compiles.
+func (d *ReloadingDetector) Reload(ctx context.Context) error {
+	d.reloading.Lock()
+	defer d.reloading.Unlock()
+	return d.reload(ctx)
+}
+
+func (d *ReloadingDetector) reload(ctx context.Context) error {
+	d.lastCheck = time.Now()
+
+	data, version, err := d.Source.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch rule file %s: %w", d.Source, err)
+	}
+
+	current := d.current.Load()
+	if current != nil && current.version == version {
+		return nil
+	}
+
+	ruleSet, err := ParseRuleFile(data, d.DefaultThreshold)
+	if err != nil {
+		if current != nil {
+			return fmt.Errorf("rejected rule file %s, keeping version %s: %w", d.Source, current.version, err)
+		}
+		return fmt.Errorf("rejected rule file %s: %w", d.Source, err)
+	}
+
+	engine := fraud.NewRuleEngine(ruleSet.Threshold, ruleSet.Rules...).WithEnrichers(d.Enrichers...)
+	d.current.Store(&loadedRuleSet{engine: engine, version: version})
+	log.Printf("Loaded %d fraud rules from %s (file version %q)", len(ruleSet.Rules), d.Source, ruleSet.Version)
+	return nil
+}
+
+// Version identifies the contents of the active rule file, or is empty if none was loaded
+func (d *ReloadingDetector) Version() string {
+	if current := d.current.Load(); current != nil {
+		return current.version
+	}
+	return ""
+}
+
+// Evaluate scores with the active rules. When a check is due one caller reloads while concurrent
+// callers keep using the active rules instead of waiting.
+func (d *ReloadingDetector) Evaluate(ctx context.Context, transaction models.Transaction) (*fraud.Decision, error) {
+	if d.reloading.TryLock() {
+		if time.Since(d.lastCheck) >= d.Interval {
+			if err := d.reload(ctx); err != nil {
+				log.Printf("Warning: %s", err)
+			}
+		}
+		d.reloading.Unlock()
+	}
+
+	current := d.current.Load()
+	if current == nil {
+		return nil, ErrNoRuleSet
+	}
+	return current.engine.Evaluate(ctx, transaction)
+}
@@ -0,0 +1,128 @@
+// Package rules loads fraud rules written as expressions in a YAML or JSON rule file, for example:
+//
+//	version: "2024-06-01"
+//	threshold: 600
+//	rules:
+//	  - name: BigSpendOnNewDevice
+//	    expression: amount > 3 * account.avg_amount && device.is_new
+//	    weight: 600
+//	    reason_code: BIG_SPEND_NEW_DEVICE
+package rules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/dsl"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFile is the document format of a rule file. JSON files are read the same way since JSON is valid YAML.
+type RuleFile struct {
+	Version   string           `yaml:"version"`
+	Threshold *int             `yaml:"threshold"`
+	Rules     []RuleDefinition `yaml:"rules"`
+}
+
+type RuleDefinition struct {
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+	Weight     int    `yaml:"weight"`
+	ReasonCode string `yaml:"reason_code"`
+}
+
+// RuleSet is a compiled rule file.
+type RuleSet struct {
+	Version   string
+	Threshold int
+	Rules     []fraud.FraudRule
+}
+
+// ParseRuleFile decodes and compiles a rule file. Any problem, including unknown fields, rejects the whole file
+// so a partially valid file is never used. defaultThreshold applies when the file does not set one.
+func ParseRuleFile(data []byte, defaultThreshold int) (*RuleSet, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file RuleFile
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("rule file is empty")
+		}
+		return nil, fmt.Errorf("failed to decode rule file: %w", err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("rule file has no rules")
+	}
+
+	ruleSet := &RuleSet{Version: file.Version, Threshold: defaultThreshold}
+	if file.Threshold != nil {
+		if *file.Threshold < fraud.MinRiskScore || *file.Threshold > fraud.MaxRiskScore {
+			return nil, fmt.Errorf("threshold %d is outside %d-%d", *file.Threshold, fraud.MinRiskScore, fraud.MaxRiskScore)
+		}
+		ruleSet.Threshold = *file.Threshold
+	}
+
+	names := make(map[string]bool)
+	for i, definition := range file.Rules {
+		rule, err := compileRule(definition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, definition.Name, err)
+		}
+		if names[definition.Name] {
+			return nil, fmt.Errorf("rule %d (%s): duplicate rule name", i+1, definition.Name)
+		}
+		names[definition.Name] = true
+		ruleSet.Rules = append(ruleSet.Rules, rule)
+	}
+
+	return ruleSet, nil
+}
+
+func compileRule(definition RuleDefinition) (*ExpressionRule, error) {
+	if definition.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if definition.ReasonCode == "" {
+		return nil, fmt.Errorf("reason_code is required")
+	}
+	if definition.Weight <= 0 || definition.Weight > fraud.MaxRiskScore {
+		return nil, fmt.Errorf("weight %d is outside 1-%d", definition.Weight, fraud.MaxRiskScore)
+	}
+
+	expression, err := dsl.Compile(definition.Expression, Variables)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", definition.Expression, err)
+	}
+
+	return &ExpressionRule{
+		RuleName:   definition.Name,
+		Expression: expression,
+		Weight:     definition.Weight,
+		ReasonCode: definition.ReasonCode,
+	}, nil
+}
+
+// ExpressionRule triggers when its compiled expression is true for the transaction's signals.
+type ExpressionRule struct {
+	RuleName   string
+	Expression *dsl.Expression
+	Weight     int
+	ReasonCode string
+}
+
+func (r *ExpressionRule) Name() string {
+	return r.RuleName
+}
+
+func (r *ExpressionRule) Evaluate(ctx context.Context, signals *fraud.Signals) (fraud.RuleResult, error) {
+	return fraud.RuleResult{
+		Triggered:  r.Expression.Evaluate(Environment(signals)),
+		Weight:     r.Weight,
+		ReasonCode: r.ReasonCode,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	s3Scheme       = "s3://"
+	dynamoDBScheme = "dynamodb://"
+)
+
+// Source fetches the raw contents of a rule file. The version changes whenever the contents do,
+// so an unchanged file is not recompiled on every check.
+type Source interface {
+	Fetch(ctx context.Context) (data []byte, version string, err error)
+	String() string
+}
+
+// ObjectGetter is the part of the S3 client used to download rule files, so any S3-compatible store works.
+type ObjectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// ConfigGetter reads config items, implemented by db.ConfigRepository
+type ConfigGetter interface {
+	GetConfigItem(ctx context.Context, configID string) (*models.ConfigItem, error)
+}
+
+// NewSource picks a Source from the path: s3://bucket/key, dynamodb://ConfigID or a local file path.
+func NewSource(path string, objects ObjectGetter, configs ConfigGetter) (Source, error) {
+	switch {
+	case strings.HasPrefix(path, s3Scheme):
+		bucket, key, ok := strings.Cut(strings.TrimPrefix(path, s3Scheme), "/")
+		if !ok || bucket == "" || key == "" {
+			return nil, fmt.Errorf("invalid rule file path %s, expected %sbucket/key", path, s3Scheme)
+		}
+		return &S3Source{Objects: objects, Bucket: bucket, Key: key}, nil
+	case strings.HasPrefix(path, dynamoDBScheme):
+		configID := strings.TrimPrefix(path, dynamoDBScheme)
+		if configID == "" {
+			return nil, fmt.Errorf("invalid rule file path %s, expected %sConfigID", path, dynamoDBScheme)
+		}
+		return &ConfigItemSource{Configs: configs, ConfigID: configID}, nil
+	default:
+		return &FileSource{Path: path}, nil
+	}
+}
+
+type FileSource struct {
+	Path string
+}
+
+func (s *FileSource) Fetch(ctx context.Context) ([]byte, string, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentVersion(data), nil
+}
+
+func (s *FileSource) String() string {
+	return s.Path
+}
+
+type S3Source struct {
+	Objects ObjectGetter
+	Bucket  string
+	Key     string
+}
+
+func (s *S3Source) Fetch(ctx context.Context) ([]byte, string, error) {
+	output, err := s.Objects.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentVersion(data), nil
+}
+
+func (s *S3Source) String() string {
+	return s3Scheme + s.Bucket + "/" + s.Key
+}
+
+// ConfigItemSource reads the rule file from the Content of a config item.
+type ConfigItemSource struct {
+	Configs  ConfigGetter
+	ConfigID string
+}
+
+func (s *ConfigItemSource) Fetch(ctx context.Context) ([]byte, string, error) {
+	item, err := s.Configs.GetConfigItem(ctx, s.ConfigID)
+	if err != nil {
+		return nil, "", err
+	}
+	data := []byte(item.Content)
+	return data, contentVersion(data), nil
+}
+
+func (s *ConfigItemSource) String() string {
+	return dynamoDBScheme + s.ConfigID
+}
+
+func contentVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
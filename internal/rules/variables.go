@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/dsl"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+)
+
+// Variables lists everything a rule expression can reference. Features an enricher did not populate,
+// such as travel for a first transaction, evaluate to zero or false.
+var Variables = dsl.Variables{
+	"amount":           dsl.TypeNumber,
+	"login_attempts":   dsl.TypeNumber,
+	"duration":         dsl.TypeNumber,
+	"account_balance":  dsl.TypeNumber,
+	"customer_age":     dsl.TypeNumber,
+	"channel":          dsl.TypeString,
+	"transaction_type": dsl.TypeString,
+	"merchant_id":      dsl.TypeString,
+	"location":         dsl.TypeString,
+	"occupation":       dsl.TypeString,
+
+	"account.avg_amount":        dsl.TypeNumber,
+	"account.stddev_amount":     dsl.TypeNumber,
+	"account.amount_zscore":     dsl.TypeNumber,
+	"account.amount_percentile": dsl.TypeNumber,
+	"account.transaction_count": dsl.TypeNumber,
+	"account.cold_start":        dsl.TypeBool,
+
+	"velocity.count_5m":         dsl.TypeNumber,
+	"velocity.count_1h":         dsl.TypeNumber,
+	"velocity.count_24h":        dsl.TypeNumber,
+	"velocity.amount_5m":        dsl.TypeNumber,
+	"velocity.amount_1h":        dsl.TypeNumber,
+	"velocity.amount_24h":       dsl.TypeNumber,
+	"device_velocity.count_5m":  dsl.TypeNumber,
+	"device_velocity.count_1h":  dsl.TypeNumber,
+	"device_velocity.count_24h": dsl.TypeNumber,
+
+	"device.has_history":     dsl.TypeBool,
+	"device.is_new":          dsl.TypeBool,
+	"device.ip_is_new":       dsl.TypeBool,
+	"device.last_seen_hours": dsl.TypeNumber,
+
+	"travel.distance_km":     dsl.TypeNumber,
+	"travel.speed_kmh":       dsl.TypeNumber,
+	"travel.elapsed_minutes": dsl.TypeNumber,
+}
+
+// Environment exposes a transaction's signals under the names in Variables
+func Environment(signals *fraud.Signals) dsl.Env {
+	txn := signals.Transaction
+	env := dsl.Env{
+		"amount":           txn.TransactionAmount,
+		"login_attempts":   float64(txn.LoginAttempts),
+		"duration":         float64(txn.TransactionDuration),
+		"account_balance":  txn.AccountBalance,
+		"customer_age":     float64(txn.CustomerAge),
+		"channel":          txn.Channel,
+		"transaction_type": txn.TransactionType,
+		"merchant_id":      txn.MerchantID,
+		"location":         txn.Location,
+		"occupation":       txn.CustomerOccupation,
+	}
+
+	if baseline := signals.Baseline; baseline != nil {
+		env["account.avg_amount"] = baseline.Mean
+		env["account.stddev_amount"] = baseline.StdDev
+		env["account.amount_zscore"] = baseline.ZScore
+		env["account.amount_percentile"] = baseline.Percentile
+		env["account.transaction_count"] = float64(baseline.SampleCount)
+		env["account.cold_start"] = baseline.ColdStart
+	}
+
+	if velocity := signals.Velocity; velocity != nil {
+		for window, suffix := range map[time.Duration]string{5 * time.Minute: "5m", time.Hour: "1h", 24 * time.Hour: "24h"} {
+			env["velocity.count_"+suffix] = float64(velocity.Account[window].Count)
+			env["velocity.amount_"+suffix] = velocity.Account[window].Amount
+			env["device_velocity.count_"+suffix] = float64(velocity.Device[window].Count)
+		}
+	}
+
+	if device := signals.Device; device != nil {
+		env["device.has_history"] = device.HasHistory
+		env["device.is_new"] = device.HasHistory && device.IsNewDevice
+		env["device.ip_is_new"] = device.HasHistory && device.IsNewIP
+		env["device.last_seen_hours"] = device.DeviceLastSeenAgo.Hours()
+	}
+
+	if travel := signals.Travel; travel != nil {
+		env["travel.distance_km"] = travel.DistanceKm
+		env["travel.speed_kmh"] = travel.SpeedKmh
+		env["travel.elapsed_minutes"] = travel.Elapsed.Minutes()
+	}
+
+	return env
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+type DigestService interface {
+	FlushDigests(ctx context.Context, now time.Time) (*DigestSummary, error)
+}
+
+// DigestSummary counts the alerts sent in one run and the windows that failed
+type DigestSummary struct {
+	Alerts       int `json:"alerts"`
+	Transactions int `json:"transactions"`
+	Failed       int `json:"failed"`
+}
+
+// GfDigestService sends the alerts held in recipients' windows once the windows end. Each account's held
+// transactions are sent as one digest with one reply code, or as a plain alert when only one is held.
+type GfDigestService struct {
+	EventDispatcher  events.EventDispatcher
+	TransactionRepo  db.TransactionRepository
+	ConversationRepo db.ConversationRepository
+	AlertLimits      db.AlertLimitRepository
+}
+
+func NewDigestService(dispatcher events.EventDispatcher, repo db.TransactionRepository, conversationRepo db.ConversationRepository, alertLimits db.AlertLimitRepository) *GfDigestService {
+	return &GfDigestService{
+		EventDispatcher:  dispatcher,
+		TransactionRepo:  repo,
+		ConversationRepo: conversationRepo,
+		AlertLimits:      alertLimits,
+	}
+}
+
+// FlushDigests sends every digest that is due. Alerts that fail stay held and are retried on the next run.
+func (ds *GfDigestService) FlushDigests(ctx context.Context, now time.Time) (*DigestSummary, error) {
+	windows, err := ds.AlertLimits.GetDueDigests(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	errorResults := make(chan error, len(windows))
+	sent := make(chan [][]models.Transaction, len(windows))
+	for _, window := range windows {
+		wg.Add(1)
+		go func(window models.AlertWindow) {
+			defer wg.Done()
+			alerts, err := ds.flush(ctx, window, now)
+			if err != nil {
+				errorResults <- fmt.Errorf("failed to send digest of window %s: %w", window.LimitKey, err)
+			}
+			sent <- alerts
+		}(window)
+	}
+	wg.Wait()
+	close(errorResults)
+	close(sent)
+
+	summary := &DigestSummary{Failed: len(errorResults)}
+	for alerts := range sent {
+		summary.Alerts += len(alerts)
+		for _, transactions := range alerts {
+			summary.Transactions += len(transactions)
+		}
+	}
+	return summary, middleware.MergeErrors(errorResults)
+}
+
+// flush sends one alert per account for the transactions held in a window and returns the transactions of each
+// alert sent. Held transactions no longer POTENTIAL_FRAUD were resolved some other way and are dropped.
+func (ds *GfDigestService) flush(ctx context.Context, window models.AlertWindow, now time.Time) ([][]models.Transaction, error) {
+	var done []string
+	var failures []error
+	byAccount := make(map[string][]models.Transaction)
+	for _, alert := range window.HeldAlerts() {
+		txn, err := ds.TransactionRepo.GetTransaction(ctx, alert.AccountID, alert.TransactionID)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to get held transaction %s: %w", alert.TransactionID, err))
+			continue
+		}
+		if txn.TransactionStatus != "POTENTIAL_FRAUD" {
+			done = append(done, txn.TransactionID)
+			continue
+		}
+		byAccount[txn.AccountID] = append(byAccount[txn.AccountID], *txn)
+	}
+
+	accounts := make([]string, 0, len(byAccount))
+	for accountID := range byAccount {
+		accounts = append(accounts, accountID)
+	}
+	sort.Strings(accounts)
+
+	var sent [][]models.Transaction
+	for _, accountID := range accounts {
+		transactions := byAccount[accountID]
+		alerted, err := ds.alert(ctx, transactions, now)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		if len(alerted) > 0 {
+			sent = append(sent, alerted)
+		}
+		for _, txn := range transactions {
+			done = append(done, txn.TransactionID)
+		}
+	}
+
+	if err := ds.AlertLimits.CompleteDigest(ctx, window.LimitKey, done); err != nil {
+		failures = append(failures, err)
+	}
+	return sent, errors.Join(failures...)
+}
+
+// alert opens the conversation for an account's held transactions, sends the alert about them and records the
+// reply code and channel on each. Only those two fields are written, so an answer the customer gave since the
+// transactions were read is kept, and transactions answered before their reply code was recorded are left out of
+// the alert. It returns the transactions alerted on. A retried alert reuses its conversation, so its message is the
+// same and the dispatcher does not send it twice.
+func (ds *GfDigestService) alert(ctx context.Context, transactions []models.Transaction, now time.Time) ([]models.Transaction, error) {
+	conversation := models.NewDigestConversation(transactions, "", now, config.ConversationConfig.TTL)
+	if len(transactions) == 1 {
+		conversation = models.NewConversation(transactions[0], "", now, config.ConversationConfig.TTL)
+	}
+	replyCode, err := startConversation(ctx, ds.ConversationRepo, conversation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start conversation for alert %s: %w", conversation.AlertID, err)
+	}
+
+	var pending []models.Transaction
+	for _, txn := range transactions {
+		err := ds.TransactionRepo.UpdateReplyCode(ctx, txn.AccountID, txn.TransactionID, replyCode)
+		if errors.Is(err, db.ErrTransactionResolved) {
+			fmt.Printf("Transaction %s was resolved before its held alert was sent\n", txn.TransactionID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		txn.ReplyCode = replyCode
+		pending = append(pending, txn)
+	}
+
+	var channel string
+	switch len(pending) {
+	case 0:
+		return nil, nil
+	case 1:
+		channel, err = ds.EventDispatcher.DispatchFraudAlertEvent(pending[0])
+	default:
+		channel, err = ds.EventDispatcher.DispatchFraudDigestEvent(pending)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Alerts held back for quiet hours have no channel yet, and the deferred handler records it once they are sent
+	if channel == "" {
+		return pending, nil
+	}
+
+	var failures []error
+	for i := range pending {
+		pending[i].AlertChannel = channel
+		if err := ds.TransactionRepo.UpdateAlertChannel(ctx, pending[i].AccountID, pending[i].TransactionID, channel); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return pending, errors.Join(failures...)
+}
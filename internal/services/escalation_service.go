@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+type EscalationService interface {
+	EscalateAlerts(ctx context.Context, now time.Time) (*EscalationSummary, error)
+}
+
+// EscalationPolicy decides what to do about a fraud alert the customer has not answered, by how long ago it was
+// delivered. A zero duration skips that step.
+type EscalationPolicy struct {
+	ReminderAfter         time.Duration
+	SecondaryChannelAfter time.Duration
+	FinalActionAfter      time.Duration
+	FinalAction           string
+}
+
+// NewEscalationPolicy reads the policy from config.EscalationConfig
+func NewEscalationPolicy() (EscalationPolicy, error) {
+	policy := EscalationPolicy{
+		ReminderAfter:         config.EscalationConfig.ReminderAfter,
+		SecondaryChannelAfter: config.EscalationConfig.SecondaryChannelAfter,
+		FinalActionAfter:      config.EscalationConfig.FinalActionAfter,
+	}
+	switch strings.ToUpper(config.EscalationConfig.FinalAction) {
+	case "HOLD":
+		policy.FinalAction = models.EscalationHold
+	case "DECLINE":
+		policy.FinalAction = models.EscalationDecline
+	default:
+		return policy, fmt.Errorf("unknown escalation final action %q, expected HOLD or DECLINE", config.EscalationConfig.FinalAction)
+	}
+	return policy, nil
+}
+
+// Due returns the escalation step a conversation is due for when its alert was delivered at alertedAt, or an empty
+// string. Only the latest step due is returned, so an alert that was missed for a while goes straight to its final
+// action rather than being reminded.
+func (p EscalationPolicy) Due(conversation models.Conversation, alertedAt time.Time, now time.Time) string {
+	age := now.Sub(alertedAt)
+
+	step := ""
+	switch {
+	case conversation.IsExpired(now) || (p.FinalActionAfter > 0 && age >= p.FinalActionAfter):
+		step = p.FinalAction
+	case p.SecondaryChannelAfter > 0 && age >= p.SecondaryChannelAfter:
+		step = models.EscalationSecondaryChannel
+	case p.ReminderAfter > 0 && age >= p.ReminderAfter:
+		step = models.EscalationReminder
+	}
+
+	if models.EscalationRank(step) <= models.EscalationRank(conversation.Escalation) {
+		return ""
+	}
+	return step
+}
+
+// youngestDue is the age at which a conversation may first be due for a step
+func (p EscalationPolicy) youngestDue() time.Duration {
+	youngest := config.ConversationConfig.TTL
+	for _, after := range []time.Duration{p.ReminderAfter, p.SecondaryChannelAfter, p.FinalActionAfter} {
+		if after > 0 && after < youngest {
+			youngest = after
+		}
+	}
+	return youngest
+}
+
+// EscalationSummary counts the steps taken in one run
+type EscalationSummary struct {
+	Steps  map[string]int `json:"steps"`
+	Failed int            `json:"failed"`
+}
+
+type GfEscalationService struct {
+	EventDispatcher  events.EventDispatcher
+	TransactionRepo  db.TransactionRepository
+	ConversationRepo db.ConversationRepository
+	Policy           EscalationPolicy
+}
+
+func NewEscalationService(dispatcher events.EventDispatcher, repo db.TransactionRepository, conversationRepo db.ConversationRepository, policy EscalationPolicy) *GfEscalationService {
+	return &GfEscalationService{
+		EventDispatcher:  dispatcher,
+		TransactionRepo:  repo,
+		ConversationRepo: conversationRepo,
+		Policy:           policy,
+	}
+}
+
+// EscalateAlerts takes the step each unanswered alert is due for. Failed alerts are retried on the next run
+// since their conversations are still awaiting a reply.
+func (es *GfEscalationService) EscalateAlerts(ctx context.Context, now time.Time) (*EscalationSummary, error) {
+	conversations, err := es.ConversationRepo.GetAwaitingConversations(ctx, now.Add(-es.Policy.youngestDue()))
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	errorResults := make(chan error, len(conversations))
+	steps := make(chan string, len(conversations))
+	for _, conversation := range conversations {
+		// An alert is delivered no sooner than its conversation is started, so one not due counting from then is
+		// skipped without reading its transactions
+		if es.Policy.Due(conversation, time.Unix(conversation.CreatedAt, 0), now) == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(conversation models.Conversation) {
+			defer wg.Done()
+			step, err := es.escalate(ctx, &conversation, now)
+			if err != nil {
+				errorResults <- fmt.Errorf("failed to escalate alert %s: %w", conversation.AlertID, err)
+				return
+			}
+			if step != "" {
+				steps <- step
+			}
+		}(conversation)
+	}
+	wg.Wait()
+	close(errorResults)
+	close(steps)
+
+	summary := &EscalationSummary{Steps: make(map[string]int), Failed: len(errorResults)}
+	for step := range steps {
+		summary.Steps[step]++
+	}
+	return summary, middleware.MergeErrors(errorResults)
+}
+
+// escalate takes the step one alert is due for, counting from when it was delivered, records it on the alert's
+// transactions and conversation and returns it. Alerts still held back for quiet hours are not escalated. Messages
+// are sent before anything is recorded so a failed send is retried by the next run. Transactions are only updated
+// while they are still POTENTIAL_FRAUD, so a customer who answers first keeps their answer.
+func (es *GfEscalationService) escalate(ctx context.Context, conversation *models.Conversation, now time.Time) (string, error) {
+	pending, err := es.pendingTransactions(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+
+	if len(pending) == 0 {
+		// Every transaction was resolved some other way, so there is nothing left to ask about
+		if err := conversation.Expire(now); err != nil {
+			return "", err
+		}
+		return "", es.saveConversation(ctx, conversation, "expiry")
+	}
+
+	alertedAt, delivered := alertedAt(pending[0], conversation)
+	if !delivered {
+		fmt.Printf("Alert %s is still held back, not escalating it yet\n", conversation.AlertID)
+		return "", nil
+	}
+	step := es.Policy.Due(*conversation, alertedAt, now)
+	if step == "" {
+		return "", nil
+	}
+
+	detail := ""
+	switch step {
+	case models.EscalationReminder:
+		reminder := models.NewTransactionNotification(models.NotificationReminder, pending[0])
+		err = es.EventDispatcher.DispatchFraudUpdateEvent(conversation.PhoneNumber, reminder)
+		detail = "SMS"
+	case models.EscalationSecondaryChannel:
+		var channel string
+		channel, err = es.EventDispatcher.DispatchFraudEscalationEvent(pending[0])
+		detail = strings.ToUpper(channel)
+	case models.EscalationDecline:
+		detail = models.StatusDeclined
+	case models.EscalationHold:
+		detail = models.StatusOnHold
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, txn := range pending {
+		if models.IsFinalEscalation(step) {
+			txn.TransactionStatus = detail
+		}
+		txn.Escalations = append(txn.Escalations, models.EscalationStep{Step: step, At: now.Unix(), Detail: detail})
+		err := es.TransactionRepo.UpdatePendingTransaction(ctx, txn.AccountID, txn.TransactionID, &txn)
+		if errors.Is(err, db.ErrTransactionResolved) {
+			fmt.Printf("Transaction %s was resolved while escalating to %s\n", txn.TransactionID, step)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to record escalation on transaction %s: %w", txn.TransactionID, err)
+		}
+	}
+
+	if err := conversation.Escalate(step, now); err != nil {
+		return "", err
+	}
+	return step, es.saveConversation(ctx, conversation, step)
+}
+
+// alertedAt is when a transaction's alert was delivered, and false while it is still held back. Alerts delivered
+// before delivery times were recorded count from when their conversation was started.
+func alertedAt(txn models.Transaction, conversation *models.Conversation) (time.Time, bool) {
+	switch {
+	case txn.AlertedAt > 0:
+		return time.Unix(txn.AlertedAt, 0), true
+	case txn.AlertChannel != "":
+		return time.Unix(conversation.CreatedAt, 0), true
+	}
+	return time.Time{}, false
+}
+
+// saveConversation stores an escalated conversation unless a reply resolved it first
+func (es *GfEscalationService) saveConversation(ctx context.Context, conversation *models.Conversation, step string) error {
+	err := es.ConversationRepo.UpdateConversation(ctx, conversation, models.ConversationAwaitingReply)
+	if errors.Is(err, db.ErrConversationChanged) {
+		fmt.Printf("Alert %s was answered while escalating to %s\n", conversation.AlertID, step)
+		return nil
+	}
+	return err
+}
+
+// pendingTransactions returns the conversation's transactions still awaiting the customer
+func (es *GfEscalationService) pendingTransactions(ctx context.Context, conversation *models.Conversation) ([]models.Transaction, error) {
+	var pending []models.Transaction
+	for _, transactionID := range conversation.TransactionIDs {
+		txn, err := es.TransactionRepo.GetTransaction(ctx, conversation.AccountID, transactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s for alert %s: %w", transactionID, conversation.AlertID, err)
+		}
+		if txn.TransactionStatus == "POTENTIAL_FRAUD" {
+			pending = append(pending, *txn)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/ml"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/rules"
+)
+
+// ConfigureDetectors replaces the built-in rules with the model or rule file set in config.ModelConfig and
+// config.RulesConfig, and registers any configured challengers. Models and rule files are read from local disk,
+// S3 through objects or, for rule files, the config table through configs.
+func (fs *GfFraudService) ConfigureDetectors(ctx context.Context, objects ml.ObjectGetter, configs rules.ConfigGetter) error {
+	if config.ModelConfig.Path != "" && config.RulesConfig.Path != "" {
+		return fmt.Errorf("only one of a fraud model or a rule file can be the live detector")
+	}
+
+	if config.ModelConfig.Path != "" {
+		detector, err := loadModelDetector(ctx, config.ModelConfig.Path, objects)
+		if err != nil {
+			return err
+		}
+		fs.Detector = detector
+	}
+	if config.RulesConfig.Path != "" {
+		detector, err := fs.loadRuleFileDetector(ctx, config.RulesConfig.Path, objects, configs)
+		if err != nil {
+			return err
+		}
+		fs.Detector = detector
+	}
+
+	if config.ModelConfig.ChallengerPath != "" {
+		detector, err := loadModelDetector(ctx, config.ModelConfig.ChallengerPath, objects)
+		if err != nil {
+			return err
+		}
+		fs.AddChallenger(config.ModelConfig.ChallengerPath, detector)
+	}
+	if config.RulesConfig.ChallengerPath != "" {
+		detector, err := fs.loadRuleFileDetector(ctx, config.RulesConfig.ChallengerPath, objects, configs)
+		if err != nil {
+			return err
+		}
+		fs.AddChallenger(config.RulesConfig.ChallengerPath, detector)
+	}
+
+	return nil
+}
+
+func loadModelDetector(ctx context.Context, path string, objects ml.ObjectGetter) (fraud.Detector, error) {
+	model, err := ml.LoadModel(ctx, path, objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fraud model: %w", err)
+	}
+	return fraud.NewModelDetector(model, config.ModelConfig.Threshold), nil
+}
+
+// loadRuleFileDetector evaluates the rule file with the same enrichers as the built-in rules so every feature is available
+func (fs *GfFraudService) loadRuleFileDetector(ctx context.Context, path string, objects ml.ObjectGetter, configs rules.ConfigGetter) (fraud.Detector, error) {
+	source, err := rules.NewSource(path, objects, configs)
+	if err != nil {
+		return nil, err
+	}
+	return rules.NewReloadingDetector(
+		ctx,
+		source,
+		config.RulesConfig.ReloadInterval,
+		config.FraudConfig.ScoreThreshold,
+		fraud.DefaultEnrichers(fs.TransactionRepo, fs.ProfileRepo)...,
+	)
+}
@@ -2,33 +2,71 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"time"
 
-	"slices"
-
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
-	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/observability"
 )
 
 type FraudService interface {
 	PredictFraud(ctx context.Context, transactions []models.Transaction) ([]models.Transaction, []models.Transaction, error)
+	ChallengerStats() map[string]fraud.ChallengerStats
 }
 
+// GfFraudService scores transactions with the champion Detector. Challengers are evaluated in shadow and their
+// decisions stored on the transaction, but only the champion drives alerts and status updates. Their disagreement
+// with the champion is written to MetricsOutput as CloudWatch metrics.
+// Allowlist and denylist entries are checked first and, when one matches, decide the transaction without scoring.
+// When AlertLimits is set, fraud flagged shortly after an alert to the same recipient is held for a digest instead
+// of alerted on its own. Alerts are not sent here but recorded in Outbox together with the transaction's new status,
+// and the outbox relay sends them.
 type GfFraudService struct {
-	EventDispatcher events.EventDispatcher
-	TransactionRepo db.TransactionRepository
+	TransactionRepo   db.TransactionRepository
+	ProfileRepo       db.AccountProfileRepository
+	ListRepo          db.ListRepository
+	ConversationRepo  db.ConversationRepository
+	AlertLimits       db.AlertLimitRepository
+	Outbox            db.OutboxRepository
+	Detector          fraud.Detector
+	Challengers       []fraud.Challenger
+	ChallengerMetrics *fraud.ChallengerMetrics
+	MetricsOutput     io.Writer
 }
 
-func NewFraudService(dispatcher events.EventDispatcher, repo db.TransactionRepository) *GfFraudService {
+func NewFraudService(repo db.TransactionRepository, profileRepo db.AccountProfileRepository, listRepo db.ListRepository, conversationRepo db.ConversationRepository, alertLimits db.AlertLimitRepository, outbox db.OutboxRepository) *GfFraudService {
 	return &GfFraudService{
-		EventDispatcher: dispatcher,
-		TransactionRepo: repo,
+		TransactionRepo:   repo,
+		ProfileRepo:       profileRepo,
+		ListRepo:          listRepo,
+		ConversationRepo:  conversationRepo,
+		AlertLimits:       alertLimits,
+		Outbox:            outbox,
+		Detector:          fraud.NewDefaultRuleEngine(repo, profileRepo),
+		ChallengerMetrics: fraud.NewChallengerMetrics(),
+		MetricsOutput:     os.Stdout,
 	}
 }
 
+// AddChallenger registers a detector to run in shadow next to the champion
+func (fs *GfFraudService) AddChallenger(name string, detector fraud.Detector) {
+	fs.Challengers = append(fs.Challengers, fraud.NewChallenger(name, detector))
+}
+
+// ChallengerStats returns how often each challenger has disagreed with the champion since this container started.
+// The same counts are emitted as CloudWatch metrics, which are the ones to compare challengers on.
+func (fs *GfFraudService) ChallengerStats() map[string]fraud.ChallengerStats {
+	return fs.ChallengerMetrics.Snapshot()
+}
+
 func (fs *GfFraudService) PredictFraud(ctx context.Context, transactions []models.Transaction) ([]models.Transaction, []models.Transaction, error) {
 	var wg sync.WaitGroup
 	errorResults := make(chan error, len(transactions))
@@ -39,47 +77,58 @@ func (fs *GfFraudService) PredictFraud(ctx context.Context, transactions []model
 		wg.Add(1)
 		go func(txn models.Transaction) {
 			defer wg.Done()
-			isFraud, err := predictFraud(txn)
+			decision, err := fs.decide(ctx, &txn)
 			if err != nil {
 				errorResults <- err
 				failedTransactions <- txn
 				return
 			}
 
-			if isFraud {
+			txn.RiskScore = decision.Score
+			txn.ReasonCodes = decision.ReasonCodes
+
+			if decision.IsFraud && fraud.SuppressesAlerts(txn.ListMatch) {
+				// The decision is recorded, but no conversation is opened and no alert is sent
+				txn.TransactionStatus = "POTENTIAL_FRAUD"
 				fraudulentTransactions <- txn
-				err := fs.EventDispatcher.DispatchFraudAlertEvent(txn)
+				if _, err := fs.TransactionRepo.UpdateTransaction(ctx, txn.AccountID, txn.TransactionID, &txn); err != nil {
+					errorResults <- wrapPredictionError(txn, err)
+					failedTransactions <- txn
+				}
+			} else if decision.IsFraud {
+				now := time.Now()
+				admission, err := fs.admitAlert(ctx, txn, now)
 				if err != nil {
-					wrappedErr := fmt.Errorf("fraud prediction failed for transaction %s (account: %s, amount: %.2f, merchant: %s, email: %s): %w",
-						txn.TransactionID,
-						txn.AccountID,
-						txn.TransactionAmount,
-						txn.MerchantID,
-						txn.Email,
-						err)
-					errorResults <- wrappedErr
+					fraudulentTransactions <- txn
+					errorResults <- wrapPredictionError(txn, err)
 					failedTransactions <- txn
 					return
-				} else {
+				}
+				if admission == models.AlertHeld {
+					// The digest of the recipient's window alerts on it later
 					txn.TransactionStatus = "POTENTIAL_FRAUD"
-					_, err := fs.TransactionRepo.UpdateTransaction(
-						ctx,
-						txn.AccountID,
-						txn.TransactionID,
-						&txn,
-					)
-					if err != nil {
-						wrappedErr := fmt.Errorf("fraud prediction failed for transaction %s (account: %s, amount: %.2f, merchant: %s, email: %s): %w",
-							txn.TransactionID,
-							txn.AccountID,
-							txn.TransactionAmount,
-							txn.MerchantID,
-							txn.Email,
-							err)
-						errorResults <- wrappedErr
+					fraudulentTransactions <- txn
+					if _, err := fs.TransactionRepo.UpdateTransaction(ctx, txn.AccountID, txn.TransactionID, &txn); err != nil {
+						errorResults <- wrapPredictionError(txn, err)
 						failedTransactions <- txn
-						return
 					}
+					return
+				}
+
+				// The conversation exists before the alert goes out so an immediate reply can be matched to it
+				replyCode, err := startConversation(ctx, fs.ConversationRepo, models.NewConversation(txn, "", now, config.ConversationConfig.TTL))
+				if err != nil {
+					fraudulentTransactions <- txn
+					errorResults <- wrapPredictionError(txn, err)
+					failedTransactions <- txn
+					return
+				}
+				txn.ReplyCode = replyCode
+				fraudulentTransactions <- txn
+
+				if err := fs.recordAlert(ctx, txn, now); err != nil {
+					errorResults <- wrapPredictionError(txn, err)
+					failedTransactions <- txn
 				}
 			} else {
 				txn.TransactionStatus = "APPROVED"
@@ -90,17 +139,14 @@ func (fs *GfFraudService) PredictFraud(ctx context.Context, transactions []model
 					&txn,
 				)
 				if err != nil {
-					wrappedErr := fmt.Errorf("fraud prediction failed for transaction %s (account: %s, amount: %.2f, merchant: %s, email: %s): %w",
-						txn.TransactionID,
-						txn.AccountID,
-						txn.TransactionAmount,
-						txn.MerchantID,
-						txn.Email,
-						err)
-					errorResults <- wrappedErr
+					errorResults <- wrapPredictionError(txn, err)
 					failedTransactions <- txn
 					return
 				}
+
+				// Approved transactions teach the profile which devices and IPs belong to the account and how it spends.
+				// Retrying the transaction would count it in the baselines again, so failures are only logged.
+				recordSightings(ctx, fs.ProfileRepo, txn)
 			}
 		}(txn)
 	}
@@ -112,7 +158,113 @@ func (fs *GfFraudService) PredictFraud(ctx context.Context, transactions []model
 	return channelToSlice(fraudulentTransactions), channelToSlice(failedTransactions), middleware.MergeErrors(errorResults)
 }
 
-// Placeholder for fraud prediction, to be replaced with prediction algorithm
-func predictFraud(transaction models.Transaction) (bool, error) {
-	return slices.Contains([]string{"rshart@wisc.edu", "jpoconnell4@wisc.edu", "c1redflagstest@gmail.com", "wlee298@wisc.edu", "donglaiduann@gmail.com"}, transaction.Email), nil
+// admitAlert decides whether a transaction's alert is sent now or held for its recipient's digest. Every alert is
+// sent now when limiting is off or the transaction has no address to limit by.
+func (fs *GfFraudService) admitAlert(ctx context.Context, txn models.Transaction, now time.Time) (string, error) {
+	limitKey := models.AlertWindowKey(txn)
+	if fs.AlertLimits == nil || config.AlertLimitConfig.Window <= 0 || limitKey == "" {
+		return models.AlertSendNow, nil
+	}
+
+	alert := models.HeldAlert{TransactionID: txn.TransactionID, AccountID: txn.AccountID, HeldAt: now.Unix()}
+	return fs.AlertLimits.AdmitAlert(ctx, limitKey, alert, now, config.AlertLimitConfig.Window)
+}
+
+// recordAlert flags a transaction as POTENTIAL_FRAUD and records its alert for the relay in one write. A retried
+// transaction whose earlier write went through is already flagged, and its alert is not recorded again.
+func (fs *GfFraudService) recordAlert(ctx context.Context, txn models.Transaction, now time.Time) error {
+	txn.TransactionStatus = "POTENTIAL_FRAUD"
+	err := fs.Outbox.FlagTransaction(ctx, &txn, models.NewAlertOutboxNotification(txn, now))
+	if errors.Is(err, db.ErrNotificationRecorded) {
+		fmt.Printf("Alert for transaction %s was already recorded\n", txn.TransactionID)
+		return nil
+	}
+	return err
+}
+
+// startConversation opens a conversation for an alert and returns its reply code, which is unique among the phone
+// number's open alerts. A retried alert keeps the code of the conversation it already has.
+func startConversation(ctx context.Context, repository db.ConversationRepository, conversation *models.Conversation) (string, error) {
+	open, err := repository.GetOpenConversations(ctx, conversation.PhoneNumber)
+	if err != nil {
+		return "", err
+	}
+
+	inUse := make(map[string]bool, len(open))
+	for _, existing := range open {
+		if existing.AlertID == conversation.AlertID {
+			return existing.ReplyCode, nil
+		}
+		inUse[existing.ReplyCode] = true
+	}
+
+	conversation.ReplyCode = models.NewReplyCode(conversation.AlertID, inUse)
+	if _, err := repository.StartConversation(ctx, conversation); err != nil {
+		return "", err
+	}
+	return conversation.ReplyCode, nil
+}
+
+// decide returns the matching list entry's forced decision, or otherwise scores the transaction with the champion
+// while challengers run in shadow. An entry that only suppresses alerts is recorded and the transaction is scored.
+// A failed list lookup fails the transaction rather than scoring a possibly denylisted one.
+func (fs *GfFraudService) decide(ctx context.Context, txn *models.Transaction) (*fraud.Decision, error) {
+	entry, err := fraud.MatchList(ctx, fs.ListRepo, *txn, time.Now())
+	if err != nil {
+		return nil, wrapPredictionError(*txn, err)
+	}
+	if entry != nil {
+		txn.ListMatch = entry.Match()
+		if !fraud.SuppressesAlerts(txn.ListMatch) {
+			return fraud.ListDecision(entry), nil
+		}
+	}
+
+	// Challengers still running when the champion fails are cancelled rather than left behind
+	challengerCtx, cancelChallengers := context.WithCancel(ctx)
+	defer cancelChallengers()
+	waitForChallengers := fraud.StartChallengers(challengerCtx, fs.Challengers, *txn)
+	decision, err := fs.Detector.Evaluate(ctx, *txn)
+	if err != nil {
+		return nil, err
+	}
+
+	txn.ShadowDecisions = waitForChallengers(decision)
+	for _, shadow := range txn.ShadowDecisions {
+		fs.ChallengerMetrics.Record(shadow)
+		if err := observability.WriteChallengerMetrics(fs.MetricsOutput, config.MetricsConfig.Namespace, shadow, time.Now()); err != nil {
+			fmt.Printf("Error writing metrics for challenger %s: %s\n", shadow.Challenger, err)
+		}
+	}
+	return decision, nil
+}
+
+func wrapPredictionError(txn models.Transaction, err error) error {
+	return fmt.Errorf("fraud prediction failed for transaction %s (account: %s, amount: %.2f, merchant: %s, email: %s): %w",
+		txn.TransactionID,
+		txn.AccountID,
+		txn.TransactionAmount,
+		txn.MerchantID,
+		txn.Email,
+		err)
+}
+
+// recordSightings marks a transaction's device as known for its account and folds its amount into the account's
+// spending baseline, logging failures
+func recordSightings(ctx context.Context, profiles db.AccountProfileRepository, txn models.Transaction) {
+	if err := profiles.RecordDeviceSighting(ctx, txn, sightingTime(txn)); err != nil {
+		fmt.Printf("Error recording device sighting for transaction %s: %s\n", txn.TransactionID, err)
+	}
+	if err := profiles.RecordSpending(ctx, txn); err != nil {
+		fmt.Printf("Error recording spending for transaction %s: %s\n", txn.TransactionID, err)
+	}
+}
+
+// sightingTime is when a transaction's device was seen, falling back to now for transactions without a usable time
+func sightingTime(txn models.Transaction) time.Time {
+	transactionTime := txn.GetTransactionTime()
+	if transactionTime.IsZero() {
+		return time.Now().UTC()
+	}
+	return transactionTime
 }
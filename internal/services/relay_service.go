@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+)
+
+type RelayService interface {
+	RelayNotifications(ctx context.Context, outboxIDs []string) ([]string, error)
+	RelayPendingNotifications(ctx context.Context, now time.Time) (*RelaySummary, error)
+}
+
+// RelaySummary counts the outbox notifications sent in one sweep, the ones given up on as undeliverable and the ones
+// that failed
+type RelaySummary struct {
+	Sent          int `json:"sent"`
+	Undeliverable int `json:"undeliverable"`
+	Failed        int `json:"failed"`
+}
+
+// GfRelayService sends the notifications recorded in the outbox and marks them sent. A notification sent again
+// because marking it failed is the same message, so the dispatcher does not deliver it twice.
+type GfRelayService struct {
+	EventDispatcher events.EventDispatcher
+	TransactionRepo db.TransactionRepository
+	Outbox          db.OutboxRepository
+}
+
+func NewRelayService(dispatcher events.EventDispatcher, repo db.TransactionRepository, outbox db.OutboxRepository) *GfRelayService {
+	return &GfRelayService{
+		EventDispatcher: dispatcher,
+		TransactionRepo: repo,
+		Outbox:          outbox,
+	}
+}
+
+// RelayNotifications sends the outbox notifications with the given IDs and returns the IDs of the ones that failed
+func (rs *GfRelayService) RelayNotifications(ctx context.Context, outboxIDs []string) ([]string, error) {
+	var notifications []models.OutboxNotification
+	var failed []string
+	var failures []error
+	for _, outboxID := range outboxIDs {
+		notification, err := rs.Outbox.GetNotification(ctx, outboxID)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to get outbox notification %s: %w", outboxID, err))
+			failed = append(failed, outboxID)
+			continue
+		}
+		notifications = append(notifications, *notification)
+	}
+
+	_, _, relayFailed, err := rs.relayAll(ctx, notifications, time.Now())
+	return append(failed, relayFailed...), errors.Join(append(failures, err)...)
+}
+
+// RelayPendingNotifications sends the notifications still pending config.OutboxConfig.SweepAfter after they were
+// written, which relaying them as they were written did not.
+func (rs *GfRelayService) RelayPendingNotifications(ctx context.Context, now time.Time) (*RelaySummary, error) {
+	pending, err := rs.Outbox.GetPendingNotifications(ctx, now.Add(-config.OutboxConfig.SweepAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	sent, undeliverable, failed, err := rs.relayAll(ctx, pending, now)
+	return &RelaySummary{Sent: sent, Undeliverable: undeliverable, Failed: len(failed)}, err
+}
+
+// relayAll relays notifications concurrently and returns how many were sent, how many were given up on and the IDs
+// of the ones that failed
+func (rs *GfRelayService) relayAll(ctx context.Context, notifications []models.OutboxNotification, now time.Time) (int, int, []string, error) {
+	var wg sync.WaitGroup
+	errorResults := make(chan error, len(notifications))
+	failedIDs := make(chan string, len(notifications))
+	undeliverableIDs := make(chan string, len(notifications))
+	for _, notification := range notifications {
+		wg.Add(1)
+		go func(notification models.OutboxNotification) {
+			defer wg.Done()
+			undeliverable, err := rs.relay(ctx, notification, now)
+			if err != nil {
+				errorResults <- fmt.Errorf("failed to relay outbox notification %s: %w", notification.OutboxID, err)
+				failedIDs <- notification.OutboxID
+				return
+			}
+			if undeliverable {
+				undeliverableIDs <- notification.OutboxID
+			}
+		}(notification)
+	}
+	wg.Wait()
+	close(errorResults)
+	close(failedIDs)
+	close(undeliverableIDs)
+
+	var failed []string
+	for outboxID := range failedIDs {
+		failed = append(failed, outboxID)
+	}
+	undeliverable := len(undeliverableIDs)
+	return len(notifications) - len(failed) - undeliverable, undeliverable, failed, middleware.MergeErrors(errorResults)
+}
+
+// relay sends the alert a pending notification records, stores the channel it went out on and marks it sent.
+// Transactions resolved before their alert went out are not alerted on. The transaction is flagged in the same
+// write that recorded the notification, so any other status means the read is behind and the notification is
+// retried. An alert that no channel can deliver, or that still fails config.OutboxConfig.GiveUpAfter it was written,
+// is marked undeliverable rather than retried, and true is returned.
+func (rs *GfRelayService) relay(ctx context.Context, notification models.OutboxNotification, now time.Time) (bool, error) {
+	if !notification.IsPending() {
+		return false, nil
+	}
+	if notification.Type != models.NotificationFraudAlert {
+		return false, fmt.Errorf("unknown outbox notification type %q", notification.Type)
+	}
+
+	txn, err := rs.TransactionRepo.GetTransaction(ctx, notification.AccountID, notification.TransactionID)
+	if err != nil {
+		return false, err
+	}
+	if models.IsResolvedStatus(txn.TransactionStatus) {
+		fmt.Printf("Transaction %s was resolved as %s before its alert was sent\n", txn.TransactionID, txn.TransactionStatus)
+		return false, rs.Outbox.MarkSent(ctx, notification.OutboxID, "", now)
+	}
+	if txn.TransactionStatus != "POTENTIAL_FRAUD" {
+		return false, fmt.Errorf("transaction %s is %q, not yet flagged for its alert", txn.TransactionID, txn.TransactionStatus)
+	}
+
+	channel, err := rs.EventDispatcher.DispatchFraudAlertEvent(*txn)
+	if errors.Is(err, events.ErrUndeliverable) && givesUp(notification, err, now) {
+		fmt.Printf("Giving up on alert for transaction %s: %s\n", txn.TransactionID, err)
+		return true, rs.Outbox.MarkUndeliverable(ctx, notification.OutboxID, err.Error(), now)
+	}
+	if err != nil {
+		return false, err
+	}
+	// Alerts held back for quiet hours have no channel yet, and the deferred handler records it once they are sent
+	if channel != "" {
+		if err := rs.TransactionRepo.UpdateAlertChannel(ctx, txn.AccountID, txn.TransactionID, channel); err != nil {
+			return false, err
+		}
+	}
+	return false, rs.Outbox.MarkSent(ctx, notification.OutboxID, channel, now)
+}
+
+// givesUp reports whether an alert that could not be delivered should stop being retried
+func givesUp(notification models.OutboxNotification, err error, now time.Time) bool {
+	return errors.Is(err, events.ErrUnreachable) || !now.Before(time.Unix(notification.CreatedAt, 0).Add(config.OutboxConfig.GiveUpAfter))
+}
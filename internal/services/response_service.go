@@ -2,13 +2,20 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/middleware"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/replies"
 )
 
 type ResponseService interface {
@@ -16,21 +23,26 @@ type ResponseService interface {
 }
 
 type GfResponseService struct {
-	EventDispatcher events.EventDispatcher
-	TransactionRepo db.TransactionRepository
+	EventDispatcher  events.EventDispatcher
+	TransactionRepo  db.TransactionRepository
+	ProfileRepo      db.AccountProfileRepository
+	ConversationRepo db.ConversationRepository
+	OptOutRepo       db.OptOutRepository
+	ProcessedReplies db.ProcessedReplyRepository
+	Classifier       *replies.Classifier
+	MinConfidence    replies.Confidence
 }
 
-const (
-	ResponseFraudConfirmed  = "Thank you for your response. We have canceled this transaction. Your balance will be updated accordingly."
-	ResponseFraudRejected   = "Thank you for your response. We have updated this transaction status to valid. Your balance will be updated accordingly."
-	ResponseInvalidResponse = "If texted about fraud, please reply YES if this was you or NO if it was not. Otherwise do not text this number."
-	ResponseUnknown         = "Please do not text this number unless prompted"
-)
-
-func NewGfResponseService(dispatcher events.EventDispatcher, repo db.TransactionRepository) *GfResponseService {
+func NewGfResponseService(dispatcher events.EventDispatcher, repo db.TransactionRepository, profileRepo db.AccountProfileRepository, conversationRepo db.ConversationRepository, optOutRepo db.OptOutRepository, processedReplies db.ProcessedReplyRepository) *GfResponseService {
 	return &GfResponseService{
-		EventDispatcher: dispatcher,
-		TransactionRepo: repo,
+		EventDispatcher:  dispatcher,
+		TransactionRepo:  repo,
+		ProfileRepo:      profileRepo,
+		ConversationRepo: conversationRepo,
+		OptOutRepo:       optOutRepo,
+		ProcessedReplies: processedReplies,
+		Classifier:       replies.ConfiguredClassifier(),
+		MinConfidence:    minReplyConfidence(),
 	}
 }
 
@@ -42,58 +54,10 @@ func (rs *GfResponseService) RsUpdateTransaction(ctx context.Context, messages [
 		wg.Add(1)
 		go func(msg models.TwilioMessage) {
 			defer wg.Done()
-			if msg.ParseUserResponse() == "NO" {
-				count, err := rs.TransactionRepo.UpdateFraudTransaction(ctx, msg.From, true, "POTENTIAL_FRAUD")
-				if err != nil {
-					fmt.Printf("Error updating fraud transaction: %s", err)
-					failedMessages <- msg
-					errorResults <- err
-				}
-				if count == 0 {
-					err = rs.EventDispatcher.DispatchFraudUpdateEvent(msg.From, ResponseUnknown)
-					if err != nil {
-						fmt.Printf("Error dispatching fraud event: %s", err)
-						failedMessages <- msg
-						errorResults <- err
-					}
-				} else {
-					err = rs.EventDispatcher.DispatchFraudUpdateEvent(msg.From, ResponseFraudConfirmed)
-					if err != nil {
-						fmt.Printf("Error dispatching fraud event: %s", err)
-						failedMessages <- msg
-						errorResults <- err
-					}
-				}
-			} else if msg.ParseUserResponse() == "YES" {
-				count, err := rs.TransactionRepo.UpdateFraudTransaction(ctx, msg.From, false, "POTENTIAL_FRAUD")
-				if err != nil {
-					fmt.Printf("Error updating fraud transaction: %s", err)
-					failedMessages <- msg
-					errorResults <- err
-				}
-				if count == 0 {
-					err = rs.EventDispatcher.DispatchFraudUpdateEvent(msg.From, ResponseUnknown)
-					if err != nil {
-						fmt.Printf("Error dispatching fraud event: %s", err)
-						failedMessages <- msg
-						errorResults <- err
-					}
-				} else {
-					err = rs.EventDispatcher.DispatchFraudUpdateEvent(msg.From, ResponseFraudRejected)
-					if err != nil {
-						fmt.Printf("Error dispatching fraud event: %s", err)
-						failedMessages <- msg
-						errorResults <- err
-					}
-				}
-
-			} else {
-				err := rs.EventDispatcher.DispatchFraudUpdateEvent(msg.From, ResponseInvalidResponse)
-				if err != nil {
-					fmt.Printf("Error dispatching fraud event: %s", err)
-					failedMessages <- msg
-					errorResults <- err
-				}
+			if err := rs.processMessage(ctx, msg, time.Now()); err != nil {
+				fmt.Printf("Error handling reply from %s: %s", msg.From, err)
+				failedMessages <- msg
+				errorResults <- err
 			}
 		}(msg)
 	}
@@ -103,3 +67,290 @@ func (rs *GfResponseService) RsUpdateTransaction(ctx context.Context, messages [
 
 	return channelToSlice(failedMessages), middleware.MergeErrors(errorResults)
 }
+
+// processMessage handles a message at most once per MessageSid. A redelivered message that was already answered is
+// skipped, and one whose answer failed to send has that answer resent rather than being handled again. A message
+// that failed before it was answered is released and handled again in full when it is redelivered.
+func (rs *GfResponseService) processMessage(ctx context.Context, msg models.TwilioMessage, now time.Time) error {
+	if msg.MessageSid == "" {
+		answer, err := rs.handleReply(ctx, msg, now)
+		if err != nil {
+			return err
+		}
+		return rs.sendReply(msg.From, answer)
+	}
+
+	claim := models.NewProcessedReply(msg, now, config.ProcessedReplyConfig.TTL)
+	processed, claimed, err := rs.ProcessedReplies.ClaimReply(ctx, claim, now.Add(-config.ProcessedReplyConfig.Lease))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		switch processed.Status {
+		case models.ReplyProcessed:
+			fmt.Printf("Skipping redelivered reply %s from %s, already answered with %q\n", msg.MessageSid, msg.From, processed.Answer().Type)
+			return nil
+		case models.ReplyUnsent:
+			return rs.completeReply(ctx, processed, processed.Answer(), now)
+		default:
+			return fmt.Errorf("reply %s is already being processed", msg.MessageSid)
+		}
+	}
+
+	answer, err := rs.handleReply(ctx, msg, now)
+	if err != nil {
+		if releaseErr := rs.ProcessedReplies.ReleaseReply(ctx, msg.MessageSid); releaseErr != nil {
+			fmt.Printf("Error releasing reply %s: %s\n", msg.MessageSid, releaseErr)
+		}
+		return err
+	}
+	return rs.completeReply(ctx, processed, answer, now)
+}
+
+// completeReply sends the answer to a message and records it as the message's outcome
+func (rs *GfResponseService) completeReply(ctx context.Context, processed *models.ProcessedReply, outcome models.Notification, now time.Time) error {
+	sendErr := rs.sendReply(processed.PhoneNumber, outcome)
+	processed.Complete(outcome, sendErr == nil, now)
+	if err := rs.ProcessedReplies.CompleteReply(ctx, processed); err != nil {
+		return errors.Join(sendErr, err)
+	}
+	return sendErr
+}
+
+func (rs *GfResponseService) sendReply(phoneNumber string, reply models.Notification) error {
+	if reply.Type == "" {
+		return nil
+	}
+	if err := rs.EventDispatcher.DispatchFraudUpdateEvent(phoneNumber, reply); err != nil {
+		return fmt.Errorf("failed to dispatch fraud event: %w", err)
+	}
+	return nil
+}
+
+// handleReply applies a message to the conversation it answers and returns the answer to send back. An answer with
+// no Type is not sent. Carrier keywords are handled first. A reply code picks the alert; a bare YES or NO only
+// resolves one when it is the phone number's only open alert.
+func (rs *GfResponseService) handleReply(ctx context.Context, msg models.TwilioMessage, now time.Time) (models.Notification, error) {
+	reply, code, keyword, locale := rs.parseReply(msg)
+	if keyword != "" {
+		return rs.handleKeyword(ctx, msg.From, keyword, locale, now)
+	}
+
+	open, expiredCodes, err := rs.openConversations(ctx, msg.From, now)
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	if reply == "" {
+		rs.prompt(ctx, open, msg.ParseUserResponse(), now)
+		return replyNotification(models.NotificationInvalidResponse, locale, open), nil
+	}
+
+	var conversation *models.Conversation
+	switch {
+	case code != "":
+		for i := range open {
+			if open[i].ReplyCode == code {
+				conversation = &open[i]
+			}
+		}
+		if conversation == nil && expiredCodes[code] {
+			return replyNotification(models.NotificationConversationExpired, locale, nil), nil
+		}
+	case len(open) == 1:
+		conversation = &open[0]
+	}
+
+	if conversation == nil {
+		if len(open) > 0 {
+			rs.prompt(ctx, open, reply, now)
+			return disambiguationPrompt(locale, open), nil
+		}
+		if len(expiredCodes) > 0 {
+			return replyNotification(models.NotificationConversationExpired, locale, nil), nil
+		}
+		return replyNotification(models.NotificationUnknown, locale, nil), nil
+	}
+
+	answered := []models.Conversation{*conversation}
+	resolved, err := rs.resolveTransactions(ctx, conversation, reply == models.ReplyNo)
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	if err := conversation.Resolve(reply, now); err != nil {
+		return models.Notification{}, err
+	}
+	err = rs.ConversationRepo.UpdateConversation(ctx, conversation, models.ConversationAwaitingReply)
+	if errors.Is(err, db.ErrConversationChanged) {
+		return replyNotification(models.NotificationConversationResolved, locale, answered), nil
+	}
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	if reply == models.ReplyNo {
+		return replyNotification(models.NotificationFraudConfirmed, locale, answered), nil
+	}
+	rs.recordConfirmedSightings(ctx, resolved)
+	return replyNotification(models.NotificationFraudRejected, locale, answered), nil
+}
+
+// handleKeyword updates the opt-out registry for STOP and START. STOP is not answered: the carrier confirms the
+// opt-out, and the number can no longer be texted.
+func (rs *GfResponseService) handleKeyword(ctx context.Context, phoneNumber string, keyword string, locale string, now time.Time) (models.Notification, error) {
+	switch keyword {
+	case models.SMSKeywordStop:
+		return models.Notification{}, rs.OptOutRepo.SetOptedOut(ctx, phoneNumber, true, keyword, now)
+	case models.SMSKeywordStart:
+		if err := rs.OptOutRepo.SetOptedOut(ctx, phoneNumber, false, keyword, now); err != nil {
+			return models.Notification{}, err
+		}
+		return replyNotification(models.NotificationOptedIn, locale, nil), nil
+	default:
+		return replyNotification(models.NotificationHelp, locale, nil), nil
+	}
+}
+
+// parseReply classifies a message. It returns models.ReplyYes or models.ReplyNo with the reply code it gave, or an
+// empty reply when it is not confidently a confirmation or denial. keyword is the carrier keyword the message is,
+// including phrases such as "opt out" the classifier is sure mean STOP or HELP. locale is the language the message
+// was written in, if the classifier recognised it.
+func (rs *GfResponseService) parseReply(msg models.TwilioMessage) (reply string, code string, keyword string, locale string) {
+	classification := rs.Classifier.Classify(msg.Body, "")
+	locale = classification.Locale
+	keyword = models.ParseSMSKeyword(msg.Body)
+	if keyword == "" && classification.Confidence == replies.ConfidenceHigh {
+		switch classification.Intent {
+		case replies.IntentStop:
+			keyword = models.SMSKeywordStop
+		case replies.IntentHelp:
+			keyword = models.SMSKeywordHelp
+		}
+	}
+	if classification.Confidence < rs.MinConfidence {
+		return "", classification.Code, keyword, locale
+	}
+
+	switch classification.Intent {
+	case replies.IntentConfirm:
+		return models.ReplyYes, classification.Code, keyword, locale
+	case replies.IntentDeny:
+		return models.ReplyNo, classification.Code, keyword, locale
+	default:
+		return "", classification.Code, keyword, locale
+	}
+}
+
+func minReplyConfidence() replies.Confidence {
+	confidence, err := replies.ParseConfidence(config.ReplyConfig.MinConfidence)
+	if err != nil {
+		log.Printf("Warning: %s, acting on medium confidence replies", err)
+		return replies.ConfidenceMedium
+	}
+	return confidence
+}
+
+// openConversations returns a phone number's open conversations that have not expired, oldest first, along with
+// the reply codes of expired ones so the customer can be told the alert lapsed. The escalation job closes expired
+// conversations.
+func (rs *GfResponseService) openConversations(ctx context.Context, phoneNumber string, now time.Time) ([]models.Conversation, map[string]bool, error) {
+	conversations, err := rs.ConversationRepo.GetOpenConversations(ctx, phoneNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var open []models.Conversation
+	expiredCodes := make(map[string]bool)
+	for _, conversation := range conversations {
+		if conversation.IsExpired(now) {
+			expiredCodes[conversation.ReplyCode] = true
+			continue
+		}
+		open = append(open, conversation)
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].CreatedAt < open[j].CreatedAt
+	})
+	return open, expiredCodes, nil
+}
+
+// prompt records that the customer was asked again about each open conversation. The reply is sent regardless,
+// so failures are logged.
+func (rs *GfResponseService) prompt(ctx context.Context, conversations []models.Conversation, reply string, now time.Time) {
+	for i := range conversations {
+		conversation := &conversations[i]
+		conversation.Prompt(reply, now)
+		if err := rs.ConversationRepo.UpdateConversation(ctx, conversation, models.ConversationAwaitingReply); err != nil && !errors.Is(err, db.ErrConversationChanged) {
+			fmt.Printf("Error recording prompt for alert %s: %s", conversation.AlertID, err)
+		}
+	}
+}
+
+func disambiguationPrompt(locale string, conversations []models.Conversation) models.Notification {
+	codes := make([]string, 0, len(conversations))
+	for _, conversation := range conversations {
+		if conversation.ReplyCode != "" {
+			codes = append(codes, conversation.ReplyCode)
+		}
+	}
+	prompt := replyNotification(models.NotificationChooseAlert, locale, conversations)
+	prompt.Data.Codes = strings.Join(codes, ", ")
+	return prompt
+}
+
+// replyNotification builds an answer in the language of the account the conversations belong to, or in the
+// language the message was written in when there are none.
+func replyNotification(notificationType string, locale string, conversations []models.Conversation) models.Notification {
+	notification := models.Notification{Type: notificationType, Locale: locale}
+	if len(conversations) > 0 {
+		notification.AccountID = conversations[0].AccountID
+		notification.Location = conversations[0].Location
+	}
+	if len(conversations) == 1 {
+		notification.Data.ReplyCode = conversations[0].ReplyCode
+	}
+	return notification
+}
+
+// resolveTransactions marks the transactions a conversation asks about as fraud or approved and returns them.
+// Transactions resolved some other way are left alone. The conversation is still open, so a transaction already in
+// the answer's status was resolved by an earlier attempt at this reply that failed to save the conversation. It is
+// returned again so the answer's follow-up, such as recording confirmed sightings, is not lost.
+func (rs *GfResponseService) resolveTransactions(ctx context.Context, conversation *models.Conversation, isFraud bool) ([]models.Transaction, error) {
+	status := "APPROVED"
+	if isFraud {
+		status = "FRAUD"
+	}
+
+	var resolved []models.Transaction
+	for _, transactionID := range conversation.TransactionIDs {
+		txn, err := rs.TransactionRepo.GetTransaction(ctx, conversation.AccountID, transactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s for alert %s: %w", transactionID, conversation.AlertID, err)
+		}
+		if txn.TransactionStatus == status {
+			resolved = append(resolved, *txn)
+			continue
+		}
+		if txn.TransactionStatus != "POTENTIAL_FRAUD" {
+			continue
+		}
+
+		txn.TransactionStatus = status
+		if _, err := rs.TransactionRepo.UpdateTransaction(ctx, txn.AccountID, txn.TransactionID, txn); err != nil {
+			return nil, fmt.Errorf("failed to update transaction %s for alert %s: %w", transactionID, conversation.AlertID, err)
+		}
+		resolved = append(resolved, *txn)
+	}
+	return resolved, nil
+}
+
+// recordConfirmedSightings marks the devices behind customer-confirmed transactions as known for the account
+// and folds their amounts into its spending baseline. Failures are logged so they do not block the answer.
+func (rs *GfResponseService) recordConfirmedSightings(ctx context.Context, transactions []models.Transaction) {
+	for _, txn := range transactions {
+		recordSightings(ctx, rs.ProfileRepo, txn)
+	}
+}
@@ -0,0 +1,251 @@
+// Package templates renders customer-facing messages from a catalog of templates keyed by message type and
+// locale, so alerts and replies reach each customer in their language.
+package templates
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundled catalog used when config.TemplateConfig.CatalogPath is not set.
+//
+//go:embed data/templates.yaml
+var bundledTemplates []byte
+
+// MessageTemplate is the text of one message. Subject is only used when the message is emailed.
+type MessageTemplate struct {
+	Subject string `yaml:"subject"`
+	Body    string `yaml:"body"`
+}
+
+// LocaleTemplates is every message in one locale. DateFormat is the Go time layout for {{.Date}}.
+type LocaleTemplates struct {
+	DateFormat string                     `yaml:"date_format"`
+	Messages   map[string]MessageTemplate `yaml:"messages"`
+}
+
+// CatalogFile is the document format of a template catalog.
+type CatalogFile struct {
+	DefaultLocale string                     `yaml:"default_locale"`
+	Locales       map[string]LocaleTemplates `yaml:"locales"`
+}
+
+// Rendered is a message ready to send, and the locale it was written in.
+type Rendered struct {
+	Subject string
+	Body    string
+	Locale  string
+}
+
+type compiledMessage struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type compiledLocale struct {
+	dateFormat string
+	messages   map[string]compiledMessage
+}
+
+// Catalog holds the compiled templates of every locale. The default locale has every message type, and other
+// locales fall back to it for messages they leave out.
+type Catalog struct {
+	defaultLocale string
+	locales       map[string]*compiledLocale
+}
+
+var (
+	configuredCatalog     *Catalog
+	configuredCatalogOnce sync.Once
+)
+
+// sampleData checks at load time that templates only use known placeholders
+var sampleData = models.NotificationData{Amount: "1.00", Merchant: "M", Date: "Jan 1", Last4: "1234", ReplyCode: "0000", Codes: "0000", Count: 2}
+
+// LoadCatalog parses and compiles a template catalog. Unknown message types, unknown placeholders or a default
+// locale missing a message type reject the whole catalog.
+func LoadCatalog(reader io.Reader) (*Catalog, error) {
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var file CatalogFile
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("template catalog is empty")
+		}
+		return nil, fmt.Errorf("failed to decode template catalog: %w", err)
+	}
+
+	defaultTemplates, ok := file.Locales[file.DefaultLocale]
+	if !ok {
+		return nil, fmt.Errorf("default locale %q has no templates", file.DefaultLocale)
+	}
+	for _, notificationType := range models.NotificationTypes {
+		if _, ok := defaultTemplates.Messages[notificationType]; !ok {
+			return nil, fmt.Errorf("default locale %s has no %s template", file.DefaultLocale, notificationType)
+		}
+	}
+
+	catalog := &Catalog{defaultLocale: normalizeLocale(file.DefaultLocale), locales: make(map[string]*compiledLocale)}
+	for name, locale := range file.Locales {
+		compiled := &compiledLocale{dateFormat: locale.DateFormat, messages: make(map[string]compiledMessage)}
+		for notificationType, message := range locale.Messages {
+			if !isNotificationType(notificationType) {
+				return nil, fmt.Errorf("unknown message type %q in locale %s", notificationType, name)
+			}
+			if message.Body == "" {
+				return nil, fmt.Errorf("%s template in locale %s has no body", notificationType, name)
+			}
+			subject, err := compile(name, notificationType, "subject", message.Subject)
+			if err != nil {
+				return nil, err
+			}
+			body, err := compile(name, notificationType, "body", message.Body)
+			if err != nil {
+				return nil, err
+			}
+			compiled.messages[notificationType] = compiledMessage{subject: subject, body: body}
+		}
+		catalog.locales[normalizeLocale(name)] = compiled
+	}
+	return catalog, nil
+}
+
+// LoadCatalogFile loads a template catalog from local disk.
+func LoadCatalogFile(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template catalog: %w", err)
+	}
+	defer file.Close()
+
+	return LoadCatalog(file)
+}
+
+// BundledCatalog returns the template catalog shipped with the binary.
+func BundledCatalog() *Catalog {
+	catalog, err := LoadCatalog(bytes.NewReader(bundledTemplates))
+	if err != nil {
+		panic(fmt.Sprintf("bundled template catalog is invalid: %s", err))
+	}
+	return catalog
+}
+
+// ConfiguredCatalog loads the catalog at config.TemplateConfig.CatalogPath once, falling back to the bundled
+// catalog when no path is set or the file cannot be loaded.
+func ConfiguredCatalog() *Catalog {
+	configuredCatalogOnce.Do(func() {
+		if path := config.TemplateConfig.CatalogPath; path != "" {
+			catalog, err := LoadCatalogFile(path)
+			if err == nil {
+				configuredCatalog = catalog
+				return
+			}
+			log.Printf("Warning: using bundled template catalog, could not load %s: %s", path, err)
+		}
+		configuredCatalog = BundledCatalog()
+	})
+	return configuredCatalog
+}
+
+// Locale returns the catalog locale closest to a requested one: the locale itself, then its language, so "es-MX"
+// reads as "es", then the default locale.
+func (c *Catalog) Locale(requested string) string {
+	requested = normalizeLocale(requested)
+	if _, ok := c.locales[requested]; ok {
+		return requested
+	}
+	if language, _, found := strings.Cut(requested, "-"); found {
+		if _, ok := c.locales[language]; ok {
+			return language
+		}
+	}
+	return c.defaultLocale
+}
+
+// HasLocale reports whether the catalog has templates for a locale or its language
+func (c *Catalog) HasLocale(requested string) bool {
+	requested = normalizeLocale(requested)
+	locale := c.Locale(requested)
+	return requested == locale || strings.HasPrefix(requested, locale+"-")
+}
+
+// Render writes a notification in the catalog locale closest to locale.
+func (c *Catalog) Render(notification models.Notification, locale string) (Rendered, error) {
+	locale = c.Locale(locale)
+	compiled := c.locales[locale]
+	message, ok := compiled.messages[notification.Type]
+	if !ok {
+		locale = c.defaultLocale
+		compiled = c.locales[locale]
+		if message, ok = compiled.messages[notification.Type]; !ok {
+			return Rendered{}, fmt.Errorf("no template for message type %q", notification.Type)
+		}
+	}
+
+	data := notification.Data
+	if date, err := time.Parse(time.RFC3339, data.Date); err == nil && compiled.dateFormat != "" {
+		data.Date = date.Format(compiled.dateFormat)
+	}
+
+	rendered := Rendered{Locale: locale}
+	var err error
+	if rendered.Subject, err = execute(message.subject, data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render %s subject in %s: %w", notification.Type, locale, err)
+	}
+	if rendered.Body, err = execute(message.body, data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render %s in %s: %w", notification.Type, locale, err)
+	}
+	return rendered, nil
+}
+
+func compile(locale string, notificationType string, part string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	parsed, err := template.New(notificationType + "." + part).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s template in locale %s: %w", notificationType, part, locale, err)
+	}
+	if _, err := execute(parsed, sampleData); err != nil {
+		return nil, fmt.Errorf("invalid %s %s template in locale %s: %w", notificationType, part, locale, err)
+	}
+	return parsed, nil
+}
+
+func execute(parsed *template.Template, data models.NotificationData) (string, error) {
+	if parsed == nil {
+		return "", nil
+	}
+	var result strings.Builder
+	if err := parsed.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+func isNotificationType(notificationType string) bool {
+	for _, known := range models.NotificationTypes {
+		if notificationType == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const limitedPhone = "19205550122"
+
+type AlertLimitTestSuite struct {
+	suite.Suite
+	ctx                    context.Context
+	now                    time.Time
+	mockEventDispatcher    *MockEventDispatcher
+	transactionRepository  *backtest.MemoryTransactionRepository
+	conversationRepository *backtest.MemoryConversationRepository
+	alertLimits            *backtest.MemoryAlertLimitRepository
+	outbox                 *backtest.MemoryOutboxRepository
+}
+
+func (suite *AlertLimitTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	suite.mockEventDispatcher = new(MockEventDispatcher)
+	suite.transactionRepository = backtest.NewMemoryTransactionRepository()
+	suite.conversationRepository = backtest.NewMemoryConversationRepository()
+	suite.alertLimits = backtest.NewMemoryAlertLimitRepository()
+	suite.outbox = backtest.NewMemoryOutboxRepository(suite.transactionRepository)
+}
+
+// fraudService flags every transaction
+func (suite *AlertLimitTestSuite) fraudService() *services.GfFraudService {
+	fraudService := services.NewFraudService(suite.transactionRepository, backtest.NewMemoryProfileRepository(),
+		backtest.NewMemoryListRepository(), suite.conversationRepository, suite.alertLimits, suite.outbox)
+	fraudService.Detector = fraud.NewRuleEngine(0)
+	return fraudService
+}
+
+func (suite *AlertLimitTestSuite) digestService() *services.GfDigestService {
+	return services.NewDigestService(suite.mockEventDispatcher, suite.transactionRepository, suite.conversationRepository, suite.alertLimits)
+}
+
+// save stores a transaction on the limited phone number
+func (suite *AlertLimitTestSuite) save(transactionID string, amount float64, status string) models.Transaction {
+	txn := models.Transaction{AccountID: "12345678", TransactionID: transactionID, PhoneNumber: limitedPhone, TransactionAmount: amount, TransactionStatus: status}
+	_, _, err := suite.transactionRepository.SaveTransaction(suite.ctx, &txn)
+	suite.Require().NoError(err)
+	return txn
+}
+
+// admit runs a transaction through the limiter at an offset from now
+func (suite *AlertLimitTestSuite) admit(transactionID string, after time.Duration) string {
+	at := suite.now.Add(after)
+	admission, err := suite.alertLimits.AdmitAlert(suite.ctx, "WINDOW#"+limitedPhone,
+		models.HeldAlert{TransactionID: transactionID, AccountID: "12345678", HeldAt: at.Unix()}, at, config.AlertLimitConfig.Window)
+	suite.Require().NoError(err)
+	return admission
+}
+
+func (suite *AlertLimitTestSuite) transaction(transactionID string) *models.Transaction {
+	txn, err := suite.transactionRepository.GetTransaction(suite.ctx, "12345678", transactionID)
+	suite.Require().NoError(err)
+	return txn
+}
+
+func (suite *AlertLimitTestSuite) TestBurstIsHeldForDigest() {
+	// Arrange
+	first := suite.save("1", 100, "")
+	second := suite.save("2", 200, "")
+	third := suite.save("3", 300, "")
+
+	// Act
+	_, _, firstErr := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{first})
+	fraudulent, failed, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{second, third})
+
+	// Assert
+	assert.NoError(suite.T(), firstErr)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Len(suite.T(), fraudulent, 2)
+	_, err = suite.outbox.GetNotification(suite.ctx, models.AlertOutboxID("12345678", "1"))
+	assert.NoError(suite.T(), err, "the first alert is recorded for the relay")
+	for _, transactionID := range []string{"2", "3"} {
+		held := suite.transaction(transactionID)
+		assert.Equal(suite.T(), "POTENTIAL_FRAUD", held.TransactionStatus)
+		assert.Empty(suite.T(), held.ReplyCode)
+		_, err := suite.outbox.GetNotification(suite.ctx, models.AlertOutboxID("12345678", transactionID))
+		assert.Error(suite.T(), err, "held alerts are sent by the digest")
+	}
+	open, err := suite.conversationRepository.GetOpenConversations(suite.ctx, limitedPhone)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), open, 1)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *AlertLimitTestSuite) TestRetriedFirstAlertIsNotHeld() {
+	// Act
+	first := suite.admit("1", 0)
+	retried := suite.admit("1", time.Minute)
+	held := suite.admit("2", time.Minute)
+	heldAgain := suite.admit("2", 2*time.Minute)
+
+	// Assert
+	assert.Equal(suite.T(), models.AlertSendNow, first)
+	assert.Equal(suite.T(), models.AlertSendNow, retried)
+	assert.Equal(suite.T(), models.AlertHeld, held)
+	assert.Equal(suite.T(), models.AlertHeld, heldAgain)
+}
+
+func (suite *AlertLimitTestSuite) TestDigestIsNotDueBeforeWindowEnds() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.save("2", 200, "POTENTIAL_FRAUD")
+
+	// Act
+	summary, err := suite.digestService().FlushDigests(suite.ctx, suite.now.Add(2*time.Minute))
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, summary.Alerts)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudDigestEvent", mock.Anything)
+}
+
+func (suite *AlertLimitTestSuite) TestDigestSendsHeldTransactions() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.admit("3", 2*time.Minute)
+	suite.save("2", 200, "POTENTIAL_FRAUD")
+	suite.save("3", 300, "POTENTIAL_FRAUD")
+	suite.mockEventDispatcher.On("DispatchFraudDigestEvent", mock.MatchedBy(func(transactions []models.Transaction) bool {
+		return len(transactions) == 2 && transactions[0].TransactionID == "2" && transactions[1].TransactionID == "3" &&
+			transactions[0].ReplyCode != "" && transactions[0].ReplyCode == transactions[1].ReplyCode
+	})).Return(messaging.ChannelSMS, nil).Once()
+	due := suite.now.Add(config.AlertLimitConfig.Window)
+
+	// Act
+	summary, err := suite.digestService().FlushDigests(suite.ctx, due)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &services.DigestSummary{Alerts: 1, Transactions: 2}, summary)
+	open, err := suite.conversationRepository.GetOpenConversations(suite.ctx, limitedPhone)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), open, 1)
+	assert.Equal(suite.T(), []string{"2", "3"}, open[0].TransactionIDs)
+	for _, transactionID := range []string{"2", "3"} {
+		sent := suite.transaction(transactionID)
+		assert.Equal(suite.T(), open[0].ReplyCode, sent.ReplyCode)
+		assert.Equal(suite.T(), messaging.ChannelSMS, sent.AlertChannel)
+	}
+	remaining, err := suite.alertLimits.GetDueDigests(suite.ctx, due)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), remaining)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *AlertLimitTestSuite) TestDigestKeepsAnswerGivenWhileSending() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.admit("3", 2*time.Minute)
+	suite.save("2", 200, "POTENTIAL_FRAUD")
+	suite.save("3", 300, "POTENTIAL_FRAUD")
+	suite.mockEventDispatcher.On("DispatchFraudDigestEvent", mock.Anything).Return(messaging.ChannelSMS, nil).Run(func(args mock.Arguments) {
+		answered := *suite.transaction("2")
+		answered.TransactionStatus = "APPROVED"
+		_, err := suite.transactionRepository.UpdateTransaction(suite.ctx, answered.AccountID, answered.TransactionID, &answered)
+		suite.Require().NoError(err)
+	}).Once()
+
+	// Act
+	_, err := suite.digestService().FlushDigests(suite.ctx, suite.now.Add(config.AlertLimitConfig.Window))
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	answered := suite.transaction("2")
+	assert.Equal(suite.T(), "APPROVED", answered.TransactionStatus)
+	assert.Equal(suite.T(), messaging.ChannelSMS, answered.AlertChannel)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.transaction("3").TransactionStatus)
+}
+
+func (suite *AlertLimitTestSuite) TestSingleHeldTransactionGetsPlainAlert() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.save("2", 200, "POTENTIAL_FRAUD")
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "2" && t.ReplyCode != ""
+	})).Return(messaging.ChannelSMS, nil).Once()
+
+	// Act
+	summary, err := suite.digestService().FlushDigests(suite.ctx, suite.now.Add(config.AlertLimitConfig.Window))
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, summary.Alerts)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudDigestEvent", mock.Anything)
+}
+
+func (suite *AlertLimitTestSuite) TestFailedDigestIsRetried() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.admit("3", 2*time.Minute)
+	suite.save("2", 200, "POTENTIAL_FRAUD")
+	suite.save("3", 300, "POTENTIAL_FRAUD")
+	suite.mockEventDispatcher.On("DispatchFraudDigestEvent", mock.Anything).Return("", errors.New("twilio unavailable")).Once()
+	suite.mockEventDispatcher.On("DispatchFraudDigestEvent", mock.Anything).Return(messaging.ChannelSMS, nil).Once()
+	due := suite.now.Add(config.AlertLimitConfig.Window)
+
+	// Act
+	failed, failedErr := suite.digestService().FlushDigests(suite.ctx, due)
+	retried, err := suite.digestService().FlushDigests(suite.ctx, due.Add(time.Minute))
+
+	// Assert
+	assert.ErrorContains(suite.T(), failedErr, "twilio unavailable")
+	assert.Equal(suite.T(), 1, failed.Failed)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, retried.Transactions)
+	open, err := suite.conversationRepository.GetOpenConversations(suite.ctx, limitedPhone)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), open, 1, "the retry reuses the digest's conversation")
+}
+
+func (suite *AlertLimitTestSuite) TestResolvedHeldTransactionIsDropped() {
+	// Arrange
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+	suite.save("2", 200, "APPROVED")
+	due := suite.now.Add(config.AlertLimitConfig.Window)
+
+	// Act
+	summary, err := suite.digestService().FlushDigests(suite.ctx, due)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, summary.Alerts)
+	remaining, err := suite.alertLimits.GetDueDigests(suite.ctx, due)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), remaining)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *AlertLimitTestSuite) TestPendingDigestKeepsCollectingAfterWindowEnds() {
+	// Arrange
+	window := config.AlertLimitConfig.Window
+	suite.admit("1", 0)
+	suite.admit("2", time.Minute)
+
+	// Act
+	lateWhilePending := suite.admit("3", window+time.Minute)
+	assert.NoError(suite.T(), suite.alertLimits.CompleteDigest(suite.ctx, "WINDOW#"+limitedPhone, []string{"2", "3"}))
+	afterDigest := suite.admit("4", window+2*time.Minute)
+
+	// Assert
+	assert.Equal(suite.T(), models.AlertHeld, lateWhilePending)
+	assert.Equal(suite.T(), models.AlertSendNow, afterDigest)
+}
+
+func TestAlertLimitSuite(t *testing.T) {
+	suite.Run(t, new(AlertLimitTestSuite))
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const backtestHeader = "TransactionID,AccountID,TransactionAmount,TransactionDate,TransactionType,Location,DeviceID,IPAddress,MerchantID,Channel,CustomerAge,CustomerOccupation,TransactionDuration,LoginAttempts,AccountBalance,PreviousTransactionDate,PhoneNumber,Email,TransactionStatus,IsFraud\n"
+
+// backtestRow builds a CSV row for an account on its own device so only amount and login attempts matter
+func backtestRow(id string, amount string, loginAttempts string, label string) string {
+	return strings.Join([]string{
+		id, "ACC-" + id, amount, "", "Debit", "Houston", "D-" + id, "10.0.0." + id, "M001", "Online",
+		"40", "Engineer", "60", loginAttempts, "5000", "", "19205550100", "user" + id + "@example.com", "Pending", label,
+	}, ",") + "\n"
+}
+
+type BacktestTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *BacktestTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *BacktestTestSuite) TestReplayReportsConfusionMatrix() {
+	// Arrange
+	csv := backtestHeader +
+		backtestRow("1", "1500", "5", "1") +
+		backtestRow("2", "1500", "5", "0") +
+		backtestRow("3", "10", "1", "true") +
+		backtestRow("4", "10", "1", "false")
+	runner, err := backtest.NewRunner(suite.ctx)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	report, err := runner.Replay(suite.ctx, strings.NewReader(csv), "IsFraud")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, report.Transactions)
+	assert.Equal(suite.T(), 2, report.Alerts)
+	assert.Equal(suite.T(), 2, runner.Alerts.Alerts)
+	assert.Equal(suite.T(), backtest.Confusion{TruePositives: 1, FalsePositives: 1, TrueNegatives: 1, FalseNegatives: 1}, report.Confusion)
+	assert.InDelta(suite.T(), 0.5, report.Confusion.Precision(), 1e-9)
+	assert.InDelta(suite.T(), 0.5, report.Confusion.Recall(), 1e-9)
+	assert.InDelta(suite.T(), 0.5, report.Confusion.FalsePositiveRate(), 1e-9)
+	assert.Equal(suite.T(), 2, report.Rules[fraud.ReasonExcessLoginAttempts].Triggered)
+	assert.Equal(suite.T(), 2, report.ScoreHistogram[len(report.ScoreHistogram)-1])
+
+	var output bytes.Buffer
+	assert.NoError(suite.T(), report.WriteText(&output))
+	assert.Contains(suite.T(), output.String(), fraud.ReasonExcessLoginAttempts)
+}
+
+func (suite *BacktestTestSuite) TestReplayAppliesListEntries() {
+	// Arrange
+	entry, _ := models.NewListEntry(models.ListDeny, models.ListKindDevice, "D-1", "known bad device", "analyst", time.Hour, time.Now())
+	runner, err := backtest.NewRunner(suite.ctx, *entry)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	report, err := runner.Replay(suite.ctx, strings.NewReader(backtestHeader+backtestRow("1", "10", "1", "1")), "IsFraud")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, report.Confusion.TruePositives)
+	assert.Equal(suite.T(), 1, report.Rules["DENYLIST_DEVICE"].Triggered)
+}
+
+func (suite *BacktestTestSuite) TestReplayRejectsUnlabeledData() {
+	// Arrange
+	runner, err := backtest.NewRunner(suite.ctx)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	_, missingColumnErr := runner.Replay(suite.ctx, strings.NewReader(backtestHeader+backtestRow("1", "10", "1", "1")), "Label")
+	_, badLabelErr := runner.Replay(suite.ctx, strings.NewReader(backtestHeader+backtestRow("2", "10", "1", "maybe")), "IsFraud")
+
+	// Assert
+	assert.Error(suite.T(), missingColumnErr)
+	assert.ErrorContains(suite.T(), badLabelErr, "row 2")
+}
+
+func TestBacktestSuite(t *testing.T) {
+	suite.Run(t, new(BacktestTestSuite))
+}
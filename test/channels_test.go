@@ -0,0 +1,216 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationChannelTestSuite struct {
+	suite.Suite
+	ctx       context.Context
+	recipient messaging.Recipient
+	message   messaging.Message
+}
+
+func (suite *NotificationChannelTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.recipient = messaging.Recipient{AccountID: "12345678", PhoneNumber: "19205550100", Email: "user@example.com"}
+	suite.message = messaging.Message{Type: models.NotificationFraudAlert, Subject: "Suspicious Activity on Your Card", Body: "alert", Locale: "en", TransactionID: "1"}
+}
+
+func (suite *NotificationChannelTestSuite) TestWebhookPostsSignedMessage() {
+	// Arrange
+	var payload messaging.WebhookPayload
+	var signature, expected string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(messaging.WebhookSignatureHeader)
+		expected = messaging.SignWebhook("secret", body)
+		_ = json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	channel, err := messaging.NewWebhookChannel(server.URL, "secret", server.Client())
+	assert.NoError(suite.T(), err)
+
+	// Act
+	err = channel.Send(suite.ctx, suite.recipient, suite.message)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expected, signature)
+	assert.Equal(suite.T(), "12345678", payload.AccountID)
+	assert.Equal(suite.T(), models.NotificationFraudAlert, payload.Type)
+	assert.Equal(suite.T(), "alert", payload.Body)
+	assert.Equal(suite.T(), "1", payload.TransactionID)
+}
+
+func (suite *NotificationChannelTestSuite) TestWebhookErrorStatusFails() {
+	// Arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	channel, err := messaging.NewWebhookChannel(server.URL, "", server.Client())
+	assert.NoError(suite.T(), err)
+
+	// Act
+	err = channel.Send(suite.ctx, suite.recipient, suite.message)
+
+	// Assert
+	assert.ErrorContains(suite.T(), err, "503")
+}
+
+func (suite *NotificationChannelTestSuite) TestWebhookRequiresHTTPS() {
+	// Act
+	_, plainErr := messaging.NewWebhookChannel("http://push.example.com/notify", "", http.DefaultClient)
+	_, emptyErr := messaging.NewWebhookChannel("", "", http.DefaultClient)
+
+	// Assert
+	assert.Error(suite.T(), plainErr)
+	assert.Error(suite.T(), emptyErr)
+}
+
+func (suite *NotificationChannelTestSuite) TestChannelsNeedAnAddress() {
+	// Arrange
+	sms := messaging.NewTwilioSMSChannel("", "", "+18333981458", fakeOptOuts{})
+	email := messaging.NewSNSEmailChannel(nil, "")
+
+	// Act
+	smsErr := sms.Send(suite.ctx, messaging.Recipient{AccountID: "12345678"}, suite.message)
+	emailErr := email.Send(suite.ctx, messaging.Recipient{AccountID: "12345678"}, suite.message)
+
+	// Assert
+	assert.ErrorIs(suite.T(), smsErr, messaging.ErrNoAddress)
+	assert.ErrorIs(suite.T(), emailErr, messaging.ErrNoAddress)
+}
+
+func (suite *NotificationChannelTestSuite) TestSMSChecksOptOutRegistry() {
+	// Arrange
+	sms := messaging.NewTwilioSMSChannel("", "", "+18333981458", fakeOptOuts{"19205550100": true})
+
+	// Act
+	err := sms.Send(suite.ctx, suite.recipient, suite.message)
+
+	// Assert
+	assert.ErrorIs(suite.T(), err, messaging.ErrOptedOut)
+}
+
+func (suite *NotificationChannelTestSuite) TestRegistryBuildsProvidersByName() {
+	// Arrange
+	registry := messaging.NewChannelRegistry()
+	push := NewMockNotificationChannel("push")
+	registry.Register("acme-push", func(env messaging.ChannelEnvironment) (messaging.NotificationChannel, error) {
+		return push, nil
+	})
+
+	// Act
+	channels, err := registry.Build(messaging.ChannelEnvironment{OptOuts: fakeOptOuts{}}, []string{messaging.ProviderTwilio, messaging.ProviderSNS, "acme-push"})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.IsType(suite.T(), &messaging.TwilioSMSChannel{}, channels[messaging.ChannelSMS])
+	assert.IsType(suite.T(), &messaging.SNSEmailChannel{}, channels[messaging.ChannelEmail])
+	assert.Same(suite.T(), push, channels["push"])
+}
+
+func (suite *NotificationChannelTestSuite) TestRegistryRejectsBadConfig() {
+	// Arrange
+	registry := messaging.NewChannelRegistry()
+	registry.Register("other-sms", func(env messaging.ChannelEnvironment) (messaging.NotificationChannel, error) {
+		return NewMockNotificationChannel(messaging.ChannelSMS), nil
+	})
+
+	// Act
+	_, unknownErr := registry.Build(messaging.ChannelEnvironment{}, []string{"carrier-pigeon"})
+	_, duplicateErr := registry.Build(messaging.ChannelEnvironment{}, []string{messaging.ProviderTwilio, "other-sms"})
+
+	// Assert
+	assert.ErrorContains(suite.T(), unknownErr, "carrier-pigeon")
+	assert.ErrorContains(suite.T(), duplicateErr, "more than one provider")
+}
+
+func (suite *NotificationChannelTestSuite) TestCheckRouteNeedsAConfiguredChannel() {
+	// Arrange
+	channels := messaging.Channels{messaging.ChannelSMS: NewMockNotificationChannel(messaging.ChannelSMS)}
+
+	// Act
+	fallbackErr := channels.CheckRoute([]string{messaging.ChannelEmail, messaging.ChannelSMS})
+	unconfiguredErr := channels.CheckRoute([]string{messaging.ChannelEmail, messaging.ChannelWebhook})
+
+	// Assert
+	assert.NoError(suite.T(), fallbackErr)
+	assert.ErrorIs(suite.T(), unconfiguredErr, messaging.ErrChannelUnavailable)
+}
+
+// fakeSNS records the subscriptions and messages sent to SNS. Subscriptions are confirmed unless pending is set.
+type fakeSNS struct {
+	pending       bool
+	subscriptions []*sns.SubscribeInput
+	published     []*sns.PublishInput
+}
+
+func (f *fakeSNS) Subscribe(ctx context.Context, params *sns.SubscribeInput, optFns ...func(*sns.Options)) (*sns.SubscribeOutput, error) {
+	f.subscriptions = append(f.subscriptions, params)
+	return &sns.SubscribeOutput{SubscriptionArn: aws.String("arn:aws:sns:us-east-1:000000000000:alerts:1")}, nil
+}
+
+func (f *fakeSNS) GetSubscriptionAttributes(ctx context.Context, params *sns.GetSubscriptionAttributesInput, optFns ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error) {
+	latest := f.subscriptions[len(f.subscriptions)-1]
+	return &sns.GetSubscriptionAttributesOutput{Attributes: map[string]string{
+		"PendingConfirmation": strconv.FormatBool(f.pending),
+		"FilterPolicy":        latest.Attributes["FilterPolicy"],
+	}}, nil
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.published = append(f.published, params)
+	return &sns.PublishOutput{}, nil
+}
+
+func (suite *NotificationChannelTestSuite) TestEmailSubscriptionOnlyReceivesItsAccount() {
+	// Arrange
+	client := &fakeSNS{}
+	channel := messaging.NewSNSEmailChannel(client, "arn:aws:sns:us-east-1:000000000000:alerts")
+
+	// Act
+	err := channel.Send(suite.ctx, suite.recipient, suite.message)
+	noAccountErr := channel.Send(suite.ctx, messaging.Recipient{Email: "user@example.com"}, suite.message)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Error(suite.T(), noAccountErr)
+	suite.Require().Len(client.subscriptions, 1)
+	assert.JSONEq(suite.T(), `{"AccountID":["12345678"]}`, client.subscriptions[0].Attributes["FilterPolicy"])
+	suite.Require().Len(client.published, 1)
+	assert.Equal(suite.T(), "12345678", aws.ToString(client.published[0].MessageAttributes["AccountID"].StringValue))
+}
+
+func (suite *NotificationChannelTestSuite) TestUnconfirmedEmailIsNotDelivered() {
+	// Arrange
+	client := &fakeSNS{pending: true}
+	channel := messaging.NewSNSEmailChannel(client, "arn:aws:sns:us-east-1:000000000000:alerts")
+
+	// Act
+	err := channel.Send(suite.ctx, suite.recipient, suite.message)
+
+	// Assert
+	assert.ErrorIs(suite.T(), err, messaging.ErrSubscriptionNotReady)
+	assert.Empty(suite.T(), client.published)
+}
+
+func TestNotificationChannelSuite(t *testing.T) {
+	suite.Run(t, new(NotificationChannelTestSuite))
+}
@@ -90,6 +90,14 @@ func (s *TransactionRepositoryTestSuite) createTestTable(client *dynamodb.Client
 				AttributeName: aws.String(config.DBConfig.Keys.SortKey),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
+			{
+				AttributeName: aws.String("DeviceID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("TransactionTimestamp"),
+				AttributeType: types.ScalarAttributeTypeN,
+			},
 		},
 		KeySchema: []types.KeySchemaElement{
 			{
@@ -101,6 +109,17 @@ func (s *TransactionRepositoryTestSuite) createTestTable(client *dynamodb.Client
 				KeyType:       types.KeyTypeRange,
 			},
 		},
+		// Index key attributes cannot be empty strings, so the device index is created like the deployed one
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("DeviceTimeIndex"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("DeviceID"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("TransactionTimestamp"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
 		BillingMode: types.BillingModePayPerRequest,
 	})
 
@@ -174,6 +193,21 @@ func (s *TransactionRepositoryTestSuite) TestSaveTransaction() {
 	assert.Contains(s.T(), err.Error(), "transaction already exists")
 }
 
+func (s *TransactionRepositoryTestSuite) TestSaveTransactionWithoutDevice() {
+	// Arrange
+	transaction := s.createValidTransaction()
+	transaction.DeviceID = ""
+
+	// Act
+	_, _, err := s.repository.SaveTransaction(s.ctx, &transaction)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	retrieved, err := s.repository.GetTransaction(s.ctx, transaction.AccountID, transaction.TransactionID)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), retrieved.DeviceID)
+}
+
 func (s *TransactionRepositoryTestSuite) TestGetTransaction() {
 	// s.T().Parallel()
 
@@ -0,0 +1,290 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const escalationPhone = "19205550111"
+
+type EscalationServiceTestSuite struct {
+	suite.Suite
+	ctx                    context.Context
+	now                    time.Time
+	mockEventDispatcher    *MockEventDispatcher
+	transactionRepository  *backtest.MemoryTransactionRepository
+	conversationRepository *backtest.MemoryConversationRepository
+	policy                 services.EscalationPolicy
+}
+
+func (suite *EscalationServiceTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	suite.mockEventDispatcher = new(MockEventDispatcher)
+	suite.transactionRepository = backtest.NewMemoryTransactionRepository()
+	suite.conversationRepository = backtest.NewMemoryConversationRepository()
+	suite.policy = services.EscalationPolicy{
+		ReminderAfter:         30 * time.Minute,
+		SecondaryChannelAfter: 2 * time.Hour,
+		FinalActionAfter:      24 * time.Hour,
+		FinalAction:           models.EscalationHold,
+	}
+}
+
+func (suite *EscalationServiceTestSuite) service() *services.GfEscalationService {
+	return services.NewEscalationService(suite.mockEventDispatcher, suite.transactionRepository, suite.conversationRepository, suite.policy)
+}
+
+// alert saves a transaction awaiting confirmation and opens its conversation, both delivered age ago
+func (suite *EscalationServiceTestSuite) alert(transactionID string, age time.Duration, escalation string) models.Transaction {
+	txn := models.Transaction{
+		AccountID:         "ACC-1",
+		TransactionID:     transactionID,
+		PhoneNumber:       escalationPhone,
+		TransactionStatus: "POTENTIAL_FRAUD",
+		AlertChannel:      messaging.ChannelSMS,
+		AlertedAt:         suite.now.Add(-age).Unix(),
+	}
+	_, _, err := suite.transactionRepository.SaveTransaction(suite.ctx, &txn)
+	suite.Require().NoError(err)
+
+	conversation := models.NewConversation(txn, "4821", suite.now.Add(-age), 48*time.Hour)
+	conversation.Escalation = escalation
+	started, err := suite.conversationRepository.StartConversation(suite.ctx, conversation)
+	suite.Require().NoError(err)
+	suite.Require().True(started)
+	return txn
+}
+
+func (suite *EscalationServiceTestSuite) transaction(transactionID string) *models.Transaction {
+	txn, err := suite.transactionRepository.GetTransaction(suite.ctx, "ACC-1", transactionID)
+	suite.Require().NoError(err)
+	return txn
+}
+
+func (suite *EscalationServiceTestSuite) openConversations() []models.Conversation {
+	open, err := suite.conversationRepository.GetOpenConversations(suite.ctx, escalationPhone)
+	suite.Require().NoError(err)
+	return open
+}
+
+func (suite *EscalationServiceTestSuite) TestReminderIsSentAndRecorded() {
+	// Arrange
+	suite.alert("1", 45*time.Minute, "")
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", escalationPhone, mock.MatchedBy(func(reminder models.Notification) bool {
+		return reminder.Type == models.NotificationReminder && reminder.AccountID == "ACC-1"
+	})).Return(nil).Once()
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, summary.Steps[models.EscalationReminder])
+	txn := suite.transaction("1")
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", txn.TransactionStatus)
+	assert.Equal(suite.T(), []models.EscalationStep{{Step: models.EscalationReminder, At: suite.now.Unix(), Detail: "SMS"}}, txn.Escalations)
+	open := suite.openConversations()
+	assert.Len(suite.T(), open, 1)
+	assert.Equal(suite.T(), models.EscalationReminder, open[0].Escalation)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *EscalationServiceTestSuite) TestReminderIsOnlySentOnce() {
+	// Arrange
+	suite.alert("1", 45*time.Minute, models.EscalationReminder)
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), summary.Steps)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+}
+
+func (suite *EscalationServiceTestSuite) TestSecondaryChannelSendsEmail() {
+	// Arrange
+	suite.alert("1", 3*time.Hour, models.EscalationReminder)
+	suite.mockEventDispatcher.On("DispatchFraudEscalationEvent", mock.MatchedBy(func(txn models.Transaction) bool {
+		return txn.TransactionID == "1"
+	})).Return(messaging.ChannelEmail, nil).Once()
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, summary.Steps[models.EscalationSecondaryChannel])
+	assert.Equal(suite.T(), models.EscalationSecondaryChannel, suite.openConversations()[0].Escalation)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *EscalationServiceTestSuite) TestFinalActionHoldsTransactionAndClosesAlert() {
+	// Arrange
+	suite.alert("1", 25*time.Hour, models.EscalationSecondaryChannel)
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, summary.Steps[models.EscalationHold])
+	txn := suite.transaction("1")
+	assert.Equal(suite.T(), models.StatusOnHold, txn.TransactionStatus)
+	assert.Equal(suite.T(), models.EscalationHold, txn.Escalations[len(txn.Escalations)-1].Step)
+	assert.Empty(suite.T(), suite.openConversations())
+}
+
+func (suite *EscalationServiceTestSuite) TestMissedAlertGoesStraightToDecline() {
+	// Arrange
+	suite.policy.FinalAction = models.EscalationDecline
+	suite.alert("1", 30*time.Hour, "")
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{models.EscalationDecline: 1}, summary.Steps)
+	assert.Equal(suite.T(), models.StatusDeclined, suite.transaction("1").TransactionStatus)
+	assert.Empty(suite.T(), suite.openConversations())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+}
+
+func (suite *EscalationServiceTestSuite) TestResolvedTransactionOnlyClosesAlert() {
+	// Arrange
+	txn := suite.alert("1", 45*time.Minute, "")
+	txn.TransactionStatus = "APPROVED"
+	_, err := suite.transactionRepository.UpdateTransaction(suite.ctx, txn.AccountID, txn.TransactionID, &txn)
+	suite.Require().NoError(err)
+
+	// Act
+	_, err = suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "APPROVED", suite.transaction("1").TransactionStatus)
+	assert.Empty(suite.T(), suite.transaction("1").Escalations)
+	assert.Empty(suite.T(), suite.openConversations())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+}
+
+// answeringTransactionRepository approves each transaction right after it is read, as a customer answering
+// while the alert is escalated would
+type answeringTransactionRepository struct {
+	*backtest.MemoryTransactionRepository
+}
+
+func (r answeringTransactionRepository) GetTransaction(ctx context.Context, accountID, transactionID string) (*models.Transaction, error) {
+	txn, err := r.MemoryTransactionRepository.GetTransaction(ctx, accountID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	answered := *txn
+	answered.TransactionStatus = "APPROVED"
+	if _, err := r.UpdateTransaction(ctx, accountID, transactionID, &answered); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+func (suite *EscalationServiceTestSuite) TestCustomerAnsweringDuringFinalActionKeepsAnswer() {
+	// Arrange
+	suite.policy.FinalAction = models.EscalationDecline
+	suite.alert("1", 30*time.Hour, "")
+	repository := answeringTransactionRepository{MemoryTransactionRepository: suite.transactionRepository}
+	service := services.NewEscalationService(suite.mockEventDispatcher, repository, suite.conversationRepository, suite.policy)
+
+	// Act
+	_, err := service.EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "APPROVED", suite.transaction("1").TransactionStatus)
+	assert.Empty(suite.T(), suite.transaction("1").Escalations)
+}
+
+func (suite *EscalationServiceTestSuite) TestNothingDueYet() {
+	// Arrange
+	suite.alert("1", 10*time.Minute, "")
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), summary.Steps)
+	assert.Len(suite.T(), suite.openConversations(), 1)
+}
+
+func (suite *EscalationServiceTestSuite) TestHeldAlertIsNotEscalated() {
+	// Arrange
+	txn := suite.alert("1", 3*time.Hour, "")
+	txn.AlertChannel = ""
+	txn.AlertedAt = 0
+	_, err := suite.transactionRepository.UpdateTransaction(suite.ctx, txn.AccountID, txn.TransactionID, &txn)
+	suite.Require().NoError(err)
+	suite.Require().Empty(suite.transaction("1").AlertChannel)
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), summary.Steps)
+	assert.Empty(suite.T(), suite.transaction("1").Escalations)
+	assert.Equal(suite.T(), "", suite.openConversations()[0].Escalation)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudEscalationEvent", mock.Anything)
+}
+
+func (suite *EscalationServiceTestSuite) TestEscalationCountsFromDelivery() {
+	// Arrange
+	txn := suite.alert("1", 3*time.Hour, "")
+	txn.AlertedAt = suite.now.Add(-45 * time.Minute).Unix()
+	_, err := suite.transactionRepository.UpdateTransaction(suite.ctx, txn.AccountID, txn.TransactionID, &txn)
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", escalationPhone, mock.MatchedBy(func(reminder models.Notification) bool {
+		return reminder.Type == models.NotificationReminder
+	})).Return(nil).Once()
+
+	// Act
+	summary, err := suite.service().EscalateAlerts(suite.ctx, suite.now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{models.EscalationReminder: 1}, summary.Steps)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudEscalationEvent", mock.Anything)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *EscalationServiceTestSuite) TestPolicyDue() {
+	created := suite.now.Add(-time.Hour)
+	conversation := models.NewConversation(models.Transaction{TransactionID: "1"}, "4821", created, 48*time.Hour)
+
+	assert.Equal(suite.T(), "", suite.policy.Due(*conversation, created, created.Add(29*time.Minute)))
+	assert.Equal(suite.T(), models.EscalationReminder, suite.policy.Due(*conversation, created, created.Add(30*time.Minute)))
+	assert.Equal(suite.T(), models.EscalationSecondaryChannel, suite.policy.Due(*conversation, created, created.Add(2*time.Hour)))
+	assert.Equal(suite.T(), models.EscalationHold, suite.policy.Due(*conversation, created, created.Add(24*time.Hour)))
+
+	// The conversation's TTL ends the alert even before the final action is due
+	conversation.ExpiresAt = created.Add(time.Hour).Unix()
+	assert.Equal(suite.T(), models.EscalationHold, suite.policy.Due(*conversation, created, created.Add(time.Hour)))
+
+	conversation.Escalation = models.EscalationHold
+	assert.Equal(suite.T(), "", suite.policy.Due(*conversation, created, created.Add(48*time.Hour)))
+}
+
+func TestEscalationSuite(t *testing.T) {
+	suite.Run(t, new(EscalationServiceTestSuite))
+}
@@ -1,11 +1,315 @@
 package test
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
 )
 
 func TestEventsPlaceholder(t *testing.T) {
 	assert.True(t, 1+1 == 2)
 }
+
+type MockNotificationChannel struct {
+	mock.Mock
+	name string
+}
+
+func NewMockNotificationChannel(name string) *MockNotificationChannel {
+	return &MockNotificationChannel{name: name}
+}
+
+// Name implements messaging.NotificationChannel.
+func (m *MockNotificationChannel) Name() string {
+	return m.name
+}
+
+// Send implements messaging.NotificationChannel.
+func (m *MockNotificationChannel) Send(ctx context.Context, recipient messaging.Recipient, message messaging.Message) error {
+	args := m.Called(recipient, message)
+	return args.Error(0)
+}
+
+// body matches a message by its body
+func body(expected string) interface{} {
+	return mock.MatchedBy(func(message messaging.Message) bool {
+		return message.Body == expected
+	})
+}
+
+// fakeOptOuts is an opt-out registry holding the given phone numbers
+type fakeOptOuts map[string]bool
+
+func (f fakeOptOuts) IsOptedOut(ctx context.Context, phoneNumber string) (bool, error) {
+	return f[phoneNumber], nil
+}
+
+type EventDispatcherTestSuite struct {
+	suite.Suite
+	sms         *MockNotificationChannel
+	email       *MockNotificationChannel
+	preferences *backtest.MemoryProfileRepository
+	sent        *backtest.MemoryAlertLimitRepository
+	dispatcher  *events.GfEventDispatcher
+}
+
+func (suite *EventDispatcherTestSuite) SetupTest() {
+	suite.sms = NewMockNotificationChannel(messaging.ChannelSMS)
+	suite.email = NewMockNotificationChannel(messaging.ChannelEmail)
+	suite.preferences = backtest.NewMemoryProfileRepository()
+	suite.sent = backtest.NewMemoryAlertLimitRepository()
+	channels := messaging.Channels{messaging.ChannelSMS: suite.sms, messaging.ChannelEmail: suite.email}
+	suite.dispatcher = events.NewGfEventDispatcher(channels, templates.BundledCatalog(), suite.preferences, suite.sent, nil)
+	suite.dispatcher.Routing = events.RoutingPolicy{Default: []string{messaging.ChannelSMS, messaging.ChannelEmail, messaging.ChannelWebhook}}
+}
+
+func (suite *EventDispatcherTestSuite) TestOptedOutAlertIsEmailed() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100", Email: "user@example.com"}
+	recipient := messaging.RecipientOf(txn)
+	suite.sms.On("Send", recipient, mock.Anything).Return(messaging.ErrOptedOut).Once()
+	suite.email.On("Send", recipient, mock.MatchedBy(func(message messaging.Message) bool {
+		return message.Subject == "Suspicious Activity on Your Card" && message.TransactionID == "1"
+	})).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelEmail, channel)
+	suite.sms.AssertExpectations(suite.T())
+	suite.email.AssertExpectations(suite.T())
+}
+
+func (suite *EventDispatcherTestSuite) TestTextFailureFallsBackToEmail() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100", Email: "user@example.com"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(errors.New("twilio unavailable")).Once()
+	suite.email.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelEmail, channel)
+}
+
+func (suite *EventDispatcherTestSuite) TestEveryChannelFailing() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(errors.New("twilio unavailable")).Once()
+	suite.email.On("Send", mock.Anything, mock.Anything).Return(messaging.ErrNoAddress).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.ErrorIs(suite.T(), err, events.ErrUndeliverable)
+	assert.ErrorIs(suite.T(), err, messaging.ErrNoAddress)
+	assert.ErrorContains(suite.T(), err, "twilio unavailable")
+	assert.NotErrorIs(suite.T(), err, events.ErrUnreachable, "a provider error may pass")
+	assert.Empty(suite.T(), channel)
+}
+
+func (suite *EventDispatcherTestSuite) TestUnreachableRecipient() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(messaging.ErrOptedOut).Once()
+	suite.email.On("Send", mock.Anything, mock.Anything).Return(messaging.ErrNoAddress).Once()
+
+	// Act
+	_, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.ErrorIs(suite.T(), err, events.ErrUndeliverable)
+	assert.ErrorIs(suite.T(), err, events.ErrUnreachable)
+}
+
+func (suite *EventDispatcherTestSuite) TestCustomerRouteIsFollowed() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", Email: "user@example.com"}
+	preferences := models.NewAccountPreferences("12345678")
+	preferences.Channels = []string{messaging.ChannelEmail, messaging.ChannelSMS}
+	assert.NoError(suite.T(), suite.preferences.SavePreferences(context.Background(), preferences))
+	suite.email.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelEmail, channel)
+	suite.sms.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything)
+}
+
+func (suite *EventDispatcherTestSuite) TestEscalationSkipsAlertChannel() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100", Email: "user@example.com", AlertChannel: messaging.ChannelEmail}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudEscalationEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	suite.email.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything)
+}
+
+func (suite *EventDispatcherTestSuite) TestOptedOutUpdateIsDropped() {
+	// Arrange
+	suite.sms.On("Send", messaging.Recipient{PhoneNumber: "19205550100"}, mock.Anything).Return(messaging.ErrOptedOut).Once()
+
+	// Act
+	err := suite.dispatcher.DispatchFraudUpdateEvent("19205550100", models.Notification{Type: models.NotificationHelp})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	suite.sms.AssertExpectations(suite.T())
+}
+
+func (suite *EventDispatcherTestSuite) TestUnconfiguredChannelFails() {
+	// Arrange
+	txn := models.Transaction{TransactionID: "1", PhoneNumber: "19205550100", Email: "user@example.com"}
+	dispatcher := events.NewGfEventDispatcher(messaging.Channels{messaging.ChannelSMS: suite.sms}, templates.BundledCatalog(), nil, nil, nil)
+
+	// Act
+	_, err := dispatcher.DispatchFraudEscalationEvent(txn)
+
+	// Assert
+	assert.ErrorIs(suite.T(), err, events.ErrUndeliverable)
+	assert.ErrorIs(suite.T(), err, messaging.ErrChannelUnavailable)
+}
+
+func (suite *EventDispatcherTestSuite) TestIdenticalAlertIsSentOnce() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", ReplyCode: "4821"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	first, firstErr := suite.dispatcher.DispatchFraudAlertEvent(txn)
+	second, secondErr := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), firstErr)
+	assert.NoError(suite.T(), secondErr)
+	assert.Equal(suite.T(), messaging.ChannelSMS, first)
+	assert.Equal(suite.T(), messaging.ChannelSMS, second)
+	suite.sms.AssertNumberOfCalls(suite.T(), "Send", 1)
+}
+
+func (suite *EventDispatcherTestSuite) TestClaimedAlertIsSentOnceLeaseRunsOut() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", ReplyCode: "4821"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(nil)
+	render := events.NewGfEventDispatcher(messaging.Channels{messaging.ChannelSMS: suite.sms}, templates.BundledCatalog(), nil, nil, nil)
+	_, err := render.DispatchFraudAlertEvent(txn)
+	suite.Require().NoError(err)
+	alert := suite.sms.Calls[0].Arguments.Get(1).(messaging.Message)
+	key := models.SentMessageKey(messaging.ChannelSMS, txn.PhoneNumber, alert.Subject, alert.Body)
+	lease := config.AlertLimitConfig.ClaimLease
+	// A sender that claimed the alert and stopped before sending it
+	_, err = suite.sent.ClaimMessage(context.Background(), key, time.Now().Add(-2*lease), lease)
+	suite.Require().NoError(err)
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+	_, againErr := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), againErr)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	suite.sms.AssertNumberOfCalls(suite.T(), "Send", 2)
+	claimed, err := suite.sent.ClaimMessage(context.Background(), key, time.Now().Add(2*lease), lease)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), claimed, "a delivered alert is remembered for the dedup window, not just the lease")
+}
+
+func (suite *EventDispatcherTestSuite) TestFailedAlertIsSentOnRetry() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", ReplyCode: "4821"}
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(errors.New("twilio unavailable")).Once()
+	suite.email.On("Send", mock.Anything, mock.Anything).Return(messaging.ErrNoAddress).Once()
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	_, firstErr := suite.dispatcher.DispatchFraudAlertEvent(txn)
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.ErrorIs(suite.T(), firstErr, events.ErrUndeliverable)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	suite.sms.AssertNumberOfCalls(suite.T(), "Send", 2)
+}
+
+func (suite *EventDispatcherTestSuite) TestDigestTotalsTransactions() {
+	// Arrange
+	transactions := []models.Transaction{
+		{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", TransactionAmount: 100, ReplyCode: "4821"},
+		{AccountID: "12345678", TransactionID: "2", PhoneNumber: "19205550100", TransactionAmount: 50.25, ReplyCode: "4821"},
+	}
+	suite.sms.On("Send", messaging.RecipientOf(transactions[0]), mock.MatchedBy(func(message messaging.Message) bool {
+		return message.Type == models.NotificationFraudDigest &&
+			strings.Contains(message.Body, "2 suspicious transactions on your card ending in 5678 totaling $150.25") &&
+			strings.Contains(message.Body, "reply YES 4821")
+	})).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudDigestEvent(transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	suite.sms.AssertExpectations(suite.T())
+}
+
+func (suite *EventDispatcherTestSuite) TestAlertUsesLanguagePreference() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", TransactionAmount: 42.5, MerchantID: "Cafe", TransactionDate: "2024-03-05T14:30:00Z", ReplyCode: "4821"}
+	preferences := models.NewAccountPreferences("12345678")
+	preferences.Language = "es"
+	assert.NoError(suite.T(), suite.preferences.SavePreferences(context.Background(), preferences))
+	suite.sms.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	_, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	message := suite.sms.Calls[0].Arguments.Get(1).(messaging.Message)
+	assert.Equal(suite.T(), "es", message.Locale)
+	assert.Contains(suite.T(), message.Body, "terminada en 5678 por $42.50 en Cafe el 05/03/2024 a las 14:30")
+	assert.Contains(suite.T(), message.Body, "responda SI 4821")
+}
+
+func (suite *EventDispatcherTestSuite) TestReplyFallsBackToMessageLocale() {
+	// Arrange
+	suite.sms.On("Send", mock.Anything, body("Ya recibimos su respuesta a esta alerta.")).Return(nil).Once()
+
+	// Act
+	err := suite.dispatcher.DispatchFraudUpdateEvent("19205550100", models.Notification{Type: models.NotificationConversationResolved, AccountID: "12345678", Locale: "es"})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	suite.sms.AssertExpectations(suite.T())
+}
+
+func TestEventDispatcherSuite(t *testing.T) {
+	suite.Run(t, new(EventDispatcherTestSuite))
+}
@@ -1,16 +1,25 @@
 package test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/db"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/observability"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -26,10 +35,16 @@ func (m *MockEventDispatcher) DeleteTransaction(ctx context.Context, accountID s
 	return args.Error(0)
 }
 
-// GetFraudTransaction implements db.TransactionRepository.
-func (m *MockEventDispatcher) GetTransactionByNumberAndStatus(ctx context.Context, phoneNumber string, status string) ([]models.Transaction, error) {
-	args := m.Called(ctx, phoneNumber, status)
-	return nil, args.Error(1)
+// GetTransactionsByAccountAndTimeRange implements db.TransactionRepository.
+func (m *MockEventDispatcher) GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	args := m.Called(ctx, accountID, start, end)
+	return args.Get(0).([]models.Transaction), args.Error(1)
+}
+
+// GetTransactionsByDeviceAndTimeRange implements db.TransactionRepository.
+func (m *MockEventDispatcher) GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	args := m.Called(ctx, deviceID, start, end)
+	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
 // GetTransaction implements db.TransactionRepository.
@@ -44,21 +59,163 @@ func (m *MockEventDispatcher) SaveTransaction(ctx context.Context, t *models.Tra
 	return nil, "", args.Error(1)
 }
 
-// UpdateFraudTransaction implements db.TransactionRepository.
-func (m *MockEventDispatcher) UpdateFraudTransaction(ctx context.Context, phoneNumber string, isFraud bool, status string) (int, error) {
-	args := m.Called(ctx, phoneNumber, isFraud, status)
-	return 1, args.Error(1)
-}
-
 // UpdateTransaction implements db.TransactionRepository.
 func (m *MockEventDispatcher) UpdateTransaction(ctx context.Context, accountID string, transactionID string, values *models.Transaction) (*dynamodb.UpdateItemOutput, error) {
 	args := m.Called(ctx, accountID, transactionID, values)
 	return nil, args.Error(1)
 }
 
+// UpdatePendingTransaction implements db.TransactionRepository.
+func (m *MockEventDispatcher) UpdatePendingTransaction(ctx context.Context, accountID string, transactionID string, values *models.Transaction) error {
+	args := m.Called(ctx, accountID, transactionID, values)
+	return args.Error(0)
+}
+
+// UpdateAlertChannel implements db.TransactionRepository.
+func (m *MockEventDispatcher) UpdateAlertChannel(ctx context.Context, accountID string, transactionID string, channel string) error {
+	args := m.Called(ctx, accountID, transactionID, channel)
+	return args.Error(0)
+}
+
+// UpdateReplyCode implements db.TransactionRepository.
+func (m *MockEventDispatcher) UpdateReplyCode(ctx context.Context, accountID string, transactionID string, replyCode string) error {
+	args := m.Called(ctx, accountID, transactionID, replyCode)
+	return args.Error(0)
+}
+
 // DispatchFraudUpdateEvent implements events.EventDispatcher.
-func (m *MockEventDispatcher) DispatchFraudUpdateEvent(number string, body string) error {
-	args := m.Called(number, body)
+func (m *MockEventDispatcher) DispatchFraudUpdateEvent(number string, notification models.Notification) error {
+	args := m.Called(number, notification)
+	return args.Error(0)
+}
+
+type MockAccountProfileRepository struct {
+	mock.Mock
+}
+
+// GetDeviceProfile implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(*models.DeviceProfile), args.Error(1)
+}
+
+// RecordDeviceSighting implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) RecordDeviceSighting(ctx context.Context, transaction models.Transaction, seenAt time.Time) error {
+	args := m.Called(ctx, transaction, seenAt)
+	return args.Error(0)
+}
+
+// GetSpendingBaselines implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(map[string]*models.SpendingBaseline), args.Error(1)
+}
+
+// RecordSpending implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) RecordSpending(ctx context.Context, transaction models.Transaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+// GetPreferences implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) GetPreferences(ctx context.Context, accountID string) (*models.AccountPreferences, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(*models.AccountPreferences), args.Error(1)
+}
+
+// SavePreferences implements db.AccountProfileRepository.
+func (m *MockAccountProfileRepository) SavePreferences(ctx context.Context, preferences *models.AccountPreferences) error {
+	args := m.Called(ctx, preferences)
+	return args.Error(0)
+}
+
+type MockListRepository struct {
+	mock.Mock
+}
+
+// GetListEntries implements db.ListRepository.
+func (m *MockListRepository) GetListEntries(ctx context.Context, listKeys []string) ([]models.ListEntry, error) {
+	args := m.Called(ctx, listKeys)
+	return args.Get(0).([]models.ListEntry), args.Error(1)
+}
+
+// PutListEntry implements db.ListRepository.
+func (m *MockListRepository) PutListEntry(ctx context.Context, entry models.ListEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// RemoveListEntry implements db.ListRepository.
+func (m *MockListRepository) RemoveListEntry(ctx context.Context, kind string, value string, actor string, reason string) error {
+	args := m.Called(ctx, kind, value, actor, reason)
+	return args.Error(0)
+}
+
+// GetListAudit implements db.ListRepository.
+func (m *MockListRepository) GetListAudit(ctx context.Context, kind string, value string) ([]models.ListAuditRecord, error) {
+	args := m.Called(ctx, kind, value)
+	return args.Get(0).([]models.ListAuditRecord), args.Error(1)
+}
+
+type MockConversationRepository struct {
+	mock.Mock
+}
+
+// StartConversation implements db.ConversationRepository.
+func (m *MockConversationRepository) StartConversation(ctx context.Context, conversation *models.Conversation) (bool, error) {
+	args := m.Called(ctx, conversation)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetOpenConversations implements db.ConversationRepository.
+func (m *MockConversationRepository) GetOpenConversations(ctx context.Context, phoneNumber string) ([]models.Conversation, error) {
+	args := m.Called(ctx, phoneNumber)
+	return args.Get(0).([]models.Conversation), args.Error(1)
+}
+
+// UpdateConversation implements db.ConversationRepository.
+func (m *MockConversationRepository) UpdateConversation(ctx context.Context, conversation *models.Conversation, expectedState string) error {
+	args := m.Called(ctx, conversation, expectedState)
+	return args.Error(0)
+}
+
+// GetAwaitingConversations implements db.ConversationRepository.
+func (m *MockConversationRepository) GetAwaitingConversations(ctx context.Context, createdBefore time.Time) ([]models.Conversation, error) {
+	args := m.Called(ctx, createdBefore)
+	return args.Get(0).([]models.Conversation), args.Error(1)
+}
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+// FlagTransaction implements db.OutboxRepository.
+func (m *MockOutboxRepository) FlagTransaction(ctx context.Context, txn *models.Transaction, notification *models.OutboxNotification) error {
+	args := m.Called(ctx, txn, notification)
+	return args.Error(0)
+}
+
+// GetNotification implements db.OutboxRepository.
+func (m *MockOutboxRepository) GetNotification(ctx context.Context, outboxID string) (*models.OutboxNotification, error) {
+	args := m.Called(ctx, outboxID)
+	return args.Get(0).(*models.OutboxNotification), args.Error(1)
+}
+
+// GetPendingNotifications implements db.OutboxRepository.
+func (m *MockOutboxRepository) GetPendingNotifications(ctx context.Context, createdBefore time.Time) ([]models.OutboxNotification, error) {
+	args := m.Called(ctx, createdBefore)
+	return args.Get(0).([]models.OutboxNotification), args.Error(1)
+}
+
+// MarkSent implements db.OutboxRepository.
+func (m *MockOutboxRepository) MarkSent(ctx context.Context, outboxID string, channel string, now time.Time) error {
+	args := m.Called(ctx, outboxID, channel, now)
+	return args.Error(0)
+}
+
+// MarkUndeliverable implements db.OutboxRepository.
+func (m *MockOutboxRepository) MarkUndeliverable(ctx context.Context, outboxID string, reason string, now time.Time) error {
+	args := m.Called(ctx, outboxID, reason, now)
 	return args.Error(0)
 }
 
@@ -66,9 +223,26 @@ type MockFraudService struct {
 	mock.Mock
 }
 
-func (m *MockEventDispatcher) DispatchFraudAlertEvent(txn models.Transaction) error {
+func (m *MockEventDispatcher) DispatchFraudAlertEvent(txn models.Transaction) (string, error) {
 	args := m.Called(txn)
-	return args.Error(0)
+	return args.String(0), args.Error(1)
+}
+
+// DispatchFraudEscalationEvent implements events.EventDispatcher.
+func (m *MockEventDispatcher) DispatchFraudEscalationEvent(txn models.Transaction) (string, error) {
+	args := m.Called(txn)
+	return args.String(0), args.Error(1)
+}
+
+// DispatchFraudDigestEvent implements events.EventDispatcher.
+func (m *MockEventDispatcher) DispatchFraudDigestEvent(transactions []models.Transaction) (string, error) {
+	args := m.Called(transactions)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFraudService) ChallengerStats() map[string]fraud.ChallengerStats {
+	args := m.Called()
+	return args.Get(0).(map[string]fraud.ChallengerStats)
 }
 
 func (m *MockFraudService) PredictFraud(ctx context.Context, transactions []models.Transaction) ([]models.Transaction, []models.Transaction, error) {
@@ -78,15 +252,35 @@ func (m *MockFraudService) PredictFraud(ctx context.Context, transactions []mode
 
 type PredictFraudTestSuite struct {
 	suite.Suite
-	mockEventDispatcher       *MockEventDispatcher
-	mockFraudService          *MockFraudService
-	mockTransactionRepository *MockTransactionRepository
+	mockEventDispatcher        *MockEventDispatcher
+	mockFraudService           *MockFraudService
+	mockTransactionRepository  *MockTransactionRepository
+	mockProfileRepository      *MockAccountProfileRepository
+	mockListRepository         *MockListRepository
+	mockConversationRepository *MockConversationRepository
+	mockOutboxRepository       *MockOutboxRepository
 }
 
 func (suite *PredictFraudTestSuite) SetupTest() {
 	suite.mockEventDispatcher = new(MockEventDispatcher)
 	suite.mockFraudService = new(MockFraudService)
 	suite.mockTransactionRepository = new(MockTransactionRepository)
+	suite.mockProfileRepository = new(MockAccountProfileRepository)
+	suite.mockListRepository = new(MockListRepository)
+	suite.mockConversationRepository = new(MockConversationRepository)
+	suite.mockOutboxRepository = new(MockOutboxRepository)
+
+	// No account or device history unless a test says otherwise
+	for _, m := range []*mock.Mock{&suite.mockEventDispatcher.Mock, &suite.mockTransactionRepository.Mock} {
+		m.On("GetTransactionsByAccountAndTimeRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.Transaction{}, nil).Maybe()
+		m.On("GetTransactionsByDeviceAndTimeRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.Transaction{}, nil).Maybe()
+	}
+	suite.mockProfileRepository.On("GetDeviceProfile", mock.Anything, mock.Anything).Return(models.NewDeviceProfile(""), nil).Maybe()
+	suite.mockProfileRepository.On("GetSpendingBaselines", mock.Anything, mock.Anything).Return(map[string]*models.SpendingBaseline{}, nil).Maybe()
+	suite.mockListRepository.On("GetListEntries", mock.Anything, mock.Anything).Return([]models.ListEntry{}, nil).Maybe()
+	suite.mockConversationRepository.On("GetOpenConversations", mock.Anything, mock.Anything).Return([]models.Conversation{}, nil).Maybe()
+	suite.mockConversationRepository.On("StartConversation", mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	suite.mockFraudService.On("ChallengerStats").Return(map[string]fraud.ChallengerStats{}).Maybe()
 }
 
 // Fraud Service Tests
@@ -114,7 +308,9 @@ func (suite *PredictFraudTestSuite) TestNoFraudDetected() {
 			return t.Email == "anotheruser@example.com" && t.TransactionStatus == "APPROVED"
 		}),
 	).Return(nil, nil).Once()
-	fraudService := services.NewFraudService(suite.mockEventDispatcher, suite.mockTransactionRepository)
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.Anything, mock.Anything).Return(nil).Twice()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.Anything).Return(nil).Twice()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
 
 	// Act
 	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
@@ -122,50 +318,104 @@ func (suite *PredictFraudTestSuite) TestNoFraudDetected() {
 	// Assert
 	assert.NoError(suite.T(), err, "Should not return an error for non-fraud transactions")
 	assert.Empty(suite.T(), failedTransactions)
+	suite.mockProfileRepository.AssertExpectations(suite.T())
 }
 
 func (suite *PredictFraudTestSuite) TestFraudDetected() {
 	ctx := context.Background()
 	// Arrange
 	transactions := []models.Transaction{
-		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1"},
+		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1", TransactionAmount: 1500, LoginAttempts: 5},
 	}
 
-	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", transactions[0]).Return(nil).Once()
+	suite.mockOutboxRepository.On("FlagTransaction", ctx, mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionID == "1" && t.RiskScore == fraud.MaxRiskScore && t.TransactionStatus == "POTENTIAL_FRAUD"
+	}), mock.MatchedBy(func(n *models.OutboxNotification) bool {
+		return n.OutboxID == models.AlertOutboxID("1", "1") && n.IsPending()
+	})).Return(nil).Once()
 
-	suite.mockEventDispatcher.On("UpdateTransaction", ctx, "1", "1", mock.MatchedBy(func(t *models.Transaction) bool {
-		return t.AccountID == "1" && t.TransactionID == "1"
-	})).Return(nil, nil).Once()
+	fraudService := services.NewFraudService(suite.mockEventDispatcher, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err, "Should not return an error when fraud alert is successfully recorded")
+	assert.Empty(suite.T(), failedTransactions)
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
 
-	fraudService := services.NewFraudService(suite.mockEventDispatcher, suite.mockEventDispatcher)
+func (suite *PredictFraudTestSuite) TestRetriedAlertKeepsReplyCode() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1", PhoneNumber: "19205550100", TransactionAmount: 1500, LoginAttempts: 5},
+	}
+	suite.mockConversationRepository = new(MockConversationRepository)
+	suite.mockConversationRepository.On("GetOpenConversations", ctx, "19205550100").Return([]models.Conversation{
+		{PhoneNumber: "19205550100", AlertID: "7", ReplyCode: "1234", State: models.ConversationAwaitingReply},
+		{PhoneNumber: "19205550100", AlertID: "1", ReplyCode: "4821", State: models.ConversationAwaitingReply},
+	}, nil).Once()
+	suite.mockOutboxRepository.On("FlagTransaction", ctx, mock.MatchedBy(func(t *models.Transaction) bool {
+		alert, err := templates.BundledCatalog().Render(models.NewTransactionNotification(models.NotificationFraudAlert, *t), "")
+		return err == nil && t.ReplyCode == "4821" && t.TransactionStatus == "POTENTIAL_FRAUD" && strings.Contains(alert.Body, "reply NO 4821")
+	}), mock.Anything).Return(db.ErrNotificationRecorded).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
 
 	// Act
 	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
 
 	// Assert
-	assert.NoError(suite.T(), err, "Should not return an error when fraud alert is successfully dispatched")
+	assert.NoError(suite.T(), err)
 	assert.Empty(suite.T(), failedTransactions)
-	suite.mockEventDispatcher.AssertExpectations(suite.T())
+	suite.mockConversationRepository.AssertNotCalled(suite.T(), "StartConversation", mock.Anything, mock.Anything)
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestConversationFailureSkipsAlert() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1", PhoneNumber: "19205550100", TransactionAmount: 1500, LoginAttempts: 5},
+	}
+	suite.mockConversationRepository = new(MockConversationRepository)
+	suite.mockConversationRepository.On("GetOpenConversations", ctx, "19205550100").Return([]models.Conversation{}, nil).Once()
+	suite.mockConversationRepository.On("StartConversation", ctx, mock.MatchedBy(func(c *models.Conversation) bool {
+		return c.AlertID == "1" && c.PhoneNumber == "19205550100" && c.State == models.ConversationAwaitingReply
+	})).Return(false, errors.New("throttled")).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failedTransactions, 1)
+	suite.mockConversationRepository.AssertExpectations(suite.T())
+	suite.mockOutboxRepository.AssertNotCalled(suite.T(), "FlagTransaction", mock.Anything, mock.Anything, mock.Anything)
 }
 
-func (suite *PredictFraudTestSuite) TestFraudDispatchFails() {
+func (suite *PredictFraudTestSuite) TestFraudFlagFails() {
 	ctx := context.Background()
 	// Arrange
 	transactions := []models.Transaction{
-		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1"},
+		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1", TransactionAmount: 1500, LoginAttempts: 5},
 	}
 
-	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", transactions[0]).Return(errors.New("dispatch error")).Once()
-	fraudService := services.NewFraudService(suite.mockEventDispatcher, suite.mockTransactionRepository)
+	suite.mockOutboxRepository.On("FlagTransaction", ctx, mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionID == "1" && t.RiskScore == fraud.MaxRiskScore
+	}), mock.Anything).Return(errors.New("transaction canceled")).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
 
 	// Act
 
 	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
 
 	// Assert
-	assert.Error(suite.T(), err, "Should return an error when fraud alert dispatch fails")
+	assert.Error(suite.T(), err, "Should return an error when the fraud alert cannot be recorded")
 	assert.Len(suite.T(), failedTransactions, 1)
-	suite.mockEventDispatcher.AssertExpectations(suite.T())
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
 }
 
 func (suite *PredictFraudTestSuite) TestConcurrentTransactions() {
@@ -173,39 +423,44 @@ func (suite *PredictFraudTestSuite) TestConcurrentTransactions() {
 	// Arrange
 	transactions := []models.Transaction{
 		{Email: "jalarsen5@wisc.edu", AccountID: "1", TransactionID: "1"},
-		{Email: "rshart@wisc.edu", AccountID: "2", TransactionID: "2"},
-		{Email: "jpoconnell4@wisc.edu", AccountID: "3", TransactionID: "3"},
+		{Email: "rshart@wisc.edu", AccountID: "2", TransactionID: "2", LoginAttempts: 5},
+		{Email: "jpoconnell4@wisc.edu", AccountID: "3", TransactionID: "3", TransactionAmount: 1500, TransactionDuration: 2},
 	}
 	suite.mockTransactionRepository.On(
 		"UpdateTransaction",
 		ctx,
 		"1", "1",
 		mock.MatchedBy(func(t *models.Transaction) bool {
-			return t.Email == "jalarsen5@wisc.edu" && t.TransactionStatus == "APPROVED"
+			return t.Email == "jalarsen5@wisc.edu" && t.TransactionStatus == "APPROVED" && t.RiskScore == 0 && len(t.ReasonCodes) == 0
 		}),
 	).Return(nil, nil).Once()
 
-	suite.mockTransactionRepository.On(
-		"UpdateTransaction",
+	suite.mockOutboxRepository.On(
+		"FlagTransaction",
 		ctx,
-		"2", "2",
 		mock.MatchedBy(func(t *models.Transaction) bool {
-			return t.Email == "rshart@wisc.edu" && t.TransactionStatus == "POTENTIAL_FRAUD"
+			return t.Email == "rshart@wisc.edu" && t.TransactionStatus == "POTENTIAL_FRAUD" &&
+				t.RiskScore == fraud.LoginAttemptsWeight && slices.Equal(t.ReasonCodes, []string{fraud.ReasonExcessLoginAttempts})
 		}),
-	).Return(nil, nil).Once()
+		mock.Anything,
+	).Return(nil).Once()
 
-	suite.mockTransactionRepository.On(
-		"UpdateTransaction",
+	suite.mockOutboxRepository.On(
+		"FlagTransaction",
 		ctx,
-		"3", "3",
 		mock.MatchedBy(func(t *models.Transaction) bool {
-			return t.Email == "jpoconnell4@wisc.edu" && t.TransactionStatus == "POTENTIAL_FRAUD"
+			return t.Email == "jpoconnell4@wisc.edu" && t.TransactionStatus == "POTENTIAL_FRAUD" &&
+				slices.Equal(t.ReasonCodes, []string{fraud.ReasonHighAmount, fraud.ReasonUnusualDuration})
 		}),
-	).Return(nil, nil).Once()
-
-	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", transactions[1]).Return(nil).Once()
-	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", transactions[2]).Return(nil).Once()
-	fraudService := services.NewFraudService(suite.mockEventDispatcher, suite.mockTransactionRepository)
+		mock.Anything,
+	).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "1"
+	}), mock.Anything).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "1"
+	})).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
 
 	// Act
 	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
@@ -214,7 +469,308 @@ func (suite *PredictFraudTestSuite) TestConcurrentTransactions() {
 	assert.NoError(suite.T(), err, "Should not return error for multiple transactions")
 	assert.Empty(suite.T(), failedTransactions)
 	suite.mockTransactionRepository.AssertExpectations(suite.T())
-	suite.mockEventDispatcher.AssertExpectations(suite.T())
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
+	suite.mockProfileRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestSightingFailureDoesNotFailTransaction() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1", DeviceID: "D1"},
+	}
+	suite.mockTransactionRepository.On("UpdateTransaction", ctx, "1", "1", mock.Anything).Return(nil, nil).Once()
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.Anything, mock.Anything).Return(errors.New("profile error")).Once()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.Anything).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions, "a retry would count the approved transaction in the baselines again")
+	suite.mockProfileRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestChallengerRunsInShadow() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1"},
+		{Email: "rshart@wisc.edu", AccountID: "2", TransactionID: "2", LoginAttempts: 5},
+	}
+	suite.mockTransactionRepository.On("UpdateTransaction", ctx, "1", "1", mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "APPROVED" && len(t.ShadowDecisions) == 1 &&
+			t.ShadowDecisions[0].Challenger == "strict" && t.ShadowDecisions[0].IsFraud && t.ShadowDecisions[0].Disagrees
+	})).Return(nil, nil).Once()
+	suite.mockOutboxRepository.On("FlagTransaction", ctx, mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "POTENTIAL_FRAUD" && len(t.ShadowDecisions) == 1 && !t.ShadowDecisions[0].Disagrees
+	}), mock.Anything).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.Anything).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+	fraudService.AddChallenger("strict", fraud.NewRuleEngine(0))
+
+	// Act
+	fraudulentTransactions, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions)
+	assert.Len(suite.T(), fraudulentTransactions, 1)
+	assert.Equal(suite.T(), map[string]fraud.ChallengerStats{"strict": {Evaluated: 2, Disagreements: 1}}, fraudService.ChallengerStats())
+	suite.mockTransactionRepository.AssertExpectations(suite.T())
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestChallengerErrorDoesNotFailTransaction() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1"},
+	}
+	suite.mockTransactionRepository.On("UpdateTransaction", ctx, "1", "1", mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "APPROVED" && len(t.ShadowDecisions) == 1 && t.ShadowDecisions[0].Error != ""
+	})).Return(nil, nil).Once()
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.Anything).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+	fraudService.AddChallenger("broken", fraud.NewRuleEngine(600, failingRule{}))
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions)
+	assert.Equal(suite.T(), 1, fraudService.ChallengerStats()["broken"].Errors)
+	suite.mockTransactionRepository.AssertExpectations(suite.T())
+}
+
+// waitingDetector never decides, and reports when its context is cancelled
+type waitingDetector struct {
+	cancelled chan struct{}
+}
+
+func (d *waitingDetector) Evaluate(ctx context.Context, txn models.Transaction) (*fraud.Decision, error) {
+	<-ctx.Done()
+	close(d.cancelled)
+	return nil, ctx.Err()
+}
+
+func (suite *PredictFraudTestSuite) TestChampionErrorCancelsChallengers() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1"},
+	}
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+	fraudService.Detector = fraud.NewRuleEngine(600, failingRule{})
+	challenger := &waitingDetector{cancelled: make(chan struct{})}
+	fraudService.AddChallenger("waiting", challenger)
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failedTransactions, 1)
+	select {
+	case <-challenger.cancelled:
+	case <-time.After(time.Second):
+		suite.Fail("the challenger was not cancelled")
+	}
+}
+
+func (suite *PredictFraudTestSuite) TestChallengerMetricsAreEmbedded() {
+	// Arrange
+	var output bytes.Buffer
+	now := time.Unix(1700000000, 0)
+
+	// Act
+	err := observability.WriteChallengerMetrics(&output, "GreenFlag", models.ShadowDecision{Challenger: "strict", IsFraud: true, Disagrees: true}, now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	var document map[string]interface{}
+	assert.NoError(suite.T(), json.Unmarshal(output.Bytes(), &document))
+	assert.Equal(suite.T(), "strict", document["Challenger"])
+	assert.EqualValues(suite.T(), 1, document["ChallengerEvaluated"])
+	assert.EqualValues(suite.T(), 1, document["ChallengerDisagreements"])
+	assert.EqualValues(suite.T(), 0, document["ChallengerErrors"])
+	metadata := document["_aws"].(map[string]interface{})
+	assert.EqualValues(suite.T(), now.UnixMilli(), metadata["Timestamp"])
+	directive := metadata["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(suite.T(), "GreenFlag", directive["Namespace"])
+	assert.Equal(suite.T(), []interface{}{[]interface{}{"Challenger"}}, directive["Dimensions"])
+}
+
+func (suite *PredictFraudTestSuite) TestDenylistForcesFraudWithoutScoring() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1", IPAddress: "203.0.113.7", TransactionAmount: 10},
+	}
+	entry, _ := models.NewListEntry(models.ListDeny, models.ListKindIP, "203.0.113.7", "card testing", "analyst", 0, time.Now())
+	suite.mockListRepository = new(MockListRepository)
+	suite.mockListRepository.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{*entry}, nil).Once()
+	suite.mockOutboxRepository.On("FlagTransaction", ctx, mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "POTENTIAL_FRAUD" && t.RiskScore == fraud.MaxRiskScore && t.ListMatch != nil &&
+			t.ListMatch.List == models.ListDeny && assert.ObjectsAreEqual([]string{"DENYLIST_IP"}, t.ReasonCodes)
+	}), mock.Anything).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+	fraudService.AddChallenger("shadow", fraud.NewRuleEngine(600, failingRule{}))
+
+	// Act
+	fraudulentTransactions, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions)
+	assert.Len(suite.T(), fraudulentTransactions, 1)
+	assert.Equal(suite.T(), "203.0.113.7", fraudulentTransactions[0].ListMatch.Value)
+	assert.Zero(suite.T(), fraudService.ChallengerStats()["shadow"].Evaluated)
+	suite.mockOutboxRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestAllowlistApprovesWithoutScoring() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "rshart@wisc.edu", AccountID: "1", TransactionID: "1", MerchantID: "M100", TransactionAmount: 1500, LoginAttempts: 5},
+	}
+	entry, _ := models.NewListEntry(models.ListAllow, models.ListKindMerchant, "M100", "trusted payroll merchant", "analyst", time.Hour, time.Now())
+	suite.mockListRepository = new(MockListRepository)
+	suite.mockListRepository.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{*entry}, nil).Once()
+	suite.mockTransactionRepository.On("UpdateTransaction", ctx, "1", "1", mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "APPROVED" && t.ListMatch != nil && t.ListMatch.Kind == models.ListKindMerchant
+	})).Return(nil, nil).Once()
+	suite.mockProfileRepository.On("RecordDeviceSighting", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	suite.mockProfileRepository.On("RecordSpending", ctx, mock.Anything).Return(nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	fraudulentTransactions, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions)
+	assert.Empty(suite.T(), fraudulentTransactions)
+	suite.mockOutboxRepository.AssertNotCalled(suite.T(), "FlagTransaction", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockTransactionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestAccountAllowlistScoresWithoutAlerting() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "rshart@wisc.edu", AccountID: "TEST-1", TransactionID: "1", TransactionAmount: 1500, LoginAttempts: 5},
+	}
+	entry, _ := models.NewListEntry(models.ListAllow, models.ListKindAccount, "TEST-1", "load test account", "analyst", 0, time.Now())
+	suite.mockListRepository = new(MockListRepository)
+	suite.mockListRepository.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{*entry}, nil).Once()
+	suite.mockTransactionRepository.On("UpdateTransaction", ctx, "TEST-1", "1", mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.TransactionStatus == "POTENTIAL_FRAUD" && t.RiskScore == fraud.MaxRiskScore &&
+			t.ListMatch != nil && t.ListMatch.Kind == models.ListKindAccount
+	})).Return(nil, nil).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	fraudulentTransactions, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failedTransactions)
+	assert.Len(suite.T(), fraudulentTransactions, 1)
+	assert.NotEmpty(suite.T(), fraudulentTransactions[0].ReasonCodes)
+	suite.mockOutboxRepository.AssertNotCalled(suite.T(), "FlagTransaction", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockConversationRepository.AssertNotCalled(suite.T(), "StartConversation", mock.Anything, mock.Anything)
+	suite.mockTransactionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *PredictFraudTestSuite) TestMatchListPrefersForcedDecisionOverSuppression() {
+	ctx := context.Background()
+	// Arrange
+	now := time.Now()
+	txn := models.Transaction{AccountID: "TEST-1", MerchantID: "M100"}
+	account, _ := models.NewListEntry(models.ListAllow, models.ListKindAccount, "TEST-1", "", "analyst", 0, now)
+	merchant, _ := models.NewListEntry(models.ListAllow, models.ListKindMerchant, "M100", "", "analyst", 0, now)
+	lists := new(MockListRepository)
+	lists.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{*account, *merchant}, nil).Once()
+
+	// Act
+	match, err := fraud.MatchList(ctx, lists, txn, now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ListKindMerchant, match.Kind)
+	assert.True(suite.T(), fraud.SuppressesAlerts(account.Match()))
+	assert.False(suite.T(), fraud.SuppressesAlerts(merchant.Match()))
+}
+
+func (suite *PredictFraudTestSuite) TestMatchListPrefersDenyAndSkipsExpired() {
+	ctx := context.Background()
+	// Arrange
+	now := time.Now()
+	txn := models.Transaction{AccountID: "1", MerchantID: "M100", DeviceID: "D1", IPAddress: "203.0.113.7"}
+	allow, _ := models.NewListEntry(models.ListAllow, models.ListKindMerchant, "M100", "", "analyst", 0, now)
+	deny, _ := models.NewListEntry(models.ListDeny, models.ListKindDevice, "D1", "", "analyst", 0, now)
+	expired, _ := models.NewListEntry(models.ListDeny, models.ListKindIP, "203.0.113.7", "", "analyst", time.Minute, now.Add(-time.Hour))
+	lists := new(MockListRepository)
+	lists.On("GetListEntries", ctx, mock.MatchedBy(func(keys []string) bool { return len(keys) == 4 })).
+		Return([]models.ListEntry{*expired, *allow, *deny}, nil).Once()
+	lists.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{*expired}, nil).Once()
+
+	// Act
+	match, err := fraud.MatchList(ctx, lists, txn, now)
+	expiredMatch, expiredErr := fraud.MatchList(ctx, lists, models.Transaction{IPAddress: "203.0.113.7"}, now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ListKey(models.ListKindDevice, "D1"), match.ListKey)
+	assert.NoError(suite.T(), expiredErr)
+	assert.Nil(suite.T(), expiredMatch)
+}
+
+func (suite *PredictFraudTestSuite) TestListLookupFailureFailsTransaction() {
+	ctx := context.Background()
+	// Arrange
+	transactions := []models.Transaction{
+		{Email: "safeuser@example.com", AccountID: "1", TransactionID: "1"},
+	}
+	suite.mockListRepository = new(MockListRepository)
+	suite.mockListRepository.On("GetListEntries", ctx, mock.Anything).Return([]models.ListEntry{}, errors.New("throttled")).Once()
+	fraudService := services.NewFraudService(suite.mockTransactionRepository, suite.mockProfileRepository, suite.mockListRepository, suite.mockConversationRepository, nil, suite.mockOutboxRepository)
+
+	// Act
+	_, failedTransactions, err := fraudService.PredictFraud(ctx, transactions)
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failedTransactions, 1)
+	suite.mockTransactionRepository.AssertNotCalled(suite.T(), "UpdateTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PredictFraudTestSuite) TestNewListEntryValidates() {
+	// Arrange
+	now := time.Now()
+
+	// Act
+	entry, err := models.NewListEntry("deny", "ip", "203.0.113.7", "card testing", "analyst", time.Hour, now)
+	_, unknownListErr := models.NewListEntry("maybe", "ip", "203.0.113.7", "", "analyst", 0, now)
+	_, unknownKindErr := models.NewListEntry("deny", "email", "a@b.com", "", "analyst", 0, now)
+	_, noActorErr := models.NewListEntry("deny", "ip", "203.0.113.7", "", "", 0, now)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "IP#203.0.113.7", entry.ListKey)
+	assert.Equal(suite.T(), now.Add(time.Hour).Unix(), entry.ExpiresAt)
+	assert.False(suite.T(), entry.IsExpired(now))
+	assert.True(suite.T(), entry.IsExpired(now.Add(2*time.Hour)))
+	assert.Error(suite.T(), unknownListErr)
+	assert.Error(suite.T(), unknownKindErr)
+	assert.Error(suite.T(), noActorErr)
 }
 
 // Fraud Detection Handler Tests
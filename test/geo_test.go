@@ -0,0 +1,164 @@
+package test
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/geo"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type GeoTestSuite struct {
+	suite.Suite
+	ctx     context.Context
+	dataset *geo.Dataset
+	history *fakeTransactionHistory
+}
+
+func (suite *GeoTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.dataset = geo.BundledDataset()
+	suite.history = &fakeTransactionHistory{}
+}
+
+func (suite *GeoTestSuite) TestBundledDataset_ResolvesCitiesButNotIPs() {
+	// Act
+	newYork, newYorkOk := suite.dataset.LookupCity(" new york ")
+	seattle, seattleOk := suite.dataset.LookupCity("Seattle")
+	_, unknownCityOk := suite.dataset.LookupCity("Atlantis")
+	_, ipOk := suite.dataset.LookupIP("203.0.113.42")
+
+	// Assert
+	assert.True(suite.T(), newYorkOk)
+	assert.True(suite.T(), seattleOk)
+	assert.False(suite.T(), unknownCityOk)
+	assert.False(suite.T(), ipOk, "documentation addresses are never in the bundled IP ranges")
+	assert.InDelta(suite.T(), 3866, geo.DistanceKm(newYork, seattle), 10)
+}
+
+func (suite *GeoTestSuite) TestLoadDatasetFile_FromLocalDisk() {
+	// Arrange
+	path := filepath.Join(suite.T().TempDir(), "locations.csv")
+	contents := "type,key,latitude,longitude\ncity,Springfield,39.7817,-89.6501\ncidr,10.1.0.0/16,39.7817,-89.6501\n"
+	assert.NoError(suite.T(), os.WriteFile(path, []byte(contents), 0o600))
+
+	// Act
+	dataset, err := geo.LoadDatasetFile(path)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	_, cityOk := dataset.LookupCity("Springfield")
+	_, ipOk := dataset.LookupIP("10.1.2.3")
+	assert.True(suite.T(), cityOk)
+	assert.True(suite.T(), ipOk)
+}
+
+func (suite *GeoTestSuite) TestLoadDataset_PrefersMostSpecificNetwork() {
+	// Arrange
+	contents := "type,key,latitude,longitude\ncidr,10.0.0.0/8,40.7128,-74.0060\ncidr,10.1.0.0/16,47.6062,-122.3321\ncidr,2001:db8::/32,51.5074,-0.1278\n"
+	dataset, err := geo.LoadDataset(strings.NewReader(contents))
+	assert.NoError(suite.T(), err)
+
+	// Act
+	seattle, seattleOk := dataset.LookupIP("10.1.2.3")
+	newYork, newYorkOk := dataset.LookupIP("10.2.0.1")
+	mapped, mappedOk := dataset.LookupIP("::ffff:10.1.2.3")
+	london, londonOk := dataset.LookupIP("2001:db8::1")
+	_, outsideOk := dataset.LookupIP("11.0.0.1")
+
+	// Assert
+	assert.True(suite.T(), seattleOk)
+	assert.Equal(suite.T(), 47.6062, seattle.Latitude)
+	assert.True(suite.T(), newYorkOk)
+	assert.Equal(suite.T(), 40.7128, newYork.Latitude)
+	assert.True(suite.T(), mappedOk)
+	assert.Equal(suite.T(), seattle, mapped)
+	assert.True(suite.T(), londonOk)
+	assert.Equal(suite.T(), 51.5074, london.Latitude)
+	assert.False(suite.T(), outsideOk)
+}
+
+func (suite *GeoTestSuite) TestLoadDataset_RejectsInvalidRows() {
+	// Act
+	_, err := geo.LoadDataset(strings.NewReader("type,key,latitude,longitude\ncity,Nowhere,north,west\n"))
+
+	// Assert
+	assert.Error(suite.T(), err)
+}
+
+func (suite *GeoTestSuite) TestBundledDataset_ResolvesTimeZones() {
+	// Act
+	seattle, ok := suite.dataset.LookupTimeZone(" seattle ")
+	_, unknownOk := suite.dataset.LookupTimeZone("Atlantis")
+
+	// Assert
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), "America/Los_Angeles", seattle)
+	assert.False(suite.T(), unknownOk)
+}
+
+func (suite *GeoTestSuite) TestLoadDataset_RejectsUnknownTimeZones() {
+	// Act
+	_, err := geo.LoadDataset(strings.NewReader("type,key,latitude,longitude,timezone\ncity,Springfield,39.7817,-89.6501,America/Springfield\n"))
+
+	// Assert
+	assert.Error(suite.T(), err)
+}
+
+func (suite *GeoTestSuite) TestTravelEnricher_ComputesSpeedFromPreviousTransaction() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.Location = "Seattle"
+	previous := getHistoryTransaction(txn, -time.Hour, 20)
+	previous.Location = "New York"
+	older := getHistoryTransaction(txn, -5*time.Hour, 20)
+	older.Location = "Seattle"
+	suite.history.transactions = []models.Transaction{txn, previous, older}
+
+	signals := fraud.NewSignals(txn)
+	assert.NoError(suite.T(), fraud.NewHistoryEnricher(suite.history, fraud.DefaultHistoryLookback).Enrich(suite.ctx, signals))
+
+	// Act
+	err := fraud.NewTravelEnricher(suite.dataset).Enrich(suite.ctx, signals)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), previous.TransactionID, signals.Travel.PreviousTransactionID)
+	assert.Equal(suite.T(), time.Hour, signals.Travel.Elapsed)
+	assert.InDelta(suite.T(), 3866, signals.Travel.SpeedKmh, 10)
+}
+
+func (suite *GeoTestSuite) TestImpossibleTravelRule() {
+	rule := fraud.NewImpossibleTravelRule(900, 100)
+	cases := []struct {
+		travel   *fraud.TravelFeatures
+		expected bool
+	}{
+		{travel: nil, expected: false},
+		{travel: &fraud.TravelFeatures{DistanceKm: 3866, SpeedKmh: 3866}, expected: true},
+		{travel: &fraud.TravelFeatures{DistanceKm: 3866, SpeedKmh: math.Inf(1)}, expected: true},
+		{travel: &fraud.TravelFeatures{DistanceKm: 3866, SpeedKmh: 500}, expected: false},
+		{travel: &fraud.TravelFeatures{DistanceKm: 50, SpeedKmh: 5000}, expected: false},
+	}
+
+	for _, c := range cases {
+		// Act
+		result, err := rule.Evaluate(suite.ctx, &fraud.Signals{Travel: c.travel})
+
+		// Assert
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), c.expected, result.Triggered)
+	}
+}
+
+func TestGeoSuite(t *testing.T) {
+	suite.Run(t, new(GeoTestSuite))
+}
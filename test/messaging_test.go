@@ -7,6 +7,8 @@ import (
 
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
 	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -14,7 +16,8 @@ import (
 
 type SNSMessagingTestSuite struct {
 	suite.Suite
-	snsMessenger *messaging.GfSNSMessenger
+	emailChannel *messaging.SNSEmailChannel
+	client       *sns.Client
 	ctx          context.Context
 	topicArn     string
 }
@@ -38,16 +41,15 @@ func (s *SNSMessagingTestSuite) SetupSuite() {
 	}
 
 	topicName := config.SNSMessengerConfig.TopicName
-	twilioUsername := config.SNSMessengerConfig.TwilioUsername
-	twilioPassword := config.SNSMessengerConfig.TwilioPassword
 
 	topicArn, err := messaging.CreateTopic(client, topicName)
 	if err != nil {
 		log.Fatalf("Failed to create SNS Topic: %s\n", err)
 	}
 	s.topicArn = topicArn
+	s.client = client
 
-	s.snsMessenger = messaging.NewGfSNSMessenger(client, config.SNSMessengerConfig.TopicName, topicArn, twilioUsername, twilioPassword)
+	s.emailChannel = messaging.NewSNSEmailChannel(client, topicArn)
 }
 
 func (s *SNSMessagingTestSuite) TestSendEmailAlert() {
@@ -55,11 +57,12 @@ func (s *SNSMessagingTestSuite) TestSendEmailAlert() {
 	txn := GetTestTransaction("c1redflagstest@gmail.com")
 
 	// Act
-	output, err := s.snsMessenger.SendEmailAlert(txn)
+	alert, err := templates.BundledCatalog().Render(models.NewTransactionNotification(models.NotificationFraudAlert, txn), "")
+	assert.NoError(s.T(), err)
+	err = s.emailChannel.Send(s.ctx, messaging.RecipientOf(txn), messaging.Message{Type: models.NotificationFraudAlert, Subject: alert.Subject, Body: alert.Body})
 
 	// Assert
 	assert.NoError(s.T(), err)
-	assert.NotNil(s.T(), output)
 }
 
 func (s *SNSMessagingTestSuite) TearDownSuite() {
@@ -67,7 +70,7 @@ func (s *SNSMessagingTestSuite) TearDownSuite() {
 		TopicArn: &s.topicArn,
 	}
 
-	_, err := s.snsMessenger.Client.DeleteTopic(context.TODO(), input)
+	_, err := s.client.DeleteTopic(context.TODO(), input)
 	if err != nil {
 		log.Fatalf("Failed to delete topic: %s\n", err)
 	}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/ml"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testLogisticModel = `{
+	"type": "logistic_regression",
+	"version": "test",
+	"features": ["TransactionAmount", "LoginAttempts", "Channel=Online"],
+	"logistic_regression": {
+		"intercept": -6,
+		"coefficients": [0.002, 1.5, 0.5]
+	}
+}`
+
+const testTreeModel = `{
+	"type": "tree_ensemble",
+	"features": ["LoginAttempts", "AmountToBalanceRatio"],
+	"tree_ensemble": {
+		"objective": "binary:logistic",
+		"base_score": -1,
+		"trees": [
+			{"nodes": [
+				{"feature": 0, "threshold": 3, "left": 1, "right": 2},
+				{"leaf": true, "value": -2},
+				{"leaf": true, "value": 2}
+			]},
+			{"nodes": [
+				{"feature": 1, "threshold": 0.5, "left": 1, "right": 2},
+				{"leaf": true, "value": -1},
+				{"leaf": true, "value": 1.5}
+			]}
+		]
+	}
+}`
+
+// fakeObjectGetter serves S3 objects from memory keyed by bucket/key
+type fakeObjectGetter struct {
+	objects map[string]string
+}
+
+func (f *fakeObjectGetter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	body, ok := f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+type MLTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *MLTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *MLTestSuite) TestExtractFeatures() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 250
+	txn.AccountBalance = 1000
+	txn.Channel = "online"
+
+	// Act
+	features := ml.ExtractFeatures(txn, []string{"TransactionAmount", "AmountToBalanceRatio", "Channel=Online", "Channel=ATM"})
+
+	// Assert
+	assert.Equal(suite.T(), []float64{250, 0.25, 1, 0}, features)
+}
+
+func (suite *MLTestSuite) TestLogisticRegression_Predict() {
+	// Arrange
+	model, err := ml.ParseModel([]byte(testLogisticModel))
+	assert.NoError(suite.T(), err)
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 2000
+	txn.LoginAttempts = 3
+	txn.Channel = "Online"
+
+	// Act
+	probability := model.Predict(ml.ExtractFeatures(txn, model.FeatureNames()))
+
+	// Assert
+	// logit = -6 + 0.002*2000 + 1.5*3 + 0.5 = 3
+	assert.InDelta(suite.T(), 0.9526, probability, 1e-4)
+}
+
+func (suite *MLTestSuite) TestTreeEnsemble_Predict() {
+	// Arrange
+	model, err := ml.ParseModel([]byte(testTreeModel))
+	assert.NoError(suite.T(), err)
+
+	// Act
+	risky := model.Predict([]float64{5, 0.9})
+	safe := model.Predict([]float64{1, 0.1})
+
+	// Assert
+	assert.InDelta(suite.T(), 0.9241, risky, 1e-4)
+	assert.InDelta(suite.T(), 0.0180, safe, 1e-4)
+}
+
+func (suite *MLTestSuite) TestParseModel_RejectsInvalidModels() {
+	cases := map[string]string{
+		"unknown type":        `{"type": "svm", "features": ["LoginAttempts"]}`,
+		"unknown feature":     `{"type": "logistic_regression", "features": ["ShoeSize"], "logistic_regression": {"coefficients": [1]}}`,
+		"coefficient count":   `{"type": "logistic_regression", "features": ["LoginAttempts"], "logistic_regression": {"coefficients": [1, 2]}}`,
+		"missing section":     `{"type": "tree_ensemble", "features": ["LoginAttempts"]}`,
+		"cyclic tree":         `{"type": "tree_ensemble", "features": ["LoginAttempts"], "tree_ensemble": {"trees": [{"nodes": [{"feature": 0, "left": 0, "right": 0}]}]}}`,
+		"split feature range": `{"type": "tree_ensemble", "features": ["LoginAttempts"], "tree_ensemble": {"trees": [{"nodes": [{"feature": 3, "left": 1, "right": 2}, {"leaf": true}, {"leaf": true}]}]}}`,
+	}
+
+	for name, data := range cases {
+		// Act
+		model, err := ml.ParseModel([]byte(data))
+
+		// Assert
+		assert.Error(suite.T(), err, name)
+		assert.Nil(suite.T(), model, name)
+	}
+}
+
+func (suite *MLTestSuite) TestLoadModel_FromFileAndS3() {
+	// Arrange
+	path := filepath.Join(suite.T().TempDir(), "model.json")
+	assert.NoError(suite.T(), os.WriteFile(path, []byte(testLogisticModel), 0o600))
+	getter := &fakeObjectGetter{objects: map[string]string{"models/fraud/tree.json": testTreeModel}}
+
+	// Act
+	fileModel, fileErr := ml.LoadModel(suite.ctx, path, nil)
+	s3Model, s3Err := ml.LoadModel(suite.ctx, "s3://models/fraud/tree.json", getter)
+	_, missingErr := ml.LoadModel(suite.ctx, "s3://models/missing.json", getter)
+
+	// Assert
+	assert.NoError(suite.T(), fileErr)
+	assert.IsType(suite.T(), &ml.LogisticRegression{}, fileModel)
+	assert.NoError(suite.T(), s3Err)
+	assert.IsType(suite.T(), &ml.TreeEnsemble{}, s3Model)
+	assert.Error(suite.T(), missingErr)
+}
+
+func (suite *MLTestSuite) TestModelDetector_ScalesProbabilityToRiskScore() {
+	// Arrange
+	model, err := ml.ParseModel([]byte(testLogisticModel))
+	assert.NoError(suite.T(), err)
+	detector := fraud.NewModelDetector(model, 500)
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 2000
+	txn.LoginAttempts = 3
+	txn.Channel = "Online"
+
+	// Act
+	decision, err := detector.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), 953, decision.Score)
+	assert.Equal(suite.T(), []string{fraud.ReasonModelScore}, decision.ReasonCodes)
+}
+
+func TestMLSuite(t *testing.T) {
+	suite.Run(t, new(MLTestSuite))
+}
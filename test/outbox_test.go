@@ -0,0 +1,316 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxTestSuite struct {
+	suite.Suite
+	ctx                    context.Context
+	mockEventDispatcher    *MockEventDispatcher
+	transactionRepository  *backtest.MemoryTransactionRepository
+	conversationRepository *backtest.MemoryConversationRepository
+	outbox                 *backtest.MemoryOutboxRepository
+}
+
+func (suite *OutboxTestSuite) SetupSuite() {
+	// The relay handler reads the outbox table's key name from config
+	config.InitializeLocalConfig()
+}
+
+func (suite *OutboxTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.mockEventDispatcher = new(MockEventDispatcher)
+	suite.transactionRepository = backtest.NewMemoryTransactionRepository()
+	suite.conversationRepository = backtest.NewMemoryConversationRepository()
+	suite.outbox = backtest.NewMemoryOutboxRepository(suite.transactionRepository)
+}
+
+// fraudService flags every transaction
+func (suite *OutboxTestSuite) fraudService() *services.GfFraudService {
+	fraudService := services.NewFraudService(suite.transactionRepository, backtest.NewMemoryProfileRepository(),
+		backtest.NewMemoryListRepository(), suite.conversationRepository, nil, suite.outbox)
+	fraudService.Detector = fraud.NewRuleEngine(0)
+	return fraudService
+}
+
+func (suite *OutboxTestSuite) relayService() *services.GfRelayService {
+	return services.NewRelayService(suite.mockEventDispatcher, suite.transactionRepository, suite.outbox)
+}
+
+func (suite *OutboxTestSuite) save(transactionID string) models.Transaction {
+	txn := models.Transaction{AccountID: "12345678", TransactionID: transactionID, PhoneNumber: "19205550140", TransactionAmount: 250}
+	_, _, err := suite.transactionRepository.SaveTransaction(suite.ctx, &txn)
+	suite.Require().NoError(err)
+	return txn
+}
+
+func (suite *OutboxTestSuite) transaction(transactionID string) *models.Transaction {
+	txn, err := suite.transactionRepository.GetTransaction(suite.ctx, "12345678", transactionID)
+	suite.Require().NoError(err)
+	return txn
+}
+
+func (suite *OutboxTestSuite) notification(transactionID string) *models.OutboxNotification {
+	notification, err := suite.outbox.GetNotification(suite.ctx, models.AlertOutboxID("12345678", transactionID))
+	suite.Require().NoError(err)
+	return notification
+}
+
+func (suite *OutboxTestSuite) TestFlaggedTransactionIsRecordedNotSent() {
+	// Arrange
+	txn := suite.save("1")
+
+	// Act
+	fraudulent, failed, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Len(suite.T(), fraudulent, 1)
+	flagged := suite.transaction("1")
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", flagged.TransactionStatus)
+	assert.NotEmpty(suite.T(), flagged.ReplyCode)
+	assert.True(suite.T(), suite.notification("1").IsPending())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *OutboxTestSuite) TestRetriedTransactionIsNotRecordedAgain() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+
+	// Act
+	_, failed, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	pending, err := suite.outbox.GetPendingNotifications(suite.ctx, time.Now().Add(time.Minute))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), pending, 1)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *OutboxTestSuite) TestFailedFlagRecordsNoAlert() {
+	// Arrange
+	unsaved := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550140", TransactionAmount: 250}
+
+	// Act
+	_, failed, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{unsaved})
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failed, 1)
+	_, err = suite.outbox.GetNotification(suite.ctx, models.AlertOutboxID("12345678", "1"))
+	assert.Error(suite.T(), err)
+}
+
+func (suite *OutboxTestSuite) TestRelaySendsAlertOnce() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "1" && t.TransactionStatus == "POTENTIAL_FRAUD" && t.ReplyCode != ""
+	})).Return(messaging.ChannelSMS, nil).Once()
+	outboxID := models.AlertOutboxID("12345678", "1")
+
+	// Act
+	failed, err := suite.relayService().RelayNotifications(suite.ctx, []string{outboxID})
+	_, againErr := suite.relayService().RelayNotifications(suite.ctx, []string{outboxID})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), againErr)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), messaging.ChannelSMS, suite.transaction("1").AlertChannel)
+	sent := suite.notification("1")
+	assert.False(suite.T(), sent.IsPending())
+	assert.Equal(suite.T(), messaging.ChannelSMS, sent.Channel)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *OutboxTestSuite) TestFailedRelayIsSentBySweep() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.Anything).Return("", errors.New("twilio unavailable")).Once()
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.Anything).Return(messaging.ChannelSMS, nil).Once()
+	outboxID := models.AlertOutboxID("12345678", "1")
+
+	// Act
+	failed, failedErr := suite.relayService().RelayNotifications(suite.ctx, []string{outboxID})
+	early, earlyErr := suite.relayService().RelayPendingNotifications(suite.ctx, time.Now())
+	swept, err := suite.relayService().RelayPendingNotifications(suite.ctx, time.Now().Add(config.OutboxConfig.SweepAfter+time.Second))
+
+	// Assert
+	assert.ErrorContains(suite.T(), failedErr, "twilio unavailable")
+	assert.Equal(suite.T(), []string{outboxID}, failed)
+	assert.NoError(suite.T(), earlyErr)
+	assert.Equal(suite.T(), &services.RelaySummary{}, early, "the sweep leaves just written notifications to the stream")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &services.RelaySummary{Sent: 1}, swept)
+	assert.False(suite.T(), suite.notification("1").IsPending())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *OutboxTestSuite) TestResolvedTransactionIsNotAlerted() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	resolved := suite.transaction("1")
+	resolved.TransactionStatus = "APPROVED"
+	_, err = suite.transactionRepository.UpdateTransaction(suite.ctx, resolved.AccountID, resolved.TransactionID, resolved)
+	suite.Require().NoError(err)
+
+	// Act
+	failed, err := suite.relayService().RelayNotifications(suite.ctx, []string{models.AlertOutboxID("12345678", "1")})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.False(suite.T(), suite.notification("1").IsPending())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *OutboxTestSuite) TestUnflaggedTransactionIsRetried() {
+	// Arrange
+	txn := suite.save("1")
+	suite.Require().NoError(suite.outbox.FlagTransaction(suite.ctx, &txn, models.NewAlertOutboxNotification(txn, time.Now())))
+	outboxID := models.AlertOutboxID("12345678", "1")
+
+	// Act
+	failed, err := suite.relayService().RelayNotifications(suite.ctx, []string{outboxID})
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), []string{outboxID}, failed)
+	assert.True(suite.T(), suite.notification("1").IsPending())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudAlertEvent", mock.Anything)
+}
+
+func (suite *OutboxTestSuite) TestHeldAlertChannelIsNotRecorded() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.Anything).Return("", nil).Once()
+
+	// Act
+	failed, err := suite.relayService().RelayNotifications(suite.ctx, []string{models.AlertOutboxID("12345678", "1")})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.False(suite.T(), suite.notification("1").IsPending())
+	flagged := suite.transaction("1")
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", flagged.TransactionStatus)
+	assert.Empty(suite.T(), flagged.AlertChannel, "the deferred handler records the channel once the alert is sent")
+}
+
+func (suite *OutboxTestSuite) TestUnreachableAlertIsNotRetried() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.Anything).
+		Return("", errors.Join(events.ErrUndeliverable, events.ErrUnreachable, messaging.ErrOptedOut)).Once()
+
+	// Act
+	failed, err := suite.relayService().RelayNotifications(suite.ctx, []string{models.AlertOutboxID("12345678", "1")})
+	swept, sweepErr := suite.relayService().RelayPendingNotifications(suite.ctx, time.Now().Add(config.OutboxConfig.SweepAfter+time.Second))
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.NoError(suite.T(), sweepErr)
+	assert.Equal(suite.T(), &services.RelaySummary{}, swept)
+	undeliverable := suite.notification("1")
+	assert.False(suite.T(), undeliverable.IsPending())
+	assert.NotZero(suite.T(), undeliverable.FailedAt)
+	assert.Contains(suite.T(), undeliverable.FailureReason, "opted out")
+	assert.Empty(suite.T(), undeliverable.Channel)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *OutboxTestSuite) TestFailingAlertIsGivenUpOn() {
+	// Arrange
+	txn := suite.save("1")
+	_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+	suite.Require().NoError(err)
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.Anything).
+		Return("", errors.Join(events.ErrUndeliverable, errors.New("twilio unavailable"))).Twice()
+
+	// Act
+	retried, retriedErr := suite.relayService().RelayPendingNotifications(suite.ctx, time.Now().Add(config.OutboxConfig.SweepAfter+time.Second))
+	given, err := suite.relayService().RelayPendingNotifications(suite.ctx, time.Now().Add(config.OutboxConfig.GiveUpAfter))
+
+	// Assert
+	assert.ErrorContains(suite.T(), retriedErr, "twilio unavailable")
+	assert.Equal(suite.T(), &services.RelaySummary{Failed: 1}, retried)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &services.RelaySummary{Undeliverable: 1}, given)
+	assert.False(suite.T(), suite.notification("1").IsPending())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *OutboxTestSuite) TestOutboxStreamReportsFailedRecords() {
+	// Arrange
+	for _, transactionID := range []string{"1", "2"} {
+		txn := suite.save(transactionID)
+		_, _, err := suite.fraudService().PredictFraud(suite.ctx, []models.Transaction{txn})
+		suite.Require().NoError(err)
+	}
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "1"
+	})).Return(messaging.ChannelSMS, nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudAlertEvent", mock.MatchedBy(func(t models.Transaction) bool {
+		return t.TransactionID == "2"
+	})).Return("", errors.New("twilio unavailable")).Once()
+	record := func(transactionID string, sequenceNumber string, eventName string) lambdaevents.DynamoDBEventRecord {
+		return lambdaevents.DynamoDBEventRecord{
+			EventName: eventName,
+			Change: lambdaevents.DynamoDBStreamRecord{
+				Keys:           map[string]lambdaevents.DynamoDBAttributeValue{"OutboxID": lambdaevents.NewStringAttribute(models.AlertOutboxID("12345678", transactionID))},
+				SequenceNumber: sequenceNumber,
+			},
+		}
+	}
+	event := lambdaevents.DynamoDBEvent{Records: []lambdaevents.DynamoDBEventRecord{
+		record("1", "100", "INSERT"),
+		record("2", "200", "INSERT"),
+		record("1", "300", "MODIFY"),
+	}}
+
+	// Act
+	result, err := handlers.NewRelayHandler(suite.relayService()).ProcessOutboxEvent(suite.ctx, event)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.BatchItemFailure{{ItemIdentifier: "200"}}, result.BatchItemFailures)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func TestOutboxSuite(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
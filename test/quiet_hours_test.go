@@ -0,0 +1,290 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/events"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/geo"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/messaging"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeDeferredQueue keeps the messages held back for quiet hours
+type fakeDeferredQueue struct {
+	messages []messaging.DeferredMessage
+	err      error
+}
+
+func (q *fakeDeferredQueue) Defer(ctx context.Context, message messaging.DeferredMessage, now time.Time) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.messages = append(q.messages, message)
+	return nil
+}
+
+type QuietHoursTestSuite struct {
+	suite.Suite
+	newYork      *time.Location
+	sms          *MockNotificationChannel
+	preferences  *backtest.MemoryProfileRepository
+	queue        *fakeDeferredQueue
+	transactions *backtest.MemoryTransactionRepository
+	dispatcher   *events.GfEventDispatcher
+}
+
+func (suite *QuietHoursTestSuite) SetupTest() {
+	var err error
+	suite.newYork, err = time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+	suite.sms = NewMockNotificationChannel(messaging.ChannelSMS)
+	suite.preferences = backtest.NewMemoryProfileRepository()
+	suite.queue = &fakeDeferredQueue{}
+	suite.transactions = backtest.NewMemoryTransactionRepository()
+	suite.dispatcher = events.NewGfEventDispatcher(messaging.Channels{messaging.ChannelSMS: suite.sms}, templates.BundledCatalog(),
+		suite.preferences, nil, suite.queue)
+	suite.dispatcher.Routing = events.RoutingPolicy{Default: []string{messaging.ChannelSMS}}
+	suite.dispatcher.QuietHours = suite.quietHours(-time.Hour, 2*time.Hour)
+}
+
+// quietHours returns quiet hours in New York from an offset of the current time to another
+func (suite *QuietHoursTestSuite) quietHours(from time.Duration, to time.Duration) events.QuietHours {
+	now := time.Now().In(suite.newYork)
+	quietHours, err := events.ParseQuietHours(now.Add(from).Format("15:04"), now.Add(to).Format("15:04"), "America/New_York")
+	suite.Require().NoError(err)
+	quietHours.UrgentScore = 800
+	quietHours.Zones = geo.BundledDataset()
+	return quietHours
+}
+
+func (suite *QuietHoursTestSuite) nightly() events.QuietHours {
+	quietHours, err := events.ParseQuietHours("21:00", "08:00", "America/New_York")
+	suite.Require().NoError(err)
+	quietHours.Zones = geo.BundledDataset()
+	return quietHours
+}
+
+func (suite *QuietHoursTestSuite) TestNextAllowedWrapsPastMidnight() {
+	// Arrange
+	quietHours := suite.nightly()
+	lateEvening := time.Date(2025, 6, 10, 23, 30, 0, 0, suite.newYork)
+	earlyMorning := time.Date(2025, 6, 11, 3, 0, 0, 0, suite.newYork)
+	afternoon := time.Date(2025, 6, 11, 14, 0, 0, 0, suite.newYork)
+
+	// Act
+	afterLateEvening := quietHours.NextAllowed(lateEvening, suite.newYork)
+	afterEarlyMorning := quietHours.NextAllowed(earlyMorning, suite.newYork)
+	afterAfternoon := quietHours.NextAllowed(afternoon, suite.newYork)
+
+	// Assert
+	morning := time.Date(2025, 6, 11, 8, 0, 0, 0, suite.newYork)
+	assert.True(suite.T(), morning.Equal(afterLateEvening))
+	assert.True(suite.T(), morning.Equal(afterEarlyMorning))
+	assert.True(suite.T(), afternoon.Equal(afterAfternoon))
+}
+
+func (suite *QuietHoursTestSuite) TestNextAllowedOnNightClocksChange() {
+	// Arrange
+	beforeSpringForward := time.Date(2025, 3, 8, 22, 0, 0, 0, suite.newYork)
+
+	// Act
+	next := suite.nightly().NextAllowed(beforeSpringForward, suite.newYork)
+
+	// Assert
+	assert.Equal(suite.T(), 8, next.In(suite.newYork).Hour())
+	assert.Equal(suite.T(), 9*time.Hour, next.Sub(beforeSpringForward))
+}
+
+func (suite *QuietHoursTestSuite) TestTimeZoneIsInferred() {
+	// Arrange
+	quietHours := suite.nightly()
+
+	// Act
+	chosen := quietHours.TimeZone("Europe/London", "Seattle")
+	fromCity := quietHours.TimeZone("", "Seattle")
+	invalidChoice := quietHours.TimeZone("Mars/Olympus", "Chicago")
+	unknown := quietHours.TimeZone("", "Atlantis")
+
+	// Assert
+	assert.Equal(suite.T(), "Europe/London", chosen.String())
+	assert.Equal(suite.T(), "America/Los_Angeles", fromCity.String())
+	assert.Equal(suite.T(), "America/Chicago", invalidChoice.String())
+	assert.Equal(suite.T(), "America/New_York", unknown.String())
+}
+
+func (suite *QuietHoursTestSuite) TestReplyIsHeldDuringQuietHours() {
+	// Arrange
+	reply := models.NewTransactionNotification(models.NotificationReminder, models.Transaction{AccountID: "12345678", TransactionID: "1", ReplyCode: "4821"})
+
+	// Act
+	err := suite.dispatcher.DispatchFraudUpdateEvent("19205550100", reply)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	suite.sms.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything)
+	assert.Len(suite.T(), suite.queue.messages, 1)
+	held := suite.queue.messages[0]
+	assert.True(suite.T(), held.Reply)
+	assert.Equal(suite.T(), "19205550100", held.Recipient.PhoneNumber)
+	assert.False(suite.T(), held.IsDue(time.Now()))
+	assert.True(suite.T(), held.IsDue(time.Now().Add(2*time.Hour+time.Minute)))
+}
+
+func (suite *QuietHoursTestSuite) TestAlertIsHeldWithoutChannel() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", RiskScore: 500}
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), channel)
+	assert.Len(suite.T(), suite.queue.messages, 1)
+	assert.Equal(suite.T(), []string{messaging.ChannelSMS}, suite.queue.messages[0].Route)
+	assert.Equal(suite.T(), "1", suite.queue.messages[0].Message.TransactionID)
+	assert.Equal(suite.T(), []string{"1"}, suite.queue.messages[0].TransactionIDs)
+}
+
+func (suite *QuietHoursTestSuite) TestUrgentAlertIsSentDuringQuietHours() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", RiskScore: 900}
+	suite.sms.On("Send", messaging.RecipientOf(txn), mock.Anything).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	assert.Empty(suite.T(), suite.queue.messages)
+	suite.sms.AssertExpectations(suite.T())
+}
+
+func (suite *QuietHoursTestSuite) TestChosenTimeZoneOutsideQuietHoursIsSentNow() {
+	// Arrange
+	preferences := models.NewAccountPreferences("12345678")
+	preferences.TimeZone = "Asia/Tokyo"
+	assert.NoError(suite.T(), suite.preferences.SavePreferences(context.Background(), preferences))
+	suite.dispatcher.QuietHours = suite.quietHours(-time.Hour, time.Hour)
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", RiskScore: 500}
+	suite.sms.On("Send", messaging.RecipientOf(txn), mock.Anything).Return(nil).Once()
+
+	// Act
+	channel, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, channel)
+	assert.Empty(suite.T(), suite.queue.messages)
+}
+
+func (suite *QuietHoursTestSuite) TestFailedHoldIsAnError() {
+	// Arrange
+	suite.queue.err = errors.New("sqs unavailable")
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", RiskScore: 500}
+
+	// Act
+	_, err := suite.dispatcher.DispatchFraudAlertEvent(txn)
+
+	// Assert
+	assert.ErrorContains(suite.T(), err, "sqs unavailable")
+	suite.sms.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything)
+}
+
+// deferredRecord queues a held message for the deferred handler
+func (suite *QuietHoursTestSuite) deferredRecord(messageID string, message messaging.DeferredMessage) lambdaevents.SQSMessage {
+	body, err := json.Marshal(message)
+	suite.Require().NoError(err)
+	return lambdaevents.SQSMessage{MessageId: messageID, Body: string(body)}
+}
+
+func (suite *QuietHoursTestSuite) TestDeferredHandlerSendsDueAndHoldsEarlyMessages() {
+	// Arrange
+	recipient := messaging.Recipient{AccountID: "12345678", PhoneNumber: "19205550100"}
+	due := messaging.DeferredMessage{Reply: true, Recipient: recipient, Message: messaging.Message{Body: "due"}, DeliverAt: time.Now().Add(-time.Minute).Unix()}
+	early := messaging.DeferredMessage{Reply: true, Recipient: recipient, Message: messaging.Message{Body: "early"}, DeliverAt: time.Now().Add(time.Hour).Unix()}
+	suite.sms.On("Send", recipient, body("due")).Return(nil).Once()
+	event := lambdaevents.SQSEvent{Records: []lambdaevents.SQSMessage{
+		suite.deferredRecord("m1", due),
+		suite.deferredRecord("m2", early),
+		{MessageId: "m3", Body: "not json"},
+	}}
+
+	// Act
+	result, err := handlers.NewDeferredHandler(suite.dispatcher, suite.queue, suite.transactions).ProcessDeferredEvent(context.Background(), event)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	assert.Len(suite.T(), suite.queue.messages, 1)
+	assert.Equal(suite.T(), "early", suite.queue.messages[0].Message.Body)
+	suite.sms.AssertExpectations(suite.T())
+}
+
+func (suite *QuietHoursTestSuite) TestDeferredHandlerReportsFailedSends() {
+	// Arrange
+	recipient := messaging.Recipient{AccountID: "12345678", PhoneNumber: "19205550100"}
+	due := messaging.DeferredMessage{Route: []string{messaging.ChannelSMS}, Recipient: recipient, Message: messaging.Message{Type: models.NotificationFraudAlert, Body: "due"}}
+	suite.sms.On("Send", recipient, body("due")).Return(errors.New("twilio unavailable")).Once()
+	event := lambdaevents.SQSEvent{Records: []lambdaevents.SQSMessage{suite.deferredRecord("m1", due)}}
+
+	// Act
+	result, err := handlers.NewDeferredHandler(suite.dispatcher, suite.queue, suite.transactions).ProcessDeferredEvent(context.Background(), event)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.BatchItemFailure{{ItemIdentifier: "m1"}}, result.BatchItemFailures)
+}
+
+func (suite *QuietHoursTestSuite) TestDeferredHandlerRecordsHeldAlertChannel() {
+	// Arrange
+	txn := models.Transaction{AccountID: "12345678", TransactionID: "1", PhoneNumber: "19205550100", TransactionStatus: "POTENTIAL_FRAUD"}
+	_, _, err := suite.transactions.SaveTransaction(context.Background(), &txn)
+	suite.Require().NoError(err)
+	recipient := messaging.RecipientOf(txn)
+	alert := messaging.DeferredMessage{Route: []string{messaging.ChannelSMS}, TransactionIDs: []string{"1"}, Recipient: recipient, Message: messaging.Message{Type: models.NotificationFraudAlert, Body: "alert"}}
+	suite.sms.On("Send", recipient, body("alert")).Return(nil).Once()
+	event := lambdaevents.SQSEvent{Records: []lambdaevents.SQSMessage{suite.deferredRecord("m1", alert)}}
+
+	// Act
+	result, err := handlers.NewDeferredHandler(suite.dispatcher, suite.queue, suite.transactions).ProcessDeferredEvent(context.Background(), event)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	stored, err := suite.transactions.GetTransaction(context.Background(), "12345678", "1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), messaging.ChannelSMS, stored.AlertChannel)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", stored.TransactionStatus)
+}
+
+func (suite *QuietHoursTestSuite) TestDeferredHandlerRetriesUnrecordedAlertChannel() {
+	// Arrange
+	recipient := messaging.Recipient{AccountID: "12345678", PhoneNumber: "19205550100"}
+	alert := messaging.DeferredMessage{Route: []string{messaging.ChannelSMS}, TransactionIDs: []string{"missing"}, Recipient: recipient, Message: messaging.Message{Type: models.NotificationFraudAlert, Body: "alert"}}
+	suite.sms.On("Send", recipient, body("alert")).Return(nil).Once()
+	event := lambdaevents.SQSEvent{Records: []lambdaevents.SQSMessage{suite.deferredRecord("m1", alert)}}
+
+	// Act
+	result, err := handlers.NewDeferredHandler(suite.dispatcher, suite.queue, suite.transactions).ProcessDeferredEvent(context.Background(), event)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.BatchItemFailure{{ItemIdentifier: "m1"}}, result.BatchItemFailures)
+}
+
+func TestQuietHoursSuite(t *testing.T) {
+	suite.Run(t, new(QuietHoursTestSuite))
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/replies"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// replyCorpus is a sample of replies customers have sent to fraud alerts and how each should be read
+var replyCorpus = []struct {
+	body       string
+	intent     string
+	confidence replies.Confidence
+	code       string
+}{
+	{"YES", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"y", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"Yes.", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"yesss", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"Yep", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"yes 4821", replies.IntentConfirm, replies.ConfidenceHigh, "4821"},
+	{"4821 yes", replies.IntentConfirm, replies.ConfidenceHigh, "4821"},
+	{"yes it was me", replies.IntentConfirm, replies.ConfidenceMedium, ""},
+	{"Yeah that was me, thanks", replies.IntentConfirm, replies.ConfidenceMedium, ""},
+	{"It was me", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"👍", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"👍🏽", replies.IntentConfirm, replies.ConfidenceHigh, ""},
+	{"NO", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"nope", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"Noooo", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"not me!", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"That wasn't me", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"that wasn’t me", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"honestly it was not me", replies.IntentDeny, replies.ConfidenceMedium, ""},
+	{"No, 4821", replies.IntentDeny, replies.ConfidenceHigh, "4821"},
+	{"I did not make this purchase", replies.IntentDeny, replies.ConfidenceMedium, ""},
+	{"not mine", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"👎", replies.IntentDeny, replies.ConfidenceHigh, ""},
+	{"HELP", replies.IntentHelp, replies.ConfidenceHigh, ""},
+	{"who is this?", replies.IntentHelp, replies.ConfidenceHigh, ""},
+	{"STOP", replies.IntentStop, replies.ConfidenceHigh, ""},
+	{"unsubscribe please", replies.IntentStop, replies.ConfidenceMedium, ""},
+	{"sí", replies.IntentConfirm, replies.ConfidenceMedium, ""},
+	{"no fui yo", replies.IntentDeny, replies.ConfidenceMedium, ""},
+	{"no it was me", replies.IntentConfirm, replies.ConfidenceLow, ""},
+	{"maybe", replies.IntentUnknown, replies.ConfidenceNone, ""},
+	{"what", replies.IntentUnknown, replies.ConfidenceNone, ""},
+	{"", replies.IntentUnknown, replies.ConfidenceNone, ""},
+}
+
+type ReplyClassifierTestSuite struct {
+	suite.Suite
+	classifier *replies.Classifier
+}
+
+func (suite *ReplyClassifierTestSuite) SetupTest() {
+	suite.classifier = replies.BundledClassifier()
+}
+
+func (suite *ReplyClassifierTestSuite) TestCorpus() {
+	for _, example := range replyCorpus {
+		// Act
+		classification := suite.classifier.Classify(example.body, "en")
+
+		// Assert
+		assert.Equal(suite.T(), example.intent, classification.Intent, example.body)
+		assert.Equal(suite.T(), example.confidence, classification.Confidence, example.body)
+		assert.Equal(suite.T(), example.code, classification.Code, example.body)
+	}
+}
+
+func (suite *ReplyClassifierTestSuite) TestLocaleRaisesConfidence() {
+	// Act
+	spanish := suite.classifier.Classify("Sí, fui yo", "es")
+	english := suite.classifier.Classify("Sí, fui yo", "en")
+	unknownLocale := suite.classifier.Classify("yes", "fr")
+
+	// Assert
+	assert.Equal(suite.T(), replies.IntentConfirm, spanish.Intent)
+	assert.Equal(suite.T(), replies.ConfidenceHigh, spanish.Confidence)
+	assert.Equal(suite.T(), "es", spanish.Locale)
+	assert.Equal(suite.T(), replies.ConfidenceMedium, english.Confidence)
+	assert.Equal(suite.T(), replies.ConfidenceHigh, unknownLocale.Confidence)
+}
+
+func (suite *ReplyClassifierTestSuite) TestCustomSynonymTable() {
+	// Arrange
+	table := `
+default_locale: en
+locales:
+  en:
+    confirm: ["all good"]
+    deny: ["report it"]
+`
+
+	// Act
+	classifier, err := replies.LoadSynonyms(strings.NewReader(table))
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), replies.IntentConfirm, classifier.Classify("All good!", "").Intent)
+	assert.Equal(suite.T(), replies.IntentDeny, classifier.Classify("report it", "").Intent)
+	assert.Equal(suite.T(), replies.IntentUnknown, classifier.Classify("yes", "").Intent)
+}
+
+func (suite *ReplyClassifierTestSuite) TestInvalidSynonymTables() {
+	// Arrange
+	tables := []string{
+		"",
+		"default_locale: fr\nlocales:\n  en:\n    confirm: [\"yes\"]\n",
+		"default_locale: en\nlocales:\n  en:\n    maybe: [\"perhaps\"]\n",
+		"default_locale: en\nlocales:\n  en:\n    confirm: [\"!!\"]\n",
+		"default_locale: en\nlocale: {}\n",
+	}
+
+	for _, table := range tables {
+		// Act
+		_, err := replies.LoadSynonyms(strings.NewReader(table))
+
+		// Assert
+		assert.Error(suite.T(), err, table)
+	}
+}
+
+func (suite *ReplyClassifierTestSuite) TestParseConfidence() {
+	// Act
+	confidence, err := replies.ParseConfidence(" High ")
+	_, unknownErr := replies.ParseConfidence("certain")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), replies.ConfidenceHigh, confidence)
+	assert.Error(suite.T(), unknownErr)
+}
+
+func TestReplyClassifierSuite(t *testing.T) {
+	suite.Run(t, new(ReplyClassifierTestSuite))
+}
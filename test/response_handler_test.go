@@ -0,0 +1,217 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/handlers"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/twilio/twilio-go/client"
+)
+
+const (
+	webhookAuthToken = "12345"
+	webhookURL       = "https://example.execute-api.us-east-1.amazonaws.com/prod/sms"
+)
+
+type MockResponseService struct {
+	mock.Mock
+}
+
+// RsUpdateTransaction implements services.ResponseService.
+func (m *MockResponseService) RsUpdateTransaction(ctx context.Context, messages []models.TwilioMessage) ([]models.TwilioMessage, error) {
+	args := m.Called(ctx, messages)
+	failed, _ := args.Get(0).([]models.TwilioMessage)
+	return failed, args.Error(1)
+}
+
+type MockSecurityEventRepository struct {
+	mock.Mock
+}
+
+// RecordSecurityEvent implements db.SecurityEventRepository.
+func (m *MockSecurityEventRepository) RecordSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+type ResponseHandlerTestSuite struct {
+	suite.Suite
+	ctx                   context.Context
+	mockResponseService   *MockResponseService
+	mockSecurityEventRepo *MockSecurityEventRepository
+	responseHandler       *handlers.GfResponseHandler
+}
+
+func (suite *ResponseHandlerTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.mockResponseService = new(MockResponseService)
+	suite.mockSecurityEventRepo = new(MockSecurityEventRepository)
+	validator := client.NewRequestValidator(webhookAuthToken)
+	suite.responseHandler = handlers.NewResponseHandler(suite.mockResponseService, &validator, suite.mockSecurityEventRepo)
+}
+
+// sign computes the X-Twilio-Signature Twilio sends for a webhook
+func sign(authToken string, url string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := url
+	for _, key := range keys {
+		data += key + params[key]
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func webhookRecord(messageID string, webhook models.TwilioWebhook) events.SQSMessage {
+	body, _ := json.Marshal(webhook)
+	return events.SQSMessage{MessageId: messageID, Body: string(body)}
+}
+
+func replyParams(body string) map[string]string {
+	return map[string]string{"From": responsePhone, "Body": body, "MessageSid": "SM123", "AccountSid": "AC123"}
+}
+
+func (suite *ResponseHandlerTestSuite) TestSignedMessageIsProcessed() {
+	// Arrange
+	params := replyParams("YES")
+	record := webhookRecord("m-1", models.TwilioWebhook{Signature: sign(webhookAuthToken, webhookURL, params), URL: webhookURL, Params: params})
+	suite.mockResponseService.On("RsUpdateTransaction", mock.Anything, []models.TwilioMessage{
+		{From: responsePhone, Body: "YES", MessageSid: "SM123", AccountSid: "AC123"},
+	}).Return(nil, nil).Once()
+
+	// Act
+	result, err := suite.responseHandler.ProcessResponseEvent(suite.ctx, events.SQSEvent{Records: []events.SQSMessage{record}})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	suite.mockResponseService.AssertExpectations(suite.T())
+	suite.mockSecurityEventRepo.AssertNotCalled(suite.T(), "RecordSecurityEvent", mock.Anything, mock.Anything)
+}
+
+func (suite *ResponseHandlerTestSuite) TestTamperedMessageIsRejected() {
+	// Arrange
+	params := replyParams("NO")
+	signature := sign(webhookAuthToken, webhookURL, params)
+	params["Body"] = "YES"
+	forged := webhookRecord("m-1", models.TwilioWebhook{Signature: signature, URL: webhookURL, Params: params})
+	valid := replyParams("NO")
+	signed := webhookRecord("m-2", models.TwilioWebhook{Signature: sign(webhookAuthToken, webhookURL, valid), URL: webhookURL, Params: valid})
+	suite.mockSecurityEventRepo.On("RecordSecurityEvent", mock.Anything, mock.MatchedBy(func(event *models.SecurityEvent) bool {
+		return event.EventType == models.SecurityEventWebhookRejected && event.Reason == "invalid signature" &&
+			event.Source == responsePhone && event.MessageSid == "SM123"
+	})).Return(nil).Once()
+	suite.mockResponseService.On("RsUpdateTransaction", mock.Anything, mock.MatchedBy(func(messages []models.TwilioMessage) bool {
+		return len(messages) == 1 && messages[0].Body == "NO"
+	})).Return(nil, nil).Once()
+
+	// Act
+	result, err := suite.responseHandler.ProcessResponseEvent(suite.ctx, events.SQSEvent{Records: []events.SQSMessage{forged, signed}})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	suite.mockResponseService.AssertExpectations(suite.T())
+	suite.mockSecurityEventRepo.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseHandlerTestSuite) TestWrongTokenIsRejected() {
+	// Arrange
+	params := replyParams("YES")
+	record := webhookRecord("m-1", models.TwilioWebhook{Signature: sign("other-token", webhookURL, params), URL: webhookURL, Params: params})
+	suite.mockSecurityEventRepo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Act
+	result, err := suite.responseHandler.ProcessResponseEvent(suite.ctx, events.SQSEvent{Records: []events.SQSMessage{record}})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	suite.mockResponseService.AssertNotCalled(suite.T(), "RsUpdateTransaction", mock.Anything, mock.Anything)
+	suite.mockSecurityEventRepo.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseHandlerTestSuite) TestUnsignedAndMalformedMessagesAreRejected() {
+	// Arrange
+	unsigned, _ := json.Marshal(models.TwilioMessage{From: responsePhone, Body: "YES"})
+	records := []events.SQSMessage{
+		{MessageId: "m-1", Body: string(unsigned)},
+		{MessageId: "m-2", Body: "not json"},
+	}
+	var reasons []string
+	suite.mockSecurityEventRepo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		reasons = append(reasons, args.Get(1).(*models.SecurityEvent).Reason)
+	}).Return(nil).Twice()
+
+	// Act
+	result, err := suite.responseHandler.ProcessResponseEvent(suite.ctx, events.SQSEvent{Records: records})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.BatchItemFailures)
+	assert.Equal(suite.T(), "missing signature", reasons[0])
+	assert.Contains(suite.T(), reasons[1], "malformed webhook")
+	suite.mockResponseService.AssertNotCalled(suite.T(), "RsUpdateTransaction", mock.Anything, mock.Anything)
+}
+
+func (suite *ResponseHandlerTestSuite) TestOnlyFailedMessagesAreReported() {
+	// Arrange
+	first := replyParams("YES")
+	second := replyParams("NO")
+	second["MessageSid"] = "SM456"
+	records := []events.SQSMessage{
+		webhookRecord("m-1", models.TwilioWebhook{Signature: sign(webhookAuthToken, webhookURL, first), URL: webhookURL, Params: first}),
+		webhookRecord("m-2", models.TwilioWebhook{Signature: sign(webhookAuthToken, webhookURL, second), URL: webhookURL, Params: second}),
+	}
+	suite.mockResponseService.On("RsUpdateTransaction", mock.Anything, mock.Anything).
+		Return([]models.TwilioMessage{{From: responsePhone, Body: "NO", MessageSid: "SM456"}}, errors.New("throttled")).Once()
+
+	// Act
+	result, err := suite.responseHandler.ProcessResponseEvent(suite.ctx, events.SQSEvent{Records: records})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.BatchItemFailure{{ItemIdentifier: "m-2"}}, result.BatchItemFailures)
+}
+
+func (suite *ResponseHandlerTestSuite) TestRetryHandlerVerifiesAndReportsFailures() {
+	// Arrange
+	validator := client.NewRequestValidator(webhookAuthToken)
+	retryHandler := handlers.NewResponseRetryHandler(suite.mockResponseService, &validator, suite.mockSecurityEventRepo)
+	params := replyParams("NO")
+	records := []events.SQSMessage{
+		webhookRecord("m-1", models.TwilioWebhook{Signature: sign(webhookAuthToken, webhookURL, params), URL: webhookURL, Params: params}),
+		webhookRecord("m-2", models.TwilioWebhook{Signature: "forged", URL: webhookURL, Params: params}),
+	}
+	suite.mockSecurityEventRepo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil).Once()
+	suite.mockResponseService.On("RsUpdateTransaction", mock.Anything, mock.Anything).
+		Return([]models.TwilioMessage{{From: responsePhone, Body: "NO", MessageSid: "SM123"}}, errors.New("throttled")).Once()
+
+	// Act
+	result, err := retryHandler.ProcessDLQResponseEvent(suite.ctx, events.SQSEvent{Records: records})
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.BatchItemFailure{{ItemIdentifier: "m-1"}}, result.BatchItemFailures)
+	suite.mockSecurityEventRepo.AssertExpectations(suite.T())
+}
+
+func TestResponseHandlerSuite(t *testing.T) {
+	suite.Run(t, new(ResponseHandlerTestSuite))
+}
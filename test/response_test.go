@@ -0,0 +1,511 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/backtest"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const responsePhone = "19205550100"
+
+type MockOptOutRepository struct {
+	mock.Mock
+}
+
+// IsOptedOut implements db.OptOutRepository.
+func (m *MockOptOutRepository) IsOptedOut(ctx context.Context, phoneNumber string) (bool, error) {
+	args := m.Called(ctx, phoneNumber)
+	return args.Bool(0), args.Error(1)
+}
+
+// SetOptedOut implements db.OptOutRepository.
+func (m *MockOptOutRepository) SetOptedOut(ctx context.Context, phoneNumber string, optedOut bool, keyword string, at time.Time) error {
+	args := m.Called(ctx, phoneNumber, optedOut, keyword, at)
+	return args.Error(0)
+}
+
+type MockProcessedReplyRepository struct {
+	mock.Mock
+}
+
+// ClaimReply implements db.ProcessedReplyRepository. A nil record in the expectation returns the claim itself.
+func (m *MockProcessedReplyRepository) ClaimReply(ctx context.Context, reply *models.ProcessedReply, staleBefore time.Time) (*models.ProcessedReply, bool, error) {
+	args := m.Called(ctx, reply, staleBefore)
+	existing, _ := args.Get(0).(*models.ProcessedReply)
+	if existing == nil {
+		existing = reply
+	}
+	return existing, args.Bool(1), args.Error(2)
+}
+
+// CompleteReply implements db.ProcessedReplyRepository.
+func (m *MockProcessedReplyRepository) CompleteReply(ctx context.Context, reply *models.ProcessedReply) error {
+	args := m.Called(ctx, reply)
+	return args.Error(0)
+}
+
+// ReleaseReply implements db.ProcessedReplyRepository.
+func (m *MockProcessedReplyRepository) ReleaseReply(ctx context.Context, messageSid string) error {
+	args := m.Called(ctx, messageSid)
+	return args.Error(0)
+}
+
+type ResponseServiceTestSuite struct {
+	suite.Suite
+	ctx                    context.Context
+	mockEventDispatcher    *MockEventDispatcher
+	mockProfileRepository  *MockAccountProfileRepository
+	mockOptOutRepository   *MockOptOutRepository
+	mockProcessedReplies   *MockProcessedReplyRepository
+	transactionRepository  *backtest.MemoryTransactionRepository
+	conversationRepository *backtest.MemoryConversationRepository
+	responseService        *services.GfResponseService
+}
+
+func (suite *ResponseServiceTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.mockEventDispatcher = new(MockEventDispatcher)
+	suite.mockProfileRepository = new(MockAccountProfileRepository)
+	suite.mockOptOutRepository = new(MockOptOutRepository)
+	suite.mockProcessedReplies = new(MockProcessedReplyRepository)
+	suite.transactionRepository = backtest.NewMemoryTransactionRepository()
+	suite.conversationRepository = backtest.NewMemoryConversationRepository()
+	suite.responseService = services.NewGfResponseService(suite.mockEventDispatcher, suite.transactionRepository, suite.mockProfileRepository, suite.conversationRepository, suite.mockOptOutRepository, suite.mockProcessedReplies)
+
+	suite.mockProfileRepository.On("RecordDeviceSighting", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.mockProfileRepository.On("RecordSpending", mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+// alert saves a transaction awaiting confirmation and opens its conversation as the fraud service would
+func (suite *ResponseServiceTestSuite) alert(transactionID string, replyCode string, createdAt time.Time) *models.Conversation {
+	txn := models.Transaction{AccountID: "ACC-1", TransactionID: transactionID, PhoneNumber: responsePhone, TransactionStatus: "POTENTIAL_FRAUD"}
+	_, _, err := suite.transactionRepository.SaveTransaction(suite.ctx, &txn)
+	suite.Require().NoError(err)
+
+	conversation := models.NewConversation(txn, replyCode, createdAt, time.Hour)
+	started, err := suite.conversationRepository.StartConversation(suite.ctx, conversation)
+	suite.Require().NoError(err)
+	suite.Require().True(started)
+	return conversation
+}
+
+func (suite *ResponseServiceTestSuite) status(transactionID string) string {
+	txn, err := suite.transactionRepository.GetTransaction(suite.ctx, "ACC-1", transactionID)
+	suite.Require().NoError(err)
+	return txn.TransactionStatus
+}
+
+func (suite *ResponseServiceTestSuite) reply(body string) ([]models.TwilioMessage, error) {
+	return suite.responseService.RsUpdateTransaction(suite.ctx, []models.TwilioMessage{{From: responsePhone, Body: body}})
+}
+
+func (suite *ResponseServiceTestSuite) TestReplyCodeResolvesOnlyThatAlert() {
+	// Arrange
+	suite.alert("1", "4821", time.Now().Add(-10*time.Minute))
+	suite.alert("2", "1234", time.Now().Add(-time.Minute))
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudRejected)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply(" yes 4821 ")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "APPROVED", suite.status("1"))
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("2"))
+	open, _ := suite.conversationRepository.GetOpenConversations(suite.ctx, responsePhone)
+	assert.Len(suite.T(), open, 1)
+	assert.Equal(suite.T(), "2", open[0].AlertID)
+	suite.mockProfileRepository.AssertNumberOfCalls(suite.T(), "RecordDeviceSighting", 1)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestBareReplyWithSeveralAlertsAsksForCode() {
+	// Arrange
+	suite.alert("1", "4821", time.Now().Add(-10*time.Minute))
+	suite.alert("2", "1234", time.Now().Add(-time.Minute))
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, chooseAlert("4821, 1234")).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("2"))
+	open, _ := suite.conversationRepository.GetOpenConversations(suite.ctx, responsePhone)
+	assert.Len(suite.T(), open, 2)
+	for _, conversation := range open {
+		assert.Equal(suite.T(), 2, conversation.PromptCount)
+	}
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestUnknownReplyCodeAsksForCode() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, chooseAlert("4821")).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("NO 9999")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestStopOptsOutWithoutReplying() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockOptOutRepository.On("SetOptedOut", suite.ctx, responsePhone, true, models.SMSKeywordStop, mock.Anything).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("Unsubscribe.")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	suite.mockOptOutRepository.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+}
+
+func (suite *ResponseServiceTestSuite) TestStartOptsBackIn() {
+	// Arrange
+	suite.mockOptOutRepository.On("SetOptedOut", suite.ctx, responsePhone, false, models.SMSKeywordStart, mock.Anything).Return(nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationOptedIn)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("UNSTOP")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	suite.mockOptOutRepository.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestHelpIsAnswered() {
+	// Arrange
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationHelp)).Return(nil).Twice()
+
+	// Act
+	_, keywordErr := suite.reply("help")
+	_, phraseErr := suite.reply("Who is this?")
+
+	// Assert
+	assert.NoError(suite.T(), keywordErr)
+	assert.NoError(suite.T(), phraseErr)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestOptOutFailureFailsMessage() {
+	// Arrange
+	suite.mockOptOutRepository.On("SetOptedOut", suite.ctx, responsePhone, true, models.SMSKeywordStop, mock.Anything).Return(errors.New("throttled")).Once()
+
+	// Act
+	failed, err := suite.reply("STOP")
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failed, 1)
+}
+
+func (suite *ResponseServiceTestSuite) replyWithSid(body string) ([]models.TwilioMessage, error) {
+	return suite.responseService.RsUpdateTransaction(suite.ctx, []models.TwilioMessage{{From: responsePhone, Body: body, MessageSid: "SM1"}})
+}
+
+func completedWith(status string, outcome string) interface{} {
+	return mock.MatchedBy(func(reply *models.ProcessedReply) bool {
+		return reply.MessageSid == "SM1" && reply.Status == status && reply.Answer().Type == outcome
+	})
+}
+
+// chooseAlert matches the prompt asking the customer which alert they are answering
+func chooseAlert(codes string) interface{} {
+	return mock.MatchedBy(func(notification models.Notification) bool {
+		return notification.Type == models.NotificationChooseAlert && notification.Data.Codes == codes
+	})
+}
+
+// answer matches the notification sent back to the customer by its type
+func answer(notificationType string) interface{} {
+	return mock.MatchedBy(func(notification models.Notification) bool {
+		return notification.Type == notificationType
+	})
+}
+
+func (suite *ResponseServiceTestSuite) TestReplyOutcomeIsRecorded() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(nil, true, nil).Once()
+	suite.mockProcessedReplies.On("CompleteReply", suite.ctx, completedWith(models.ReplyProcessed, models.NotificationFraudConfirmed)).Return(nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudConfirmed)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "FRAUD", suite.status("1"))
+	suite.mockProcessedReplies.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestRedeliveredReplyIsSkipped() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	existing := &models.ProcessedReply{MessageSid: "SM1", PhoneNumber: responsePhone, Status: models.ReplyProcessed, Outcome: &models.Notification{Type: models.NotificationFraudConfirmed}}
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(existing, false, nil).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+	suite.mockProcessedReplies.AssertNotCalled(suite.T(), "CompleteReply", mock.Anything, mock.Anything)
+}
+
+func (suite *ResponseServiceTestSuite) TestUnsentAnswerIsResentWithoutReprocessing() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	existing := &models.ProcessedReply{MessageSid: "SM1", PhoneNumber: responsePhone, Status: models.ReplyUnsent, Outcome: &models.Notification{Type: models.NotificationFraudConfirmed}}
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(existing, false, nil).Once()
+	suite.mockProcessedReplies.On("CompleteReply", suite.ctx, completedWith(models.ReplyProcessed, models.NotificationFraudConfirmed)).Return(nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudConfirmed)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	suite.mockProcessedReplies.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestFailedSendIsRecordedAsUnsent() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(nil, true, nil).Once()
+	suite.mockProcessedReplies.On("CompleteReply", suite.ctx, completedWith(models.ReplyUnsent, models.NotificationFraudConfirmed)).Return(nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudConfirmed)).Return(errors.New("twilio down")).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("NO")
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failed, 1)
+	assert.Equal(suite.T(), "FRAUD", suite.status("1"))
+	suite.mockProcessedReplies.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestFailedReplyIsReleased() {
+	// Arrange
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(nil, true, nil).Once()
+	suite.mockProcessedReplies.On("ReleaseReply", suite.ctx, "SM1").Return(nil).Once()
+	suite.mockOptOutRepository.On("SetOptedOut", suite.ctx, responsePhone, true, models.SMSKeywordStop, mock.Anything).Return(errors.New("throttled")).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("STOP")
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failed, 1)
+	suite.mockProcessedReplies.AssertExpectations(suite.T())
+	suite.mockProcessedReplies.AssertNotCalled(suite.T(), "CompleteReply", mock.Anything, mock.Anything)
+}
+
+// flakyConversationRepository fails the first conversation update
+type flakyConversationRepository struct {
+	*backtest.MemoryConversationRepository
+	failed bool
+}
+
+func (r *flakyConversationRepository) UpdateConversation(ctx context.Context, conversation *models.Conversation, expectedState string) error {
+	if !r.failed {
+		r.failed = true
+		return errors.New("throttled")
+	}
+	return r.MemoryConversationRepository.UpdateConversation(ctx, conversation, expectedState)
+}
+
+func (suite *ResponseServiceTestSuite) TestRedeliveredReplyAfterPartialFailureRecordsSightings() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.responseService.ConversationRepo = &flakyConversationRepository{MemoryConversationRepository: suite.conversationRepository}
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(nil, true, nil).Twice()
+	suite.mockProcessedReplies.On("ReleaseReply", suite.ctx, "SM1").Return(nil).Once()
+	suite.mockProcessedReplies.On("CompleteReply", suite.ctx, completedWith(models.ReplyProcessed, models.NotificationFraudRejected)).Return(nil).Once()
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudRejected)).Return(nil).Once()
+
+	// Act
+	_, firstErr := suite.replyWithSid("YES")
+	failed, err := suite.replyWithSid("YES")
+
+	// Assert
+	assert.Error(suite.T(), firstErr)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "APPROVED", suite.status("1"))
+	suite.mockProfileRepository.AssertNumberOfCalls(suite.T(), "RecordDeviceSighting", 1)
+	suite.mockProfileRepository.AssertNumberOfCalls(suite.T(), "RecordSpending", 1)
+	suite.mockProcessedReplies.AssertExpectations(suite.T())
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestReplyBeingProcessedFails() {
+	// Arrange
+	existing := &models.ProcessedReply{MessageSid: "SM1", PhoneNumber: responsePhone, Status: models.ReplyProcessing}
+	suite.mockProcessedReplies.On("ClaimReply", suite.ctx, mock.Anything, mock.Anything).Return(existing, false, nil).Once()
+
+	// Act
+	failed, err := suite.replyWithSid("NO")
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Len(suite.T(), failed, 1)
+	suite.mockEventDispatcher.AssertNotCalled(suite.T(), "DispatchFraudUpdateEvent", mock.Anything, mock.Anything)
+}
+
+func (suite *ResponseServiceTestSuite) TestParseSMSKeyword() {
+	// Act & Assert
+	assert.Equal(suite.T(), models.SMSKeywordStop, models.ParseSMSKeyword(" stop! "))
+	assert.Equal(suite.T(), models.SMSKeywordStop, models.ParseSMSKeyword("QUIT"))
+	assert.Equal(suite.T(), models.SMSKeywordStart, models.ParseSMSKeyword("Start"))
+	assert.Equal(suite.T(), models.SMSKeywordHelp, models.ParseSMSKeyword("INFO"))
+	assert.Equal(suite.T(), "", models.ParseSMSKeyword("please stop charging me"))
+	assert.Equal(suite.T(), "", models.ParseSMSKeyword("YES"))
+}
+
+func (suite *ResponseServiceTestSuite) TestNewReplyCodeSkipsCodesInUse() {
+	// Arrange
+	code := models.NewReplyCode("txn-1", nil)
+
+	// Act
+	next := models.NewReplyCode("txn-1", map[string]bool{code: true})
+
+	// Assert
+	assert.Len(suite.T(), code, 4)
+	assert.Equal(suite.T(), code, models.NewReplyCode("txn-1", map[string]bool{}))
+	assert.NotEqual(suite.T(), code, next)
+	assert.Len(suite.T(), next, 4)
+}
+
+func (suite *ResponseServiceTestSuite) TestNoConfirmsFraud() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudConfirmed)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "FRAUD", suite.status("1"))
+	open, _ := suite.conversationRepository.GetOpenConversations(suite.ctx, responsePhone)
+	assert.Empty(suite.T(), open)
+	suite.mockProfileRepository.AssertNotCalled(suite.T(), "RecordDeviceSighting", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestFreeTextDenialConfirmsFraud() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationFraudConfirmed)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("Nope, that wasn’t me!! 4821")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "FRAUD", suite.status("1"))
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestAmbiguousReplyPromptsAgain() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationInvalidResponse)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("no wait it was me")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestReplyWithoutConversationIsUnknown() {
+	// Arrange
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationUnknown)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("YES")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestExpiredConversationIsLeftForEscalation() {
+	// Arrange
+	suite.alert("1", "4821", time.Now().Add(-2*time.Hour))
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationConversationExpired)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("NO")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	assert.Equal(suite.T(), "POTENTIAL_FRAUD", suite.status("1"))
+	open, _ := suite.conversationRepository.GetOpenConversations(suite.ctx, responsePhone)
+	assert.Len(suite.T(), open, 1)
+	assert.Equal(suite.T(), models.ConversationAwaitingReply, open[0].State)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *ResponseServiceTestSuite) TestInvalidReplyPromptsAgain() {
+	// Arrange
+	suite.alert("1", "4821", time.Now())
+	suite.mockEventDispatcher.On("DispatchFraudUpdateEvent", responsePhone, answer(models.NotificationInvalidResponse)).Return(nil).Once()
+
+	// Act
+	failed, err := suite.reply("maybe")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), failed)
+	open, _ := suite.conversationRepository.GetOpenConversations(suite.ctx, responsePhone)
+	assert.Len(suite.T(), open, 1)
+	assert.Equal(suite.T(), 2, open[0].PromptCount)
+	assert.Equal(suite.T(), "MAYBE", open[0].LastReply)
+	suite.mockEventDispatcher.AssertExpectations(suite.T())
+}
+
+func TestResponseServiceSuite(t *testing.T) {
+	suite.Run(t, new(ResponseServiceTestSuite))
+}
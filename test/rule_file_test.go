@@ -0,0 +1,246 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/dsl"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testRuleFile = `
+version: "v1"
+threshold: 500
+rules:
+  - name: BigOnlineSpend
+    expression: amount > 1000 && channel in ["online", "web"]
+    weight: 600
+    reason_code: BIG_ONLINE_SPEND
+  - name: ManyLogins
+    expression: login_attempts >= 3
+    weight: 200
+    reason_code: MANY_LOGINS
+`
+
+const testRuleFileJSON = `{
+	"version": "v2",
+	"rules": [
+		{"name": "Withdrawal", "expression": "transaction_type == 'withdrawal'", "weight": 700, "reason_code": "WITHDRAWAL"}
+	]
+}`
+
+// fakeConfigGetter serves config items from memory
+type fakeConfigGetter struct {
+	items map[string]*models.ConfigItem
+}
+
+func (f *fakeConfigGetter) GetConfigItem(ctx context.Context, configID string) (*models.ConfigItem, error) {
+	item, ok := f.items[configID]
+	if !ok {
+		return nil, errors.New("config item not found")
+	}
+	return item, nil
+}
+
+type RuleFileTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *RuleFileTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *RuleFileTestSuite) TestCompileAndEvaluateExpression() {
+	// Arrange
+	variables := dsl.Variables{"amount": dsl.TypeNumber, "channel": dsl.TypeString, "is_new": dsl.TypeBool}
+
+	// Act
+	expression, err := dsl.Compile(`(amount - 100) * 2 > 300 && !is_new || channel in ["ATM"]`, variables)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), expression.Evaluate(dsl.Env{"amount": 300.0, "is_new": false}))
+	assert.False(suite.T(), expression.Evaluate(dsl.Env{"amount": 300.0, "is_new": true}))
+	assert.True(suite.T(), expression.Evaluate(dsl.Env{"channel": "atm"}))
+	assert.False(suite.T(), expression.Evaluate(dsl.Env{}))
+}
+
+func (suite *RuleFileTestSuite) TestCompileRejectsInvalidExpressions() {
+	// Arrange
+	variables := dsl.Variables{"amount": dsl.TypeNumber, "channel": dsl.TypeString}
+	invalid := []string{
+		"amount > ",
+		"amount",
+		"unknown > 1",
+		"channel > 1",
+		"amount == 'x'",
+		"channel in [1, 2]",
+		"amount > 1 )",
+		"'unterminated",
+	}
+
+	for _, source := range invalid {
+		// Act
+		_, err := dsl.Compile(source, variables)
+
+		// Assert
+		assert.Error(suite.T(), err, source)
+	}
+}
+
+func (suite *RuleFileTestSuite) TestParseRuleFile() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 1500
+	txn.LoginAttempts = 1
+
+	// Act
+	ruleSet, err := rules.ParseRuleFile([]byte(testRuleFile), 700)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "v1", ruleSet.Version)
+	assert.Equal(suite.T(), 500, ruleSet.Threshold)
+	assert.Len(suite.T(), ruleSet.Rules, 2)
+
+	decision, err := fraud.NewRuleEngine(ruleSet.Threshold, ruleSet.Rules...).Evaluate(suite.ctx, txn)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), []string{"BIG_ONLINE_SPEND"}, decision.ReasonCodes)
+}
+
+func (suite *RuleFileTestSuite) TestParseJSONRuleFileUsesDefaultThreshold() {
+	// Act
+	ruleSet, err := rules.ParseRuleFile([]byte(testRuleFileJSON), 650)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "v2", ruleSet.Version)
+	assert.Equal(suite.T(), 650, ruleSet.Threshold)
+	assert.Len(suite.T(), ruleSet.Rules, 1)
+}
+
+func (suite *RuleFileTestSuite) TestParseRuleFileRejectsInvalidFiles() {
+	// Arrange
+	invalid := map[string]string{
+		"empty":            "",
+		"no rules":         "version: v1\nrules: []\n",
+		"unknown field":    "rules:\n  - name: A\n    expression: amount > 1\n    weight: 100\n    reason_code: A\n    severity: high\n",
+		"bad expression":   "rules:\n  - name: A\n    expression: amount >\n    weight: 100\n    reason_code: A\n",
+		"bad weight":       "rules:\n  - name: A\n    expression: amount > 1\n    weight: 0\n    reason_code: A\n",
+		"bad threshold":    "threshold: 5000\nrules:\n  - name: A\n    expression: amount > 1\n    weight: 100\n    reason_code: A\n",
+		"no reason code":   "rules:\n  - name: A\n    expression: amount > 1\n    weight: 100\n",
+		"duplicate names":  "rules:\n  - name: A\n    expression: amount > 1\n    weight: 100\n    reason_code: A\n  - name: A\n    expression: amount > 2\n    weight: 100\n    reason_code: B\n",
+		"unknown variable": "rules:\n  - name: A\n    expression: balance > 1\n    weight: 100\n    reason_code: A\n",
+	}
+
+	for name, data := range invalid {
+		// Act
+		_, err := rules.ParseRuleFile([]byte(data), 700)
+
+		// Assert
+		assert.Error(suite.T(), err, name)
+	}
+}
+
+func (suite *RuleFileTestSuite) TestExpressionRuleUsesEnrichedSignals() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 900
+	profiles := &fakeAccountProfiles{baselines: make(map[string]map[string]*models.SpendingBaseline)}
+	profiles.addSpending(txn, 100, 110, 90, 105, 95, 100, 98, 102, 104, 96)
+	ruleSet, err := rules.ParseRuleFile([]byte(`
+rules:
+  - name: AboveAverage
+    expression: amount > 3 * account.avg_amount && !account.cold_start
+    weight: 800
+    reason_code: ABOVE_AVERAGE
+`), 700)
+	assert.NoError(suite.T(), err)
+	engine := fraud.NewRuleEngine(ruleSet.Threshold, ruleSet.Rules...).WithEnrichers(fraud.NewBaselineEnricher(profiles, 10))
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), []string{"ABOVE_AVERAGE"}, decision.ReasonCodes)
+}
+
+func (suite *RuleFileTestSuite) TestReloadingDetectorKeepsLastGoodVersion() {
+	// Arrange
+	path := filepath.Join(suite.T().TempDir(), "rules.yaml")
+	assert.NoError(suite.T(), os.WriteFile(path, []byte(testRuleFile), 0o600))
+	source, err := rules.NewSource(path, nil, nil)
+	assert.NoError(suite.T(), err)
+	detector, err := rules.NewReloadingDetector(suite.ctx, source, 0, 700)
+	assert.NoError(suite.T(), err)
+	version := detector.Version()
+
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 1500
+
+	// Act
+	assert.NoError(suite.T(), os.WriteFile(path, []byte("rules:\n  - name: Broken\n    expression: amount >>\n"), 0o600))
+	reloadErr := detector.Reload(suite.ctx)
+	decision, err := detector.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.Error(suite.T(), reloadErr)
+	assert.Equal(suite.T(), version, detector.Version())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+}
+
+func (suite *RuleFileTestSuite) TestReloadingDetectorPicksUpChanges() {
+	// Arrange
+	configs := &fakeConfigGetter{items: map[string]*models.ConfigItem{
+		"fraud-rules": {ConfigID: "fraud-rules", Content: testRuleFile},
+	}}
+	source, err := rules.NewSource("dynamodb://fraud-rules", nil, configs)
+	assert.NoError(suite.T(), err)
+	detector, err := rules.NewReloadingDetector(suite.ctx, source, 0, 700)
+	assert.NoError(suite.T(), err)
+
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionType = "WITHDRAWAL"
+
+	before, err := detector.Evaluate(suite.ctx, txn)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	configs.items["fraud-rules"].Content = testRuleFileJSON
+	after, err := detector.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), before.IsFraud)
+	assert.True(suite.T(), after.IsFraud)
+	assert.Equal(suite.T(), []string{"WITHDRAWAL"}, after.ReasonCodes)
+}
+
+func (suite *RuleFileTestSuite) TestReloadingDetectorFailsWithoutValidInitialFile() {
+	// Arrange
+	objects := &fakeObjectGetter{objects: map[string]string{"rules-bucket/rules.yaml": "rules: []"}}
+	source, err := rules.NewSource("s3://rules-bucket/rules.yaml", objects, nil)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	_, err = rules.NewReloadingDetector(suite.ctx, source, 0, 700)
+
+	// Assert
+	assert.Error(suite.T(), err)
+}
+
+func TestRuleFileSuite(t *testing.T) {
+	suite.Run(t, new(RuleFileTestSuite))
+}
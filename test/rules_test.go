@@ -0,0 +1,452 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/config"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/fraud"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type failingRule struct{}
+
+func (r failingRule) Name() string {
+	return "Failing"
+}
+
+func (r failingRule) Evaluate(ctx context.Context, signals *fraud.Signals) (fraud.RuleResult, error) {
+	return fraud.RuleResult{}, errors.New("rule error")
+}
+
+// fakeTransactionHistory serves account and device history from memory
+type fakeTransactionHistory struct {
+	transactions []models.Transaction
+}
+
+func (f *fakeTransactionHistory) GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	return f.filter(func(txn models.Transaction) bool { return txn.AccountID == accountID }, start, end), nil
+}
+
+func (f *fakeTransactionHistory) GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	return f.filter(func(txn models.Transaction) bool { return txn.DeviceID == deviceID }, start, end), nil
+}
+
+func (f *fakeTransactionHistory) filter(match func(models.Transaction) bool, start time.Time, end time.Time) []models.Transaction {
+	var result []models.Transaction
+	for _, txn := range f.transactions {
+		txnTime := txn.GetTransactionTime()
+		if match(txn) && !txnTime.Before(start) && !txnTime.After(end) {
+			result = append(result, txn)
+		}
+	}
+	return result
+}
+
+// fakeAccountProfiles serves device profiles and spending baselines from memory, returning empty ones for unknown accounts
+type fakeAccountProfiles struct {
+	profiles  map[string]*models.DeviceProfile
+	baselines map[string]map[string]*models.SpendingBaseline
+}
+
+func (f *fakeAccountProfiles) GetSpendingBaselines(ctx context.Context, accountID string) (map[string]*models.SpendingBaseline, error) {
+	return f.baselines[accountID], nil
+}
+
+// addSpending folds amounts into an account's segment and account-wide baselines the same way the repository does
+func (f *fakeAccountProfiles) addSpending(txn models.Transaction, amounts ...float64) {
+	if f.baselines[txn.AccountID] == nil {
+		f.baselines[txn.AccountID] = make(map[string]*models.SpendingBaseline)
+	}
+	for _, segment := range [][2]string{{txn.TransactionType, txn.Channel}, {models.AllSegments, models.AllSegments}} {
+		key := models.BaselineKey(segment[0], segment[1])
+		if f.baselines[txn.AccountID][key] == nil {
+			f.baselines[txn.AccountID][key] = models.NewSpendingBaseline(txn.AccountID, segment[0], segment[1])
+		}
+		for _, amount := range amounts {
+			f.baselines[txn.AccountID][key].Add(amount)
+		}
+	}
+}
+
+func (f *fakeAccountProfiles) GetDeviceProfile(ctx context.Context, accountID string) (*models.DeviceProfile, error) {
+	if profile, ok := f.profiles[accountID]; ok {
+		return profile, nil
+	}
+	return models.NewDeviceProfile(accountID), nil
+}
+
+// getKnownDeviceProfile builds a profile where the given device and IP were last seen an hour before the transaction
+func getKnownDeviceProfile(txn models.Transaction, deviceID string, ipAddress string) *models.DeviceProfile {
+	profile := models.NewDeviceProfile(txn.AccountID)
+	seen := txn.GetTransactionTime().Add(-time.Hour).Unix()
+	profile.Devices[deviceID] = models.Sighting{Kind: models.SightingDevice, Value: deviceID, FirstSeen: seen, LastSeen: seen, SeenCount: 1}
+	profile.IPAddresses[ipAddress] = models.Sighting{Kind: models.SightingIP, Value: ipAddress, FirstSeen: seen, LastSeen: seen, SeenCount: 1}
+	return profile
+}
+
+// getHistoryTransaction copies a transaction onto the same account and device at the given offset from base
+func getHistoryTransaction(base models.Transaction, offset time.Duration, amount float64) models.Transaction {
+	txn := GetTestTransaction(base.Email)
+	txn.AccountID = base.AccountID
+	txn.DeviceID = base.DeviceID
+	txn.TransactionAmount = amount
+	txn.TransactionTimestamp = base.GetTransactionTime().Add(offset).Unix()
+	return txn
+}
+
+type RuleEngineTestSuite struct {
+	suite.Suite
+	ctx      context.Context
+	history  *fakeTransactionHistory
+	profiles *fakeAccountProfiles
+}
+
+func (suite *RuleEngineTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.history = &fakeTransactionHistory{}
+	suite.profiles = &fakeAccountProfiles{
+		profiles:  make(map[string]*models.DeviceProfile),
+		baselines: make(map[string]map[string]*models.SpendingBaseline),
+	}
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_ApprovesNormalTransaction() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), decision.IsFraud)
+	assert.Zero(suite.T(), decision.Score)
+	assert.Empty(suite.T(), decision.TriggeredRules)
+}
+
+func (suite *RuleEngineTestSuite) TestZeroRiskScoreIsStored() {
+	// Arrange
+	config.InitializeLocalConfig()
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionStatus = "APPROVED"
+	txn.RiskScore = fraud.MinRiskScore
+
+	// Act
+	updates, err := txn.TransactionUpdatePayload()
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), updates, "RiskScore")
+	assert.EqualValues(suite.T(), 0, updates["RiskScore"])
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_FlagsExcessLoginAttempts() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.LoginAttempts = 5
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), []string{fraud.RuleLoginAttempts}, decision.TriggeredRules)
+}
+
+func (suite *RuleEngineTestSuite) TestEngine_CombinesRuleWeights() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 2500
+	txn.Channel = "Online"
+	engine := fraud.NewRuleEngine(
+		600,
+		fraud.NewAmountThresholdRule(1000),
+		fraud.NewChannelRule([]string{"ONLINE"}),
+	)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), fraud.HighAmountWeight+fraud.HighRiskChannelWeight, decision.Score)
+	assert.Equal(suite.T(), []string{fraud.RuleHighAmount, fraud.RuleHighRiskChannel}, decision.TriggeredRules)
+	assert.Equal(suite.T(), []string{fraud.ReasonHighAmount, fraud.ReasonHighRiskChannel}, decision.ReasonCodes)
+}
+
+func (suite *RuleEngineTestSuite) TestEngine_ScoreIsCappedAtMaxRiskScore() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.LoginAttempts = 5
+	txn.MerchantID = "M999"
+	engine := fraud.NewRuleEngine(600, fraud.NewLoginAttemptsRule(3), fraud.NewMerchantRule([]string{"M999"}))
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), fraud.MaxRiskScore, decision.Score)
+	assert.Equal(suite.T(), []string{fraud.ReasonExcessLoginAttempts, fraud.ReasonHighRiskMerchant}, decision.ReasonCodes)
+}
+
+func (suite *RuleEngineTestSuite) TestEngine_BelowThresholdIsNotFraud() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 2500
+	engine := fraud.NewRuleEngine(600, fraud.NewAmountThresholdRule(1000), fraud.NewMerchantRule([]string{"M999"}))
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), fraud.HighAmountWeight, decision.Score)
+}
+
+func (suite *RuleEngineTestSuite) TestTransactionDurationRule() {
+	rule := fraud.NewTransactionDurationRule(5, 600)
+	cases := map[int]bool{0: false, 2: true, 120: false, 900: true}
+
+	for duration, expected := range cases {
+		// Arrange
+		signals := fraud.NewSignals(models.Transaction{TransactionDuration: duration})
+
+		// Act
+		result, err := rule.Evaluate(suite.ctx, signals)
+
+		// Assert
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), expected, result.Triggered, "duration %d", duration)
+	}
+}
+
+func (suite *RuleEngineTestSuite) TestEngine_RuleErrorFailsEvaluation() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	engine := fraud.NewRuleEngine(600, fraud.NewAmountThresholdRule(1000), failingRule{})
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), decision)
+}
+
+func (suite *RuleEngineTestSuite) TestVelocityEnricher_CountsSlidingWindows() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.history.transactions = []models.Transaction{
+		txn,
+		getHistoryTransaction(txn, -2*time.Minute, 50),
+		getHistoryTransaction(txn, -30*time.Minute, 25),
+		getHistoryTransaction(txn, -3*time.Hour, 10),
+		getHistoryTransaction(txn, -48*time.Hour, 1000),
+	}
+	signals := fraud.NewSignals(txn)
+	err := fraud.NewHistoryEnricher(suite.history, fraud.DefaultHistoryLookback).Enrich(suite.ctx, signals)
+	assert.NoError(suite.T(), err)
+
+	// Act
+	err = fraud.NewVelocityEnricher().Enrich(suite.ctx, signals)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), fraud.VelocityWindow{Count: 2, Amount: 150.50}, signals.Velocity.Account[5*time.Minute])
+	assert.Equal(suite.T(), fraud.VelocityWindow{Count: 3, Amount: 175.50}, signals.Velocity.Account[time.Hour])
+	assert.Equal(suite.T(), fraud.VelocityWindow{Count: 4, Amount: 185.50}, signals.Velocity.Account[24*time.Hour])
+	assert.Equal(suite.T(), signals.Velocity.Account, signals.Velocity.Device)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_FlagsAccountBurst() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.history.transactions = []models.Transaction{txn}
+	for i := 1; i <= 3; i++ {
+		suite.history.transactions = append(suite.history.transactions, getHistoryTransaction(txn, -time.Duration(i)*time.Minute, 20))
+	}
+	txn.LoginAttempts = 3
+	txn.TransactionAmount = 1200
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Contains(suite.T(), decision.ReasonCodes, fraud.ReasonHighVelocity)
+	assert.Contains(suite.T(), decision.ReasonCodes, fraud.ReasonDeviceVelocity)
+}
+
+func (suite *RuleEngineTestSuite) TestDeviceVelocityRule_SharedDeviceAcrossAccounts() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.history.transactions = []models.Transaction{txn}
+	for i := 1; i <= 3; i++ {
+		other := getHistoryTransaction(txn, -time.Duration(i)*time.Minute, 20)
+		other.AccountID = "OTHER-" + other.TransactionID
+		suite.history.transactions = append(suite.history.transactions, other)
+	}
+	engine := fraud.NewRuleEngine(
+		600,
+		fraud.NewAccountVelocityRule(fraud.DefaultVelocityLimits()),
+		fraud.NewDeviceVelocityRule(fraud.DefaultVelocityLimits()),
+	).WithEnrichers(fraud.NewHistoryEnricher(suite.history, fraud.DefaultHistoryLookback), fraud.NewVelocityEnricher())
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{fraud.ReasonDeviceVelocity}, decision.ReasonCodes)
+}
+
+func (suite *RuleEngineTestSuite) TestDeviceEnricher_KnownDeviceAndIP() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.profiles.profiles[txn.AccountID] = getKnownDeviceProfile(txn, txn.DeviceID, txn.IPAddress)
+	signals := fraud.NewSignals(txn)
+
+	// Act
+	err := fraud.NewDeviceEnricher(suite.profiles).Enrich(suite.ctx, signals)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), signals.Device.HasHistory)
+	assert.False(suite.T(), signals.Device.IsNewDevice)
+	assert.False(suite.T(), signals.Device.IsNewIP)
+	assert.Equal(suite.T(), time.Hour, signals.Device.DeviceLastSeenAgo)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_FlagsNewDeviceWithHighAmount() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.profiles.profiles[txn.AccountID] = getKnownDeviceProfile(txn, "KNOWN-DEVICE", "KNOWN-IP")
+	txn.TransactionAmount = 800
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.IsFraud)
+	assert.Equal(suite.T(), fraud.NewDeviceHighAmountWeight+fraud.NewIPWeight, decision.Score)
+	assert.Equal(suite.T(), []string{fraud.ReasonNewDevice, fraud.ReasonNewIP}, decision.ReasonCodes)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_NewAccountIsNotNovel() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	txn.TransactionAmount = 800
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), decision.IsFraud)
+	assert.Zero(suite.T(), decision.Score)
+}
+
+func (suite *RuleEngineTestSuite) TestSpendingBaseline_Statistics() {
+	// Arrange
+	baseline := models.NewSpendingBaseline("A1", "Debit", "Online")
+
+	// Act
+	for _, amount := range []float64{10, 20, 30, 40, 50} {
+		baseline.Add(amount)
+	}
+
+	// Assert
+	assert.Equal(suite.T(), int64(5), baseline.Count)
+	assert.InDelta(suite.T(), 30, baseline.Mean(), 1e-9)
+	assert.InDelta(suite.T(), 250, baseline.Variance(), 1e-9)
+	assert.InDelta(suite.T(), 2, baseline.ZScore(30+2*baseline.StdDev()), 1e-9)
+	assert.Equal(suite.T(), 1.0, baseline.PercentileRank(5000))
+	assert.Equal(suite.T(), 0.0, baseline.PercentileRank(0.5))
+	assert.Equal(suite.T(), "BASELINE#DEBIT#ONLINE", baseline.ProfileKey)
+}
+
+func (suite *RuleEngineTestSuite) TestBaselineEnricher_FallsBackToAccountWideBaseline() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	other := txn
+	other.Channel = "SomeOtherChannel"
+	suite.profiles.addSpending(other, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65)
+	suite.profiles.addSpending(txn, 20, 25)
+	signals := fraud.NewSignals(txn)
+
+	// Act
+	err := fraud.NewBaselineEnricher(suite.profiles, 10).Enrich(suite.ctx, signals)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), signals.Baseline.ColdStart)
+	assert.Equal(suite.T(), models.BaselineKey(models.AllSegments, models.AllSegments), signals.Baseline.Segment)
+	assert.Equal(suite.T(), int64(12), signals.Baseline.SampleCount)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_FlagsAmountFarAboveBaseline() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.profiles.addSpending(txn, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65)
+	txn.TransactionAmount = 900
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{fraud.ReasonAmountAnomaly}, decision.ReasonCodes)
+	assert.Equal(suite.T(), fraud.AmountAnomalyWeight, decision.Score)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_HighSpenderIsNotAnomalous() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.profiles.addSpending(txn, 1500, 1800, 2100, 1900, 1600, 2500, 1700, 2000, 2200, 1400)
+	txn.TransactionAmount = 2300
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), decision.ReasonCodes)
+}
+
+func (suite *RuleEngineTestSuite) TestDefaultEngine_ColdStartUsesAmountThreshold() {
+	// Arrange
+	txn := GetTestTransaction("test@example.com")
+	suite.profiles.addSpending(txn, 20, 25)
+	txn.TransactionAmount = 1500
+	engine := fraud.NewDefaultRuleEngine(suite.history, suite.profiles)
+
+	// Act
+	decision, err := engine.Evaluate(suite.ctx, txn)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{fraud.ReasonHighAmount}, decision.ReasonCodes)
+}
+
+func TestRuleEngineSuite(t *testing.T) {
+	suite.Run(t, new(RuleEngineTestSuite))
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/models"
+	"github.com/CapitalOne-RedFlags/GreenFlag/internal/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// minimalCatalog defines every message type in English, the least a catalog may hold
+func minimalCatalog(extra string) string {
+	var catalog strings.Builder
+	catalog.WriteString("default_locale: en\nlocales:\n  en:\n    messages:\n")
+	for _, notificationType := range models.NotificationTypes {
+		catalog.WriteString("      " + notificationType + ":\n        body: \"" + notificationType + "\"\n")
+	}
+	catalog.WriteString(extra)
+	return catalog.String()
+}
+
+type TemplateCatalogTestSuite struct {
+	suite.Suite
+	catalog *templates.Catalog
+	alert   models.Notification
+}
+
+func (suite *TemplateCatalogTestSuite) SetupTest() {
+	suite.catalog = templates.BundledCatalog()
+	suite.alert = models.NewTransactionNotification(models.NotificationFraudAlert, models.Transaction{
+		AccountID: "12345678", TransactionAmount: 1500, MerchantID: "Electronics Store", TransactionDate: "2024-03-05T14:30:00Z", ReplyCode: "4821",
+	})
+}
+
+func (suite *TemplateCatalogTestSuite) TestEnglishAlertKeepsWording() {
+	// Act
+	rendered, err := suite.catalog.Render(suite.alert, "en")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Suspicious Activity on Your Card", rendered.Subject)
+	assert.Equal(suite.T(), "CAPITAL ONE: We detected a suspicious transaction on your card ending in 5678 for $1500.00 at Electronics Store on Mar 5 at 2:30 PM. If this was you, reply YES 4821. If not, reply NO 4821 or call us immediately.", rendered.Body)
+}
+
+func (suite *TemplateCatalogTestSuite) TestSpanishAlert() {
+	// Act
+	rendered, err := suite.catalog.Render(suite.alert, "es")
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "es", rendered.Locale)
+	assert.Equal(suite.T(), "Actividad sospechosa en su tarjeta", rendered.Subject)
+	assert.Contains(suite.T(), rendered.Body, "terminada en 5678 por $1500.00 en Electronics Store el 05/03/2024 a las 14:30")
+}
+
+func (suite *TemplateCatalogTestSuite) TestLocaleFallsBackToLanguageThenDefault() {
+	// Act
+	regional, regionalErr := suite.catalog.Render(suite.alert, "es_MX")
+	unknown, unknownErr := suite.catalog.Render(suite.alert, "fr")
+
+	// Assert
+	assert.NoError(suite.T(), regionalErr)
+	assert.NoError(suite.T(), unknownErr)
+	assert.Equal(suite.T(), "es", regional.Locale)
+	assert.Equal(suite.T(), "en", unknown.Locale)
+	assert.True(suite.T(), suite.catalog.HasLocale("es-MX"))
+	assert.False(suite.T(), suite.catalog.HasLocale("fr"))
+}
+
+func (suite *TemplateCatalogTestSuite) TestMissingMessageUsesDefaultLocale() {
+	// Arrange
+	catalog, err := templates.LoadCatalog(strings.NewReader(minimalCatalog("  es:\n    messages:\n      help:\n        body: \"ayuda\"\n")))
+	assert.NoError(suite.T(), err)
+
+	// Act
+	help, helpErr := catalog.Render(models.Notification{Type: models.NotificationHelp}, "es")
+	unknown, unknownErr := catalog.Render(models.Notification{Type: models.NotificationUnknown}, "es")
+
+	// Assert
+	assert.NoError(suite.T(), helpErr)
+	assert.NoError(suite.T(), unknownErr)
+	assert.Equal(suite.T(), "ayuda", help.Body)
+	assert.Equal(suite.T(), "unknown", unknown.Body)
+	assert.Equal(suite.T(), "en", unknown.Locale)
+}
+
+func (suite *TemplateCatalogTestSuite) TestInvalidCatalogsAreRejected() {
+	invalid := map[string]string{
+		"unknown placeholder": minimalCatalog("  es:\n    messages:\n      help:\n        body: \"{{.Balance}}\"\n"),
+		"unknown type":        minimalCatalog("  es:\n    messages:\n      greeting:\n        body: \"hola\"\n"),
+		"incomplete default":  "default_locale: en\nlocales:\n  en:\n    messages:\n      help:\n        body: \"help\"\n",
+		"empty":               "",
+	}
+	for name, catalog := range invalid {
+		// Act
+		_, err := templates.LoadCatalog(strings.NewReader(catalog))
+
+		// Assert
+		assert.Error(suite.T(), err, name)
+	}
+}
+
+func TestTemplateCatalogSuite(t *testing.T) {
+	suite.Run(t, new(TemplateCatalogTestSuite))
+}
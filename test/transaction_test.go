@@ -23,16 +23,16 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-// GetFraudTransaction implements db.TransactionRepository.
-func (m *MockTransactionRepository) GetTransactionByNumberAndStatus(ctx context.Context, phoneNumber string, status string) ([]models.Transaction, error) {
-	args := m.Called(ctx, phoneNumber)
-	return nil, args.Error(1)
+// GetTransactionsByAccountAndTimeRange implements db.TransactionRepository.
+func (m *MockTransactionRepository) GetTransactionsByAccountAndTimeRange(ctx context.Context, accountID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	args := m.Called(ctx, accountID, start, end)
+	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-// UpdateFraudTransaction implements db.TransactionRepository.
-func (m *MockTransactionRepository) UpdateFraudTransaction(ctx context.Context, phoneNumber string, isFraud bool, status string) (int, error) {
-	args := m.Called(ctx, phoneNumber, isFraud)
-	return 1, args.Error(1)
+// GetTransactionsByDeviceAndTimeRange implements db.TransactionRepository.
+func (m *MockTransactionRepository) GetTransactionsByDeviceAndTimeRange(ctx context.Context, deviceID string, start time.Time, end time.Time) ([]models.Transaction, error) {
+	args := m.Called(ctx, deviceID, start, end)
+	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
 // ✅ Implement `SaveTransaction`
@@ -53,6 +53,24 @@ func (m *MockTransactionRepository) UpdateTransaction(ctx context.Context, accou
 	return nil, args.Error(1)
 }
 
+// UpdatePendingTransaction implements db.TransactionRepository.
+func (m *MockTransactionRepository) UpdatePendingTransaction(ctx context.Context, accountID, transactionID string, values *models.Transaction) error {
+	args := m.Called(ctx, accountID, transactionID, values)
+	return args.Error(0)
+}
+
+// UpdateAlertChannel implements db.TransactionRepository.
+func (m *MockTransactionRepository) UpdateAlertChannel(ctx context.Context, accountID, transactionID string, channel string) error {
+	args := m.Called(ctx, accountID, transactionID, channel)
+	return args.Error(0)
+}
+
+// UpdateReplyCode implements db.TransactionRepository.
+func (m *MockTransactionRepository) UpdateReplyCode(ctx context.Context, accountID, transactionID string, replyCode string) error {
+	args := m.Called(ctx, accountID, transactionID, replyCode)
+	return args.Error(0)
+}
+
 // ✅ Implement `DeleteTransaction`
 func (m *MockTransactionRepository) DeleteTransaction(ctx context.Context, accountID, transactionID string) error {
 	args := m.Called(ctx, accountID, transactionID)
@@ -106,6 +124,19 @@ func (suite *TransactionPipelineTestSuite) TestTransactionService_Success() {
 	assert.Empty(suite.T(), failedTransactions)
 }
 
+// Test Case: A transaction without a device is written without DeviceID, so it stays out of the device index
+func (suite *TransactionPipelineTestSuite) TestTransactionWithoutDeviceOmitsDeviceID() {
+	// Arrange
+	transaction := models.Transaction{TransactionID: "tx1", AccountID: "acc123", CustomerAge: 26, PhoneNumber: "+12025550179", Email: "test@example.com"}
+
+	// Act
+	item, err := transaction.MarshalDynamoDB()
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), item, "DeviceID")
+}
+
 // Test Case: Save Fails Due to DynamoDB Error
 func (suite *TransactionPipelineTestSuite) TestTransactionService_SaveError() {
 	transactions := []models.Transaction{